@@ -53,6 +53,17 @@ type GlobalConfig struct {
 	HostIP       string
 	Hostname     string
 	DelayStopSec int
+	// Max time to wait for queued data to be flushed when a config is stopped, 0 to stop at once.
+	DrainTimeoutMs int
+	// Usage of the flush queue above which inputs are throttled, percent, 0 to disable backpressure.
+	BackpressureWatermarkPercent int
+	// Max events and bytes per second passed from inputs to processors, 0 for no limit.
+	MaxEventsPerSecond int
+	MaxBytesPerSecond  int
+	// What to do with data over the rate limit, LimitStrategyPause by default or LimitStrategyDrop.
+	LimitStrategy string
+	// Names of the configs, with or without suffix, which must be running before this config starts.
+	DependsOn []string
 
 	EnableTimestampNanosecond bool
 	UsingOldContentTag        bool
@@ -64,6 +75,14 @@ type GlobalConfig struct {
 	AgentEnvMetaTagKey     map[string]string
 }
 
+// Strategies for data over MaxEventsPerSecond or MaxBytesPerSecond.
+const (
+	// LimitStrategyPause holds data until the rate allows, so that inputs are blocked by the full queue.
+	LimitStrategyPause = "pause"
+	// LimitStrategyDrop drops data over the rate.
+	LimitStrategyDrop = "drop"
+)
+
 // LoongcollectorGlobalConfig is the singleton instance of GlobalConfig.
 var LoongcollectorGlobalConfig = newGlobalConfig()
 
@@ -0,0 +1,48 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"sync"
+)
+
+// CheckpointFileResolver returns the paths of the files a checkpoint saved by an input keeps offsets of.
+// ok is false if the checkpoint does not belong to files, such checkpoints are never removed by compaction.
+type CheckpointFileResolver func(configName, key string, value []byte) (paths []string, ok bool)
+
+var checkpointFileResolversLock sync.RWMutex
+var checkpointFileResolvers []CheckpointFileResolver
+
+// RegisterCheckpointFileResolver registers a resolver used by checkpoint compaction to find the backing files
+// of checkpoints, it is usually called in init of the input. Resolvers are tried in order of registration,
+// and the first one returning ok is used.
+func RegisterCheckpointFileResolver(resolver CheckpointFileResolver) {
+	checkpointFileResolversLock.Lock()
+	defer checkpointFileResolversLock.Unlock()
+	checkpointFileResolvers = append(checkpointFileResolvers, resolver)
+}
+
+// ResolveCheckpointFiles returns the backing files of the checkpoint, see CheckpointFileResolver.
+func ResolveCheckpointFiles(configName, key string, value []byte) ([]string, bool) {
+	checkpointFileResolversLock.RLock()
+	resolvers := checkpointFileResolvers
+	checkpointFileResolversLock.RUnlock()
+	for _, resolver := range resolvers {
+		if paths, ok := resolver(configName, key, value); ok {
+			return paths, true
+		}
+	}
+	return nil, false
+}
@@ -34,6 +34,7 @@ type ContainerMeta struct {
 	ContainerName   string
 	Image           string
 	K8sLabels       map[string]string
+	K8sAnnotations  map[string]string
 	ContainerLabels map[string]string
 	Env             map[string]string
 }
@@ -61,6 +62,7 @@ func GetContainerMeta(containerID string) *ContainerMeta {
 		}
 		c := &ContainerMeta{
 			K8sLabels:       make(map[string]string),
+			K8sAnnotations:  make(map[string]string),
 			ContainerLabels: make(map[string]string),
 			Env:             make(map[string]string),
 		}
@@ -75,6 +77,9 @@ func GetContainerMeta(containerID string) *ContainerMeta {
 		for k, v := range detail.K8SInfo.Labels {
 			c.K8sLabels[k] = v
 		}
+		for k, v := range detail.K8SInfo.Annotations {
+			c.K8sAnnotations[k] = v
+		}
 		for k, v := range detail.ContainerInfo.Config.Labels {
 			c.ContainerLabels[k] = v
 		}
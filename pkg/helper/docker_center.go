@@ -139,6 +139,7 @@ type K8SInfo struct {
 	Pod             string
 	ContainerName   string
 	Labels          map[string]string
+	Annotations     map[string]string
 	PausedContainer bool
 
 	matchedCache map[uint64]bool
@@ -169,7 +170,15 @@ func (info *K8SInfo) ExtractK8sLabels(containerInfo types.ContainerJSON) {
 			info.Labels = make(map[string]string)
 		}
 		for key, val := range containerInfo.Config.Labels {
-			if strings.HasPrefix(key, k8sInnerLabelPrefix) || strings.HasPrefix(key, k8sInnerAnnotationPrefix) {
+			if strings.HasPrefix(key, k8sInnerAnnotationPrefix) {
+				// pod annotations are kept in labels of the pause container with the prefix
+				if info.Annotations == nil {
+					info.Annotations = make(map[string]string)
+				}
+				info.Annotations[strings.TrimPrefix(key, k8sInnerAnnotationPrefix)] = val
+				continue
+			}
+			if strings.HasPrefix(key, k8sInnerLabelPrefix) {
 				continue
 			}
 			info.Labels[key] = val
@@ -183,7 +192,7 @@ func (info *K8SInfo) Merge(o *K8SInfo) {
 	defer info.mu.Unlock()
 	defer o.mu.Unlock()
 
-	// only pause container has k8s labels, so we can only check len(labels)
+	// only pause container has k8s labels and annotations, so we can only check their len
 	if len(o.Labels) > len(info.Labels) {
 		info.Labels = o.Labels
 		info.matchedCache = nil
@@ -192,6 +201,12 @@ func (info *K8SInfo) Merge(o *K8SInfo) {
 		o.Labels = info.Labels
 		o.matchedCache = nil
 	}
+	if len(o.Annotations) > len(info.Annotations) {
+		info.Annotations = o.Annotations
+	}
+	if len(o.Annotations) < len(info.Annotations) {
+		o.Annotations = info.Annotations
+	}
 }
 
 // IsMatch ...
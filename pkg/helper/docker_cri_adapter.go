@@ -372,9 +372,9 @@ func (cw *CRIRuntimeWrapper) fetchAll() error {
 		cw.containerHistory[c.GetId()] = true
 		containerMap[c.GetId()] = dockerContainer
 
-		// append the pod labels to the k8s info.
+		// append the pod labels and annotations to the k8s info.
 		if sandbox, ok := sandboxMap[c.PodSandboxId]; ok {
-			cw.wrapperK8sInfoByLabels(sandbox.GetLabels(), dockerContainer)
+			cw.wrapperK8sInfoByLabels(sandbox.GetLabels(), sandbox.GetAnnotations(), dockerContainer)
 		}
 		logger.Debugf(context.Background(), "Create container info, id:%v\tname:%v\tcreated:%v\tstatus:%v\tdetail:%+v",
 			dockerContainer.IDPrefix(), c.Metadata.Name, dockerContainer.ContainerInfo.Created, dockerContainer.Status(), c)
@@ -506,21 +506,34 @@ func (cw *CRIRuntimeWrapper) wrapperK8sInfoByID(sandboxID string, detail *Docker
 		logger.Debug(context.Background(), "fetchone cannot read k8s info from sandbox, sandboxID", sandboxID)
 		return
 	}
-	cw.wrapperK8sInfoByLabels(status.GetStatus().GetLabels(), detail)
+	cw.wrapperK8sInfoByLabels(status.GetStatus().GetLabels(), status.GetStatus().GetAnnotations(), detail)
 }
 
-func (cw *CRIRuntimeWrapper) wrapperK8sInfoByLabels(sandboxLabels map[string]string, detail *DockerInfoDetail) {
-	if detail.K8SInfo == nil || sandboxLabels == nil {
+func (cw *CRIRuntimeWrapper) wrapperK8sInfoByLabels(sandboxLabels, sandboxAnnotations map[string]string, detail *DockerInfoDetail) {
+	if detail.K8SInfo == nil {
 		return
 	}
-	if detail.K8SInfo.Labels == nil {
-		detail.K8SInfo.Labels = make(map[string]string)
+	if sandboxLabels != nil {
+		if detail.K8SInfo.Labels == nil {
+			detail.K8SInfo.Labels = make(map[string]string)
+		}
+		for k, v := range sandboxLabels {
+			if strings.HasPrefix(k, k8sInnerLabelPrefix) || strings.HasPrefix(k, k8sInnerAnnotationPrefix) {
+				continue
+			}
+			detail.K8SInfo.Labels[k] = v
+		}
 	}
-	for k, v := range sandboxLabels {
-		if strings.HasPrefix(k, k8sInnerLabelPrefix) || strings.HasPrefix(k, k8sInnerAnnotationPrefix) {
-			continue
+	if sandboxAnnotations != nil {
+		if detail.K8SInfo.Annotations == nil {
+			detail.K8SInfo.Annotations = make(map[string]string)
+		}
+		for k, v := range sandboxAnnotations {
+			if strings.HasPrefix(k, k8sInnerLabelPrefix) {
+				continue
+			}
+			detail.K8SInfo.Annotations[k] = v
 		}
-		detail.K8SInfo.Labels[k] = v
 	}
 }
 
@@ -17,6 +17,8 @@ package helper
 import (
 	"crypto/tls"
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +29,9 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/alibaba/ilogtail/pkg/tlscommon"
 )
 
 var supportedCompressionType = map[string]interface{}{"gzip": nil, "snappy": nil, "zstd": nil}
@@ -60,6 +65,9 @@ type GrpcClientConfig struct {
 	Retry RetryConfig `json:"Retry"`
 
 	Timeout int `json:"Timeout"`
+
+	// TLS settings of the client. If not enabled, TLS is used with system roots for endpoints with https://.
+	TLS *tlscommon.TLSConfig `json:"TLS"`
 }
 
 type RetryConfig struct {
@@ -79,7 +87,13 @@ func (cfg *GrpcClientConfig) GetDialOptions() ([]grpc.DialOption, error) {
 	}
 
 	cred := insecure.NewCredentials()
-	if strings.HasPrefix(cfg.Endpoint, "https://") {
+	if cfg.TLS != nil && cfg.TLS.Enabled {
+		tlsConfig, err := cfg.TLS.LoadTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		cred = credentials.NewTLS(tlsConfig)
+	} else if strings.HasPrefix(cfg.Endpoint, "https://") {
 		/* #nosec G402 - it is a false positive since tls.VersionTLS13 is the latest version */
 		cred = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS13})
 	}
@@ -159,6 +173,31 @@ func GetRetryInfo(err error) *RetryInfo {
 	return &RetryInfo{delay: throttle, err: err}
 }
 
+// HTTPStatusError converts a failed response of an OTLP/HTTP like server into a gRPC status error, so that
+// GetRetryInfo applies to it: 429, 502, 503 and 504 are retryable, after the delay of Retry-After if given.
+func HTTPStatusError(statusCode int, header http.Header, message string) error {
+	code := codes.Unknown
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		code = codes.Unavailable
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	}
+	st := status.New(code, fmt.Sprintf("http status %d: %s", statusCode, message))
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 && code == codes.Unavailable {
+		if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Duration(seconds) * time.Second)}); err == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
+
 func shouldRetry(code codes.Code, retryInfo *errdetails.RetryInfo) bool {
 	switch code {
 	case codes.Canceled,
@@ -14,12 +14,16 @@
 
 package pipeline
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+)
 
 // AsyncControl is an asynchronous execution control that can be canceled.
 type AsyncControl struct {
 	cancelToken chan struct{}
 	wg          sync.WaitGroup
+	running     atomic.Int64
 }
 
 // CancelToken returns a readonly channel that can be subscribed to as a cancel token
@@ -41,12 +45,19 @@ func (p *AsyncControl) Reset() {
 // Run function as a Task
 func (p *AsyncControl) Run(task func(*AsyncControl)) {
 	p.wg.Add(1)
+	p.running.Add(1)
 	go func(cc *AsyncControl, fn func(*AsyncControl)) {
 		defer cc.wg.Done()
+		defer cc.running.Add(-1)
 		fn(cc)
 	}(p, task)
 }
 
+// Running returns the number of tasks which have not returned yet.
+func (p *AsyncControl) Running() int64 {
+	return p.running.Load()
+}
+
 // Waiting for executing task to be canceled
 func (p *AsyncControl) WaitCancel() {
 	close(p.cancelToken)
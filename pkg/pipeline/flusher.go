@@ -72,3 +72,32 @@ type FlusherV2 interface {
 	// before it to make sure there is space for next data.
 	Export([]*models.PipelineGroupEvents, PipelineContext) error
 }
+
+// FlusherCounters are the numbers of events handled by a flusher since it was started.
+type FlusherCounters struct {
+	Sent    int64
+	Failed  int64
+	Retried int64
+	Dropped int64
+}
+
+// FlusherCounterReporter is optionally implemented by flushers counting the events they deliver.
+type FlusherCounterReporter interface {
+	// FlusherCounters returns the counters, it may be called concurrently with Flush or Export.
+	FlusherCounters() FlusherCounters
+}
+
+// FallbackFlusher is optionally implemented by flushers which only receive the data missed by the other
+// flushers of the config, e.g. to spill data to local disk while the backend is unreachable.
+// Fallback flushers of a config without other flushers receive all data.
+type FallbackFlusher interface {
+	// IsFallback returns true if the flusher is used as a fallback.
+	IsFallback() bool
+}
+
+// IdleConnectionCloser is optionally implemented by flushers keeping connections to remote endpoints.
+type IdleConnectionCloser interface {
+	// CloseIdle closes idle connections to the endpoint, active transfers are not affected.
+	// It returns false if the flusher does not send data to the endpoint.
+	CloseIdle(endpoint string) (bool, error)
+}
@@ -14,7 +14,11 @@
 
 package pipeline
 
-import "github.com/alibaba/ilogtail/pkg/selfmonitor"
+import (
+	"context"
+
+	"github.com/alibaba/ilogtail/pkg/selfmonitor"
+)
 
 // logtail plugin type define
 const (
@@ -37,6 +41,28 @@ type PluginMeta struct {
 	PluginTypeWithID string
 }
 
+// Closer is an optional interface of plugins that need a final cleanup when the agent shuts down,
+// such as syncing files or committing offsets. OnAgentShutdown is called after the plugin is stopped,
+// and should return when ctx is done.
+type Closer interface {
+	OnAgentShutdown(ctx context.Context) error
+}
+
+// Reconfigurable is an optional interface of plugins, mostly flushers and processors, that can apply a new
+// detail while running, such as a batch size. Reconfigure is called concurrently with Process or Flush, so the
+// plugin must guard the settings it re-reads. If an error is returned, the previous settings must be kept.
+type Reconfigurable interface {
+	Reconfigure(detail map[string]interface{}) error
+}
+
+// StopPrioritizer is an optional interface of service inputs, flushers and extensions that need to be stopped
+// before or after the other plugins of the same category in a config, e.g. a flusher forwarding data to another
+// flusher of the config. Plugins of a category are stopped in ascending StopPriority, plugins not implementing
+// it have priority 0. Categories are still stopped from inputs to flushers.
+type StopPrioritizer interface {
+	StopPriority() int
+}
+
 type MetricCreator func() MetricInput
 
 var MetricInputs = map[string]MetricCreator{}
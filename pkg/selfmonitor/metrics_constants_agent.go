@@ -20,6 +20,12 @@ package selfmonitor
 
 // metric keys
 const (
-	MetricAgentMemoryGo        = "go_memory_used_mb"
-	MetricAgentGoRoutinesTotal = "go_routines_total"
+	MetricAgentMemoryGo         = "go_memory_used_mb"
+	MetricAgentGoRoutinesTotal  = "go_routines_total"
+	MetricAgentGoRoutinesLeaked = "go_routines_leaked"
+
+	// unsent data of stopped configs adopted by their new versions, and stopped configs whose unsent data is dropped
+	MetricAgentUnsendBufferRecoveredRecordsTotal = "unsend_buffer_recovered_records_total"
+	MetricAgentUnsendBufferRecoveredBytesTotal   = "unsend_buffer_recovered_bytes_total"
+	MetricAgentUnsendBufferDroppedTotal          = "unsend_buffer_dropped_total"
 )
@@ -0,0 +1,39 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selfmonitor
+
+//////////////////////////////////////////////////////////////////////////
+// pipeline
+//////////////////////////////////////////////////////////////////////////
+
+// label values
+const (
+	MetricLabelValueMetricCategoryPipeline = "pipeline"
+)
+
+// metric keys
+const (
+	MetricPipelineInRecordsTotal                = "in_records_total"
+	MetricPipelineProcessorOutRecordsTotal      = "processor_out_records_total"
+	MetricPipelineAggregatorOutRecordsTotal     = "aggregator_out_records_total"
+	MetricPipelineFlushedRecordsTotal           = "flushed_records_total"
+	MetricPipelineProcessorDroppedRecordsTotal  = "processor_dropped_records_total"
+	MetricPipelineAggregatorDroppedRecordsTotal = "aggregator_dropped_records_total"
+	MetricPipelineFlusherDroppedRecordsTotal    = "flusher_dropped_records_total"
+	MetricPipelineDisabledSeconds               = "disabled_seconds"
+	MetricPipelinePanicsTotal                   = "panics_total"
+	MetricPipelineStartLatencyMs                = "start_latency_ms"
+	MetricPipelineRateLimitDroppedRecordsTotal  = "rate_limit_dropped_records_total"
+)
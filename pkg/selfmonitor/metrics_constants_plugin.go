@@ -105,3 +105,10 @@ const (
 const (
 	PluginPairsPerLogTotal = "pairs_per_log_total"
 )
+
+/**********************************************************
+*   processor_redact
+**********************************************************/
+const (
+	MetricPluginRedactedFieldsTotal = "redacted_fields_total"
+)
@@ -0,0 +1,30 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import "sync/atomic"
+
+var diskSpaceLow atomic.Bool
+
+// IsDiskSpaceLow returns true if free space of the data dir is below the threshold of the disk space watchdog
+// of the plugin manager. Plugins buffering data on disk should drop data with alarm instead of writing it.
+func IsDiskSpaceLow() bool {
+	return diskSpaceLow.Load()
+}
+
+// SetDiskSpaceLow is called by the disk space watchdog when free space of the data dir crosses the threshold.
+func SetDiskSpaceLow(low bool) {
+	diskSpaceLow.Store(low)
+}
@@ -17,6 +17,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"runtime"
 	"runtime/debug"
@@ -233,6 +234,10 @@ func StopBuiltInModules() {
 func Start(configName string) {
 	logger.Info(context.Background(), "Start", "start", "config", configName)
 	err := pluginmanager.Start(configName)
+	if errors.Is(err, pluginmanager.ErrAlreadyStarted) {
+		logger.Info(context.Background(), "Start", "skipped, config already started", "config", configName)
+		return
+	}
 	if err != nil {
 		logger.Error(context.Background(), "PLUGIN_ALARM", "start error", err)
 	}
@@ -350,16 +355,11 @@ func initPluginBase(cfgStr string) int {
 			stopCh := make(chan struct{})
 			instance.Run(stopCh)
 		}
-		if err := pluginmanager.Init(); err != nil {
+		if err := pluginmanager.Init(context.Background(), false); err != nil {
 			logger.Error(context.Background(), "PLUGIN_ALARM", "init plugin error", err)
 			rst = 1
 		}
-		if pluginmanager.AlarmConfig != nil {
-			pluginmanager.AlarmConfig.Start()
-		}
-		if pluginmanager.ContainerConfig != nil {
-			pluginmanager.ContainerConfig.Start()
-		}
+		pluginmanager.StartBuiltInModulesConfig()
 		err := pluginmanager.CheckPointManager.Init()
 		if err != nil {
 			logger.Error(context.Background(), "CHECKPOINT_INIT_ALARM", "init checkpoint manager error", err)
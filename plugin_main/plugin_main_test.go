@@ -115,7 +115,7 @@ func TestHangConfigWhenStop(t *testing.T) {
 	// Stop config, it will hang.
 	Stop(configName, 0)
 	time.Sleep(time.Second * 2)
-	_, exists = pluginmanager.DisabledLogtailConfig[config]
+	_, exists = pluginmanager.DisabledLogtailConfig[config.Generation()]
 	require.True(t, exists)
 	// Load again, succeed. Changed since independently reload
 	time.Sleep(time.Second)
@@ -144,7 +144,7 @@ func TestHangConfigWhenStop(t *testing.T) {
 	// Stop config, hang again.
 	Stop(config.ConfigNameWithSuffix, 0)
 	time.Sleep(time.Second * 2)
-	_, exists = pluginmanager.DisabledLogtailConfig[config]
+	_, exists = pluginmanager.DisabledLogtailConfig[config.Generation()]
 	require.True(t, exists)
 
 	// Change config detail, load a new pipeline.
@@ -194,7 +194,7 @@ func TestSlowConfigWhenStop(t *testing.T) {
 
 	// Stop config, it will hang.
 	Stop(configName, 0)
-	_, exists = pluginmanager.DisabledLogtailConfig[config]
+	_, exists = pluginmanager.DisabledLogtailConfig[config.Generation()]
 	require.True(t, exists)
 	// Load again, success. Changed since independently reload
 	time.Sleep(time.Second)
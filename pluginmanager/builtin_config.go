@@ -0,0 +1,300 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/alibaba/ilogtail/pkg/flags"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	builtinAlarmName     = "alarm"
+	builtinContainerName = "container"
+)
+
+var EnableAlarmMetrics = flag.Bool("EnableAlarmMetrics", true, "load the built-in alarm config to report alarms and statistics")
+var EnableContainerMetrics = flag.Bool("EnableContainerMetrics", true, "load the built-in container config to report container info, disable it if there is no container runtime")
+var AlarmConfigOverridePath = flag.String("AlarmConfigOverridePath", "", "file of the json replacing the built-in alarm config, the built-in one is used if the file is invalid")
+var ContainerConfigOverridePath = flag.String("ContainerConfigOverridePath", "", "file of the json replacing the built-in container config, the built-in one is used if the file is invalid")
+var AlarmInputIntervalMs = flag.Int("AlarmInputIntervalMs", 30000, "input interval of the built-in alarm config, ms, ignored if the config is overridden by AlarmConfigOverridePath")
+var ForceSelfCollectFlusher = flag.String("ForceSelfCollectFlusher", "", "type of the flusher receiving metrics collected by ForceSelfCollect instead of the flushers of built-in configs, e.g. flusher_stdout")
+var ForceSelfCollectFlusherDetail = flag.String("ForceSelfCollectFlusherDetail", "{}", "json detail of ForceSelfCollectFlusher")
+var ContainerInputIntervalMs = flag.Int("ContainerInputIntervalMs", 30000, "input interval of the built-in container config, ms, ignored if the config is overridden by ContainerConfigOverridePath")
+var AlarmProject = flag.String("AlarmProject", "", "project of the built-in alarm config, sls-admin if empty")
+var AlarmLogstore = flag.String("AlarmLogstore", "", "logstore of the built-in alarm config, logtail_alarm if empty")
+var ContainerProject = flag.String("ContainerProject", "", "project of the built-in container config, sls-admin if empty")
+var ContainerLogstore = flag.String("ContainerLogstore", "", "logstore of the built-in container config, logtail_containers if empty")
+
+// BuiltinConfigLoadError is returned by Init when a built-in config fails to load.
+type BuiltinConfigLoadError struct {
+	Name     string
+	Critical bool
+	Cause    error
+}
+
+func (e *BuiltinConfigLoadError) Error() string {
+	return fmt.Sprintf("load built-in config %s fail: %v", e.Name, e.Cause)
+}
+
+func (e *BuiltinConfigLoadError) Unwrap() error {
+	return e.Cause
+}
+
+// builtinConfig is a config loaded by Init and stopped by StopBuiltInModulesConfig.
+// Init always fails if a critical built-in config fails to load.
+type builtinConfig struct {
+	name       string
+	project    string
+	logstore   string
+	configName string
+	jsonStr    string
+	critical   bool
+	// enabled is the flag to skip the config, nil if the config is always loaded.
+	enabled *bool
+	// overridePath is the flag of the file replacing jsonStr, nil if the config can not be overridden.
+	overridePath *string
+	// inputIntervalMs is the flag replacing InputIntervalMs of jsonStr, nil if jsonStr is used as is.
+	inputIntervalMs *int
+	// projectOverride and logstoreOverride are the flags replacing project and logstore when not empty.
+	projectOverride  *string
+	logstoreOverride *string
+	config           *LogstoreConfig
+}
+
+var builtinConfigLock sync.Mutex
+var builtinConfigs = []*builtinConfig{
+	{name: builtinAlarmName, project: "sls-admin", logstore: "logtail_alarm", configName: "logtail_alarm", jsonStr: alarmConfigJSON, critical: true, enabled: EnableAlarmMetrics, overridePath: AlarmConfigOverridePath, inputIntervalMs: AlarmInputIntervalMs, projectOverride: AlarmProject, logstoreOverride: AlarmLogstore},
+	{name: builtinContainerName, project: "sls-admin", logstore: "logtail_containers", configName: "logtail_containers", jsonStr: containerConfigJSON, enabled: EnableContainerMetrics, overridePath: ContainerConfigOverridePath, inputIntervalMs: ContainerInputIntervalMs, projectOverride: ContainerProject, logstoreOverride: ContainerLogstore},
+}
+
+// RegisterBuiltinConfig registers a built-in config to be loaded by Init, started with other built-in
+// configs and stopped by StopBuiltInModulesConfig. It must be called before Init.
+func RegisterBuiltinConfig(name, project, logstore, configName, jsonStr string) error {
+	return registerBuiltinConfig(name, project, logstore, configName, jsonStr, false)
+}
+
+// RegisterCriticalBuiltinConfig works like RegisterBuiltinConfig, and Init always fails if the config
+// fails to load, see BuiltinConfigLoadError.
+func RegisterCriticalBuiltinConfig(name, project, logstore, configName, jsonStr string) error {
+	return registerBuiltinConfig(name, project, logstore, configName, jsonStr, true)
+}
+
+func registerBuiltinConfig(name, project, logstore, configName, jsonStr string, critical bool) error {
+	if len(name) == 0 || len(configName) == 0 {
+		return fmt.Errorf("name and config name of built-in config must not be empty")
+	}
+	if !json.Valid([]byte(jsonStr)) {
+		return fmt.Errorf("invalid json of built-in config %s", name)
+	}
+	builtinConfigLock.Lock()
+	defer builtinConfigLock.Unlock()
+	for _, c := range builtinConfigs {
+		if c.name == name || c.configName == configName {
+			return fmt.Errorf("built-in config %s already registered", name)
+		}
+	}
+	builtinConfigs = append(builtinConfigs, &builtinConfig{
+		name:       name,
+		project:    project,
+		logstore:   logstore,
+		configName: configName,
+		jsonStr:    jsonStr,
+		critical:   critical,
+	})
+	return nil
+}
+
+// projectName returns the project override if it is set, otherwise the built-in project.
+func (c *builtinConfig) projectName() string {
+	return overrideOrDefault(c.projectOverride, c.project)
+}
+
+// logstoreName returns the logstore override if it is set, otherwise the built-in logstore.
+func (c *builtinConfig) logstoreName() string {
+	return overrideOrDefault(c.logstoreOverride, c.logstore)
+}
+
+func overrideOrDefault(override *string, defaultValue string) string {
+	if override == nil {
+		return defaultValue
+	}
+	if value := strings.TrimSpace(*override); len(value) > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// configJSON returns the json in the override file if it is set and valid, otherwise the built-in json.
+func (c *builtinConfig) configJSON() string {
+	if c.overridePath == nil || len(*c.overridePath) == 0 {
+		return c.builtinJSON()
+	}
+	content, err := os.ReadFile(*c.overridePath)
+	if err != nil {
+		logger.Warning(context.Background(), "LOAD_CONFIG_ALARM", "read override of built-in config fail, use the built-in one",
+			c.name, "path", *c.overridePath, "error", err)
+		return c.builtinJSON()
+	}
+	var plugins map[string]interface{}
+	if err = json.Unmarshal(content, &plugins); err != nil {
+		logger.Warning(context.Background(), "LOAD_CONFIG_ALARM", "parse override of built-in config fail, use the built-in one",
+			c.name, "path", *c.overridePath, "error", err)
+		return c.builtinJSON()
+	}
+	logger.Info(context.Background(), "override built-in config", c.name, "path", *c.overridePath)
+	return string(content)
+}
+
+// builtinJSON returns jsonStr with InputIntervalMs replaced by the interval flag.
+func (c *builtinConfig) builtinJSON() string {
+	if c.inputIntervalMs == nil {
+		return c.jsonStr
+	}
+	if *c.inputIntervalMs <= 0 {
+		logger.Warning(context.Background(), "LOAD_CONFIG_ALARM", "invalid input interval of built-in config, use the built-in one",
+			c.name, "interval", *c.inputIntervalMs)
+		return c.jsonStr
+	}
+	var plugins map[string]interface{}
+	if err := json.Unmarshal([]byte(c.jsonStr), &plugins); err != nil {
+		return c.jsonStr
+	}
+	global, ok := plugins["global"].(map[string]interface{})
+	if !ok {
+		global = make(map[string]interface{})
+		plugins["global"] = global
+	}
+	global["InputIntervalMs"] = *c.inputIntervalMs
+	jsonStr, err := json.Marshal(plugins)
+	if err != nil {
+		return c.jsonStr
+	}
+	return string(jsonStr)
+}
+
+// loadBuiltinConfigs loads all registered built-in configs.
+// If allowDegraded is set, failures of non-critical built-in configs are logged and skipped.
+func loadBuiltinConfigs(allowDegraded bool) error {
+	builtinConfigLock.Lock()
+	defer builtinConfigLock.Unlock()
+	for _, c := range builtinConfigs {
+		if c.enabled != nil && !*c.enabled {
+			logger.Info(context.Background(), "built-in config is disabled", c.name)
+			continue
+		}
+		config, err := loadBuiltinConfig(c.name, c.projectName(), c.logstoreName(), c.configName, c.configJSON())
+		if err != nil {
+			logger.Error(context.Background(), "LOAD_CONFIG_ALARM", "load "+c.name+" config fail", err)
+			if c.critical || !allowDegraded {
+				return &BuiltinConfigLoadError{Name: c.name, Critical: c.critical, Cause: err}
+			}
+			logger.Warning(context.Background(), "LOAD_CONFIG_ALARM", "continue without built-in config", c.name)
+			continue
+		}
+		c.config = config
+		switch c.name {
+		case builtinAlarmName:
+			AlarmConfig = config
+		case builtinContainerName:
+			ContainerConfig = config
+		}
+	}
+	return nil
+}
+
+// StartBuiltInModulesConfig starts all built-in configs loaded by Init.
+func StartBuiltInModulesConfig() {
+	builtinConfigLock.Lock()
+	defer builtinConfigLock.Unlock()
+	for _, c := range builtinConfigs {
+		if c.config != nil {
+			c.config.Start()
+		}
+	}
+}
+
+// forceCollectToFlusher collects the metrics of the stopped built-in config once more, and flushes them
+// to ForceSelfCollectFlusher instead of the flushers of the config.
+func (c *builtinConfig) forceCollectToFlusher() {
+	logger.Info(context.Background(), "force collect the "+c.name+" metrics", "flusher", *ForceSelfCollectFlusher)
+	var plugins map[string]interface{}
+	var detail interface{}
+	if err := json.Unmarshal([]byte(c.configJSON()), &plugins); err != nil {
+		logger.Warning(context.Background(), "LOAD_CONFIG_ALARM", "force collect the "+c.name+" metrics fail", err)
+		return
+	}
+	if err := json.Unmarshal([]byte(*ForceSelfCollectFlusherDetail), &detail); err != nil {
+		logger.Warning(context.Background(), "LOAD_CONFIG_ALARM", "invalid detail of force collect flusher", err)
+		return
+	}
+	plugins["flushers"] = []interface{}{map[string]interface{}{"type": *ForceSelfCollectFlusher, "detail": detail}}
+	jsonStr, _ := json.Marshal(plugins)
+	config, err := buildLogstoreConfigWithContext(c.projectName(), c.logstoreName(), c.configName, -1, string(jsonStr), newBuiltinRuntimeContext)
+	if err != nil {
+		logger.Warning(context.Background(), "LOAD_CONFIG_ALARM", "force collect the "+c.name+" metrics fail", err)
+		return
+	}
+	// Metric inputs collect once when started even if stopped at once, see timerRunner.Run.
+	config.Start()
+	_ = config.Stop(true)
+	cancelRuntimeContext(config)
+}
+
+// stopBuiltinConfigs stops all built-in configs, and collects their metrics once more if ForceSelfCollect is set.
+func stopBuiltinConfigs() {
+	builtinConfigLock.Lock()
+	defer builtinConfigLock.Unlock()
+	for _, c := range builtinConfigs {
+		if c.config == nil {
+			continue
+		}
+		if *flags.ForceSelfCollect && len(*ForceSelfCollectFlusher) > 0 {
+			_ = c.config.Stop(true)
+			c.forceCollectToFlusher()
+			c.config = nil
+			continue
+		}
+		if *flags.ForceSelfCollect {
+			logger.Info(context.Background(), "force collect the "+c.name+" metrics")
+			_ = ForceCollectOnce(c.config)
+		}
+		_ = c.config.Stop(true)
+		c.config = nil
+	}
+	AlarmConfig = nil
+	ContainerConfig = nil
+}
+
+func init() {
+	_ = util.InitFromEnvBool("ENABLE_ALARM_METRICS", EnableAlarmMetrics, *EnableAlarmMetrics)
+	_ = util.InitFromEnvBool("ENABLE_CONTAINER_METRICS", EnableContainerMetrics, *EnableContainerMetrics)
+	_ = util.InitFromEnvString("ALARM_CONFIG_OVERRIDE_PATH", AlarmConfigOverridePath, *AlarmConfigOverridePath)
+	_ = util.InitFromEnvString("CONTAINER_CONFIG_OVERRIDE_PATH", ContainerConfigOverridePath, *ContainerConfigOverridePath)
+	_ = util.InitFromEnvInt("ALARM_INPUT_INTERVAL_MS", AlarmInputIntervalMs, *AlarmInputIntervalMs)
+	_ = util.InitFromEnvInt("CONTAINER_INPUT_INTERVAL_MS", ContainerInputIntervalMs, *ContainerInputIntervalMs)
+	_ = util.InitFromEnvString("ALARM_PROJECT", AlarmProject, *AlarmProject)
+	_ = util.InitFromEnvString("ALARM_LOGSTORE", AlarmLogstore, *AlarmLogstore)
+	_ = util.InitFromEnvString("CONTAINER_PROJECT", ContainerProject, *ContainerProject)
+	_ = util.InitFromEnvString("CONTAINER_LOGSTORE", ContainerLogstore, *ContainerLogstore)
+}
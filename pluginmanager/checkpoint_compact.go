@@ -0,0 +1,97 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	leveldbutil "github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+var CheckPointFileTTL = flag.Int("CheckPointFileTTL", 30*24*3600, "checkpoint of a file not modified within the ttl is removed by compaction, second")
+
+// CompactCheckpoints removes the checkpoints whose backing files no longer exist or are not modified
+// within CheckPointFileTTL, and compacts the checkpoint db to reclaim disk space. Inputs register how to
+// find the backing files of their checkpoints with helper.RegisterCheckpointFileResolver.
+func CompactCheckpoints() error {
+	return CheckPointManager.compact()
+}
+
+func (p *checkPointManager) compact() error {
+	if p.db == nil {
+		return ErrCheckPointNotInit
+	}
+	ttl := time.Duration(*CheckPointFileTTL) * time.Second
+	staleItems := make([]string, 0, 10)
+	iter := p.db.NewIterator(nil, nil)
+	for iter.Next() {
+		keyStr := string(iter.Key())
+		index := strings.IndexByte(keyStr, '^')
+		if index <= 0 {
+			continue
+		}
+		paths, ok := helper.ResolveCheckpointFiles(keyStr[:index], keyStr[index+1:], iter.Value())
+		if ok && areCheckpointFilesStale(paths, ttl) {
+			staleItems = append(staleItems, keyStr)
+			if len(staleItems) >= *MaxCleanItemPerInterval {
+				break
+			}
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		logger.Warning(context.Background(), "CHECKPOINT_ALARM", "iterate checkpoint error", err)
+		return err
+	}
+	if len(staleItems) == 0 {
+		return nil
+	}
+	for _, key := range staleItems {
+		if err := p.deleteKey([]byte(key)); err != nil {
+			logger.Warning(context.Background(), "CHECKPOINT_ALARM", "delete stale checkpoint error, key", key, "error", err)
+			return err
+		}
+		logger.Info(context.Background(), "file is removed or expired, delete checkpoint", key)
+	}
+	return p.db.CompactRange(leveldbutil.Range{})
+}
+
+// areCheckpointFilesStale returns true if there are files and all of them are stale, see isCheckpointFileStale.
+func areCheckpointFilesStale(paths []string, ttl time.Duration) bool {
+	for _, path := range paths {
+		if !isCheckpointFileStale(path, ttl) {
+			return false
+		}
+	}
+	return len(paths) > 0
+}
+
+// isCheckpointFileStale returns true if the file does not exist or is not modified within ttl.
+// Files failed to stat for other reasons, e.g. permission denied, are not stale since they may be
+// readable again later.
+func isCheckpointFileStale(path string, ttl time.Duration) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	return ttl > 0 && time.Since(info.ModTime()) > ttl
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+)
+
+func Test_checkPointManager_Compact(t *testing.T) {
+	MkdirDataDir()
+	CheckPointManager.Init()
+
+	dir := t.TempDir()
+	fresh := filepath.Join(dir, "fresh.log")
+	expired := filepath.Join(dir, "expired.log")
+	removed := filepath.Join(dir, "removed.log")
+	// stat fails with ENOTDIR, the file is treated as temporarily unreadable
+	unreadable := filepath.Join(fresh, "unreadable.log")
+	for _, path := range []string{fresh, expired} {
+		if err := os.WriteFile(path, []byte("log"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(expired, old, old); err != nil {
+		t.Fatal(err)
+	}
+	*CheckPointFileTTL = 3600
+	defer func() { *CheckPointFileTTL = 30 * 24 * 3600 }()
+
+	// checkpoints of service_docker_stdout keep the offsets of the log files of all containers
+	stdoutCheckpoints := map[string][]string{
+		"compact_fresh":      {fresh, removed},
+		"compact_stale":      {expired, removed},
+		"compact_unreadable": {unreadable},
+	}
+	for configName, paths := range stdoutCheckpoints {
+		checkpointMap := make(map[string]helper.LogFileReaderCheckPoint)
+		// keyed by container id
+		for _, path := range paths {
+			checkpointMap[filepath.Base(path)] = helper.LogFileReaderCheckPoint{Path: path}
+		}
+		value, _ := json.Marshal(checkpointMap)
+		if err := CheckPointManager.SaveCheckpoint(configName, "service_docker_stdout_v2", value); err != nil {
+			t.Fatalf("checkPointManager.SaveCheckpoint() error = %v", err)
+		}
+	}
+	if err := CheckPointManager.SaveCheckpoint("compact_cursor", "cursor", []byte(removed)); err != nil {
+		t.Fatalf("checkPointManager.SaveCheckpoint() error = %v", err)
+	}
+	if err := CompactCheckpoints(); err != nil {
+		t.Errorf("CompactCheckpoints() error = %v", err)
+	}
+	for configName, removed := range map[string]bool{
+		"compact_fresh":      false,
+		"compact_stale":      true,
+		"compact_unreadable": false,
+	} {
+		_, err := CheckPointManager.GetCheckpoint(configName, "service_docker_stdout_v2")
+		if removed != (err != nil) {
+			t.Errorf("checkpoint of %s removed = %v, want %v", configName, err != nil, removed)
+		}
+		_ = CheckPointManager.DeleteCheckpoint(configName, "service_docker_stdout_v2")
+	}
+	if _, err := CheckPointManager.GetCheckpoint("compact_cursor", "cursor"); err != nil {
+		t.Errorf("checkpoint not belonging to files should be kept, error = %v", err)
+	}
+	_ = CheckPointManager.DeleteCheckpoint("compact_cursor", "cursor")
+}
@@ -18,12 +18,15 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	leveldbutil "github.com/syndtr/goleveldb/leveldb/util"
 
 	"github.com/alibaba/ilogtail/pkg/config"
 	"github.com/alibaba/ilogtail/pkg/logger"
@@ -41,8 +44,35 @@ type checkPointManager struct {
 	shutdown       chan struct{}
 	waitgroup      sync.WaitGroup
 	initFlag       bool
+	initLock       sync.Mutex
+	running        atomic.Bool
 	configCounter  map[string]int
 	cleanThreshold int
+	// tracked is the number of checkpoints in the db, counted when the db is opened and kept by putKey and
+	// deleteKey, which writeLock serializes.
+	tracked   atomic.Int64
+	writeLock sync.Mutex
+	// lastSave is the unix nano time of the latest successful save, or 0 if nothing is saved.
+	lastSave  atomic.Int64
+	lastError atomic.Pointer[checkpointError]
+	// diskSpaceAlarm limits alarms of checkpoints not saved for low disk space.
+	diskSpaceAlarm diskSpaceAlarmLimiter
+}
+
+type checkpointError struct {
+	err  error
+	time time.Time
+}
+
+// CheckpointReport is the status of the checkpoint manager.
+type CheckpointReport struct {
+	Running            bool
+	TrackedCheckpoints int
+	// LastSaveTime is zero if no checkpoint is saved since the agent started.
+	LastSaveTime time.Time
+	// LastError is the latest error of saving checkpoints, nil if there is none.
+	LastError     error
+	LastErrorTime time.Time
 }
 
 var CheckPointManager checkPointManager
@@ -53,11 +83,95 @@ func (p *checkPointManager) SaveCheckpoint(configName, key string, value []byte)
 	if p.db == nil {
 		return ErrCheckPointNotInit
 	}
-	err := p.db.Put([]byte(configName+"^"+key), value, nil)
+	// Refuse to write rather than corrupting the db when the disk is full.
+	if IsDiskSpaceLow() {
+		if alarm, suppressed := p.diskSpaceAlarm.admit(time.Now()); alarm {
+			logger.Warning(context.Background(), "CHECKPOINT_SAVE_ALARM", "disk space is low, skip saving checkpoint, key", key,
+				"skipped since last alarm", suppressed)
+		}
+		p.recordError(ErrDiskSpaceLow)
+		return ErrDiskSpaceLow
+	}
+	err := p.putKey([]byte(configName+"^"+key), value)
 	if err != nil {
 		logger.Error(context.Background(), "CHECKPOINT_SAVE_ALARM", "save checkpoint error, key", key, "error", err)
+		p.recordError(err)
+		return err
+	}
+	p.lastSave.Store(time.Now().UnixNano())
+	return nil
+}
+
+func (p *checkPointManager) recordError(err error) {
+	p.lastError.Store(&checkpointError{err: err, time: time.Now()})
+}
+
+// putKey writes the checkpoint, and counts it if it is new.
+func (p *checkPointManager) putKey(key []byte, value []byte) error {
+	p.writeLock.Lock()
+	defer p.writeLock.Unlock()
+	exists, err := p.db.Has(key, nil)
+	if err != nil {
+		return err
+	}
+	if err = p.db.Put(key, value, nil); err != nil {
+		return err
+	}
+	if !exists {
+		p.tracked.Add(1)
+	}
+	return nil
+}
+
+// deleteKey removes the checkpoint, and stops counting it if it exists.
+func (p *checkPointManager) deleteKey(key []byte) error {
+	p.writeLock.Lock()
+	defer p.writeLock.Unlock()
+	exists, err := p.db.Has(key, nil)
+	if err != nil || !exists {
+		return err
+	}
+	if err = p.db.Delete(key, nil); err != nil {
+		return err
+	}
+	p.tracked.Add(-1)
+	return nil
+}
+
+// countKeys returns the number of checkpoints in the db, it is only called when the db is opened.
+func (p *checkPointManager) countKeys() (int64, error) {
+	var count int64
+	iter := p.db.NewIterator(nil, nil)
+	for iter.Next() {
+		count++
+	}
+	iter.Release()
+	return count, iter.Error()
+}
+
+// Status returns whether the checkpoint manager is running, the number of checkpoints in the db,
+// and the result of the latest saves.
+func (p *checkPointManager) Status() CheckpointReport {
+	report := CheckpointReport{Running: p.IsRunning()}
+	if lastSave := p.lastSave.Load(); lastSave != 0 {
+		report.LastSaveTime = time.Unix(0, lastSave)
+	}
+	if lastError := p.lastError.Load(); lastError != nil {
+		report.LastError = lastError.err
+		report.LastErrorTime = lastError.time
 	}
-	return err
+	p.initLock.Lock()
+	defer p.initLock.Unlock()
+	if p.db != nil {
+		report.TrackedCheckpoints = int(p.tracked.Load())
+	}
+	return report
+}
+
+// CheckpointStatus returns the status of CheckPointManager, so that callers can alert when checkpoints
+// are not saved any more.
+func CheckpointStatus() CheckpointReport {
+	return CheckPointManager.Status()
 }
 
 func (p *checkPointManager) GetCheckpoint(configName, key string) ([]byte, error) {
@@ -71,17 +185,45 @@ func (p *checkPointManager) GetCheckpoint(configName, key string) ([]byte, error
 	return val, err
 }
 
+// listCheckpoints returns the checkpoints of the config, keyed by checkpoint key.
+func (p *checkPointManager) listCheckpoints(configName string) (map[string][]byte, error) {
+	if p.db == nil {
+		return nil, ErrCheckPointNotInit
+	}
+	prefix := configName + "^"
+	checkpoints := make(map[string][]byte)
+	iter := p.db.NewIterator(leveldbutil.BytesPrefix([]byte(prefix)), nil)
+	for iter.Next() {
+		checkpoints[string(iter.Key()[len(prefix):])] = append([]byte(nil), iter.Value()...)
+	}
+	iter.Release()
+	return checkpoints, iter.Error()
+}
+
 func (p *checkPointManager) DeleteCheckpoint(configName, key string) error {
 	if p.db == nil {
 		return ErrCheckPointNotInit
 	}
-	return p.db.Delete([]byte(configName+"^"+key), nil)
+	return p.deleteKey([]byte(configName + "^" + key))
 }
 
 func (p *checkPointManager) Init() error {
+	return p.initContext(context.Background())
+}
+
+// initContext works like Init, and gives up the db opened if ctx is done meanwhile, so that an abandoned
+// init does not hold the db lock against a retry. Any failure rolls back the partial state, see reset.
+func (p *checkPointManager) initContext(ctx context.Context) (err error) {
+	p.initLock.Lock()
+	defer p.initLock.Unlock()
 	if p.initFlag {
 		return nil
 	}
+	defer func() {
+		if err != nil {
+			p.reset()
+		}
+	}()
 	p.shutdown = make(chan struct{}, 1)
 	p.configCounter = make(map[string]int)
 	p.cleanThreshold = DefaultCleanThreshold
@@ -110,18 +252,58 @@ func (p *checkPointManager) Init() error {
 		logger.Error(context.Background(), "CHECKPOINT_ALARM", "recover db file error", err)
 		return err
 	}
+	if err = ctx.Err(); err != nil {
+		logger.Warning(context.Background(), "CHECKPOINT_ALARM", "init checkpoint aborted, close db file", dbPath)
+		return fmt.Errorf("init checkpoint manager: %w", err)
+	}
+	tracked, err := p.countKeys()
+	if err != nil {
+		logger.Error(context.Background(), "CHECKPOINT_ALARM", "count checkpoint error", err)
+		return err
+	}
+	p.tracked.Store(tracked)
 	p.initFlag = true
 	logger.Info(context.Background(), "init checkpoint", "success")
 	return nil
 }
 
+// close closes the db and resets the manager, so that it can be initialized again.
+// It must not be called while the manager is running.
+func (p *checkPointManager) close() {
+	p.initLock.Lock()
+	defer p.initLock.Unlock()
+	p.reset()
+}
+
+// reset closes the db if it is opened, and clears the state set by Init. The caller must hold initLock.
+func (p *checkPointManager) reset() {
+	if p.db != nil {
+		if err := p.db.Close(); err != nil {
+			logger.Warning(context.Background(), "CHECKPOINT_ALARM", "close checkpoint error", err)
+		}
+		p.db = nil
+	}
+	p.initFlag = false
+	p.tracked.Store(0)
+	p.shutdown = nil
+	p.configCounter = nil
+}
+
 func (p *checkPointManager) Stop() {
 	logger.Info(context.Background(), "checkpoint", "Stop")
-	if p.db == nil {
+	// Stopped twice, such as by Shutdown and StopBuiltInModulesConfig, the shutdown signal would block.
+	if p.db == nil || !p.running.Load() {
 		return
 	}
 	p.shutdown <- struct{}{}
 	p.waitgroup.Wait()
+	p.running.Store(false)
+	DiskSpaceWatchdog.Stop()
+}
+
+// IsRunning returns true if the checkpoint manager is started and not stopped.
+func (p *checkPointManager) IsRunning() bool {
+	return p.running.Load()
 }
 
 func (p *checkPointManager) Start() {
@@ -129,7 +311,9 @@ func (p *checkPointManager) Start() {
 	if p.db == nil {
 		return
 	}
+	DiskSpaceWatchdog.Start()
 	p.waitgroup.Add(1)
+	p.running.Store(true)
 	go p.run()
 }
 
@@ -141,6 +325,7 @@ func (p *checkPointManager) run() {
 			return
 		}
 		p.check()
+		_ = p.compact()
 	}
 }
 
@@ -152,6 +337,9 @@ func (p *checkPointManager) keyMatch(key []byte) bool {
 		return false
 	}
 	configName := keyStr[0:index]
+	if configName == quarantineCheckpointName {
+		return true
+	}
 	// configName in checkpoint is real config Name, while configName in LogtailConfig has suffix '/1' or '/2'
 	// since checkpoint is only used in input, so configName can only be 'realConfigName/1', meaning go pipeline with input
 	configName += "/1"
@@ -187,7 +375,7 @@ func (p *checkPointManager) check() {
 	for _, key := range cleanItems {
 		p.configCounter[key]++
 		if p.configCounter[key] > p.cleanThreshold {
-			_ = p.db.Delete([]byte(key), nil)
+			_ = p.deleteKey([]byte(key))
 			logger.Info(context.Background(), "no config, delete checkpoint", key)
 			delete(p.configCounter, key)
 		}
@@ -16,10 +16,15 @@ package pluginmanager
 
 import (
 	"context"
+	"errors"
+	"math"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/syndtr/goleveldb/leveldb"
+
 	"github.com/alibaba/ilogtail/pkg/config"
 )
 
@@ -140,3 +145,127 @@ func Test_checkPointManager_keyMatch(t *testing.T) {
 		LogtailConfigLock.Unlock()
 	})
 }
+
+func Test_checkPointManager_DiskSpaceLow(t *testing.T) {
+	MkdirDataDir()
+	CheckPointManager.Init()
+	*MinFreeDiskSpaceMB = math.MaxInt32
+	defer func() { *MinFreeDiskSpaceMB = 0 }()
+	CheckPointManager.Start()
+	if !IsDiskSpaceLow() {
+		t.Errorf("disk space should be low")
+	}
+	if err := CheckPointManager.SaveCheckpoint("1", "xx", []byte("xxxxx")); err != ErrDiskSpaceLow {
+		t.Errorf("checkPointManager.SaveCheckpoint() error = %v, want %v", err, ErrDiskSpaceLow)
+	}
+	CheckPointManager.Stop()
+
+	*MinFreeDiskSpaceMB = 1
+	CheckPointManager.Start()
+	if IsDiskSpaceLow() {
+		t.Errorf("disk space should not be low")
+	}
+	if err := CheckPointManager.SaveCheckpoint("1", "xx", []byte("xxxxx")); err != nil {
+		t.Errorf("checkPointManager.SaveCheckpoint() error = %v", err)
+	}
+	CheckPointManager.Stop()
+}
+
+func Test_checkPointManager_IsRunning(t *testing.T) {
+	MkdirDataDir()
+	CheckPointManager.Init()
+	CheckPointManager.Start()
+	if !CheckPointManager.IsRunning() {
+		t.Errorf("checkpoint manager should be running")
+	}
+	CheckPointManager.Stop()
+	if CheckPointManager.IsRunning() {
+		t.Errorf("checkpoint manager should not be running")
+	}
+}
+
+func Test_checkPointManager_Status(t *testing.T) {
+	MkdirDataDir()
+	CheckPointManager.Init()
+	CheckPointManager.Start()
+	defer CheckPointManager.Stop()
+	before := CheckpointStatus()
+	if !before.Running {
+		t.Errorf("checkpoint manager should be running")
+	}
+	// a checkpoint saved again is counted once
+	for i := 0; i < 2; i++ {
+		if err := CheckPointManager.SaveCheckpoint("status", "xx", []byte("xxxxx")); err != nil {
+			t.Errorf("checkPointManager.SaveCheckpoint() error = %v", err)
+		}
+	}
+	report := CheckpointStatus()
+	if report.TrackedCheckpoints != before.TrackedCheckpoints+1 {
+		t.Errorf("tracked checkpoints = %d, want %d", report.TrackedCheckpoints, before.TrackedCheckpoints+1)
+	}
+	if time.Since(report.LastSaveTime) > time.Minute {
+		t.Errorf("last save time %v is not updated", report.LastSaveTime)
+	}
+
+	*MinFreeDiskSpaceMB = math.MaxInt32
+	CheckPointManager.Stop()
+	CheckPointManager.Start()
+	_ = CheckPointManager.SaveCheckpoint("status", "yy", []byte("yyyyy"))
+	*MinFreeDiskSpaceMB = 0
+	report = CheckpointStatus()
+	if report.LastError != ErrDiskSpaceLow {
+		t.Errorf("last error = %v, want %v", report.LastError, ErrDiskSpaceLow)
+	}
+	if report.LastErrorTime.IsZero() {
+		t.Errorf("last error time should be set")
+	}
+	_ = CheckPointManager.DeleteCheckpoint("status", "xx")
+	if report = CheckpointStatus(); report.TrackedCheckpoints != before.TrackedCheckpoints {
+		t.Errorf("tracked checkpoints = %d, want %d", report.TrackedCheckpoints, before.TrackedCheckpoints)
+	}
+}
+
+func Test_checkPointManager_InitRollback(t *testing.T) {
+	MkdirDataDir()
+	CheckPointManager.close()
+	// the init is aborted after the db is opened
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := CheckPointManager.initContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("checkPointManager.initContext() error = %v, want %v", err, context.Canceled)
+	}
+	if CheckPointManager.db != nil || CheckPointManager.initFlag {
+		t.Errorf("checkpoint manager should be rolled back")
+	}
+	// the db lock is released
+	dbPath := filepath.Join(config.LoongcollectorGlobalConfig.LoongCollectorGoCheckPointDir, config.LoongcollectorGlobalConfig.LoongCollectorGoCheckPointFile)
+	db, err := leveldb.OpenFile(dbPath, nil)
+	if err != nil {
+		t.Fatalf("open checkpoint db error = %v", err)
+	}
+	_ = db.Close()
+
+	if err := CheckPointManager.Init(); err != nil {
+		t.Errorf("checkPointManager.Init() error = %v", err)
+	}
+	if err := CheckPointManager.SaveCheckpoint("rollback", "xx", []byte("xx")); err != nil {
+		t.Errorf("checkPointManager.SaveCheckpoint() error = %v", err)
+	}
+	_ = CheckPointManager.DeleteCheckpoint("rollback", "xx")
+}
+
+func Test_diskSpaceAlarmLimiter(t *testing.T) {
+	var limiter diskSpaceAlarmLimiter
+	now := time.Now()
+	if alarm, suppressed := limiter.admit(now); !alarm || suppressed != 0 {
+		t.Errorf("diskSpaceAlarmLimiter.admit() = %v, %v, want true, 0", alarm, suppressed)
+	}
+	for i := 0; i < 3; i++ {
+		if alarm, _ := limiter.admit(now.Add(time.Second)); alarm {
+			t.Errorf("diskSpaceAlarmLimiter.admit() should not alarm within the interval")
+		}
+	}
+	if alarm, suppressed := limiter.admit(now.Add(diskSpaceAlarmInterval)); !alarm || suppressed != 3 {
+		t.Errorf("diskSpaceAlarmLimiter.admit() = %v, %v, want true, 3", alarm, suppressed)
+	}
+}
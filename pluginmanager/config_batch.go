@@ -0,0 +1,113 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+// ErrInvalidConfigBatch is returned by ApplyConfigBatch when any config of the batch fails to build.
+var ErrInvalidConfigBatch = errors.New("invalid config batch")
+
+// ApplyConfigBatch builds all configs, keyed by config name with suffix, and applies them only if every
+// one of them builds successfully. Otherwise none of them is applied, the configs built are released, see
+// closeUnstartedConfig, and the errors of all failed configs are returned together.
+// A config replacing a running one keeps its project, logstore and logstore key, like Reload does.
+// New configs are swapped into LogtailConfig under one acquisition of LogtailConfigLock.
+func ApplyConfigBatch(configs map[string]string) error {
+	defer panicRecover("Run plugin")
+	configNames := make([]string, 0, len(configs))
+	for configName := range configs {
+		configNames = append(configNames, configName)
+	}
+	sort.Strings(configNames)
+
+	oldConfigs := make(map[string]*LogstoreConfig, len(configs))
+	LogtailConfigLock.RLock()
+	for _, configName := range configNames {
+		if oldConfig, exists := LogtailConfig[configName]; exists {
+			oldConfigs[configName] = oldConfig
+		}
+	}
+	LogtailConfigLock.RUnlock()
+
+	newConfigs := make([]*LogstoreConfig, 0, len(configs))
+	var errMsgs []string
+	for _, configName := range configNames {
+		var project, logstore string
+		var logstoreKey int64
+		if oldConfig, exists := oldConfigs[configName]; exists {
+			project, logstore, logstoreKey = oldConfig.ProjectName, oldConfig.LogstoreName, oldConfig.LogstoreKey
+		}
+		err := checkConfigPluginTypes(configs[configName])
+		if err == nil {
+			var newConfig *LogstoreConfig
+			if newConfig, err = buildLogstoreConfig(project, logstore, configName, logstoreKey, configs[configName]); err == nil {
+				newConfigs = append(newConfigs, newConfig)
+				continue
+			}
+		}
+		errMsgs = append(errMsgs, fmt.Sprintf("%s: %v", configName, err))
+	}
+	if len(errMsgs) > 0 {
+		for _, newConfig := range newConfigs {
+			closeUnstartedConfig(newConfig)
+		}
+		return fmt.Errorf("%w: %s", ErrInvalidConfigBatch, strings.Join(errMsgs, "; "))
+	}
+
+	stateChanges := make(map[string]ConfigState, len(oldConfigs))
+	for _, newConfig := range newConfigs {
+		oldConfig, exists := oldConfigs[newConfig.ConfigNameWithSuffix]
+		if !exists {
+			adoptUnsendBuffer(newConfig)
+			continue
+		}
+		if timeoutStop(oldConfig, false) {
+			newConfig.PluginRunner.Merge(oldConfig.PluginRunner)
+			stateChanges[newConfig.ConfigNameWithSuffix] = ConfigStateStopped
+		} else {
+			stateChanges[newConfig.ConfigNameWithSuffix] = ConfigStateDisabled
+		}
+	}
+	for _, newConfig := range newConfigs {
+		newConfig.Start()
+	}
+
+	LogtailConfigLock.Lock()
+	for _, newConfig := range newConfigs {
+		configName := newConfig.ConfigNameWithSuffix
+		if stateChanges[configName] == ConfigStateStopped {
+			DeleteLogstoreConfig(oldConfigs[configName], true)
+		}
+		LogtailConfig[configName] = newConfig
+		recordConfigStarted()
+	}
+	LogtailConfigLock.Unlock()
+	for configName, state := range stateChanges {
+		notifyConfigState(configName, state)
+	}
+	for _, newConfig := range newConfigs {
+		notifyConfigStarted(newConfig.ConfigNameWithSuffix, newConfig.PluginRunner.IsWithInputPlugin())
+	}
+	logger.Info(context.Background(), "apply config batch", "success", "configs", configNames)
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+// Configs whose start is deferred until all configs in DependsOn of their global config are running,
+// or until their quarantine is over, keyed by config name with suffix. They are started once another config
+// starts or the quarantine is over, see startDeferredConfigs and deferQuarantinedStart.
+var deferredConfigsLock sync.Mutex
+var deferredConfigs = make(map[string]*LogstoreConfig)
+
+// dependsOn returns true if dependency names the config, with or without suffix.
+func dependsOn(dependency string, config *LogstoreConfig) bool {
+	return config.ConfigNameWithSuffix == dependency || config.ConfigName == dependency
+}
+
+// pendingDependencies returns the dependencies of the config which are not running.
+func pendingDependencies(config *LogstoreConfig) []string {
+	var pending []string
+	for _, dependency := range config.GlobalConfig.DependsOn {
+		running := false
+		RangeConfigs(func(_ string, lc *LogstoreConfig) bool {
+			running = dependsOn(dependency, lc)
+			return !running
+		})
+		if !running {
+			pending = append(pending, dependency)
+		}
+	}
+	return pending
+}
+
+// deferStart defers the config loaded to be started if any of its dependencies is not running.
+// A deferred version of the config is replaced. The config is rejected with ErrDependencyCycle if it
+// depends on itself through deferred configs, as none of them would ever start.
+// @return true if the config is deferred.
+func deferStart(config *LogstoreConfig) (bool, error) {
+	pending := pendingDependencies(config)
+	if len(pending) == 0 {
+		return false, nil
+	}
+	deferredConfigsLock.Lock()
+	defer deferredConfigsLock.Unlock()
+	if cycle := dependencyCycle(config); cycle != nil {
+		err := fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+		logger.Error(context.Background(), "CONFIG_DEPENDENCY_ALARM", "refuse to start config", err)
+		discardUnstartedConfig(config)
+		return false, err
+	}
+	if previous, exists := deferredConfigs[config.ConfigNameWithSuffix]; exists && previous != config {
+		discardUnstartedConfig(previous)
+	}
+	deferredConfigs[config.ConfigNameWithSuffix] = config
+	logger.Info(context.Background(), "defer config start until dependencies start", config.ConfigNameWithSuffix, "pending", pending)
+	return true, nil
+}
+
+// dependencyCycle returns the configs through which the config depends on itself, among the deferred
+// configs, or nil if there is no cycle. The caller must hold deferredConfigsLock.
+func dependencyCycle(config *LogstoreConfig) []string {
+	visited := make(map[*LogstoreConfig]bool)
+	var path []string
+	var visit func(lc *LogstoreConfig) bool
+	visit = func(lc *LogstoreConfig) bool {
+		path = append(path, lc.ConfigNameWithSuffix)
+		for _, dependency := range lc.GlobalConfig.DependsOn {
+			if dependsOn(dependency, config) {
+				path = append(path, config.ConfigNameWithSuffix)
+				return true
+			}
+			for _, deferred := range sortedDeferredConfigs() {
+				if deferred.ConfigNameWithSuffix == config.ConfigNameWithSuffix || visited[deferred] || !dependsOn(dependency, deferred) {
+					continue
+				}
+				visited[deferred] = true
+				if visit(deferred) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(config) {
+		return path
+	}
+	return nil
+}
+
+// sortedDeferredConfigs returns the deferred configs by name. The caller must hold deferredConfigsLock.
+func sortedDeferredConfigs() []*LogstoreConfig {
+	configs := make([]*LogstoreConfig, 0, len(deferredConfigs))
+	for _, config := range deferredConfigs {
+		configs = append(configs, config)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].ConfigNameWithSuffix < configs[j].ConfigNameWithSuffix
+	})
+	return configs
+}
+
+// startDeferredConfigs starts the deferred configs whose dependencies are all running and which are not
+// quarantined, including those depending on the configs started here.
+func startDeferredConfigs() {
+	for {
+		var ready *LogstoreConfig
+		deferredConfigsLock.Lock()
+		for _, config := range sortedDeferredConfigs() {
+			if len(pendingDependencies(config)) == 0 && !isQuarantined(config.ConfigNameWithSuffix) {
+				ready = config
+				delete(deferredConfigs, config.ConfigNameWithSuffix)
+				break
+			}
+		}
+		deferredConfigsLock.Unlock()
+		if ready == nil {
+			return
+		}
+		// clear the quarantine which is over
+		_ = checkQuarantine(ready.ConfigNameWithSuffix)
+		logger.Info(context.Background(), "start deferred config", ready.ConfigNameWithSuffix)
+		if err := startConfig(ready, ready.PluginRunner.IsWithInputPlugin(), time.Now()); err != nil {
+			logger.Error(context.Background(), "CONFIG_DEPENDENCY_ALARM", "start deferred config fail", ready.ConfigNameWithSuffix, "error", err)
+			discardUnstartedConfig(ready)
+			releaseSiblingPipeline(ready, err)
+		}
+	}
+}
+
+// dropDeferredConfig discards the deferred config with the name with suffix.
+// @return false if the config is not deferred.
+func dropDeferredConfig(configName string) bool {
+	deferredConfigsLock.Lock()
+	defer deferredConfigsLock.Unlock()
+	config, exists := deferredConfigs[configName]
+	if exists {
+		delete(deferredConfigs, configName)
+		discardUnstartedConfig(config)
+	}
+	return exists
+}
+
+// dropDeferredConfigs discards all deferred configs matching withInput.
+func dropDeferredConfigs(withInput bool) {
+	deferredConfigsLock.Lock()
+	defer deferredConfigsLock.Unlock()
+	for configName, config := range deferredConfigs {
+		if config.PluginRunner.IsWithInputPlugin() == withInput {
+			delete(deferredConfigs, configName)
+			discardUnstartedConfig(config)
+		}
+	}
+}
+
+// deferredConfig returns the deferred config with the name with suffix, or nil.
+func deferredConfig(configName string) *LogstoreConfig {
+	deferredConfigsLock.Lock()
+	defer deferredConfigsLock.Unlock()
+	return deferredConfigs[configName]
+}
+
+// DeferredConfigs returns the names with suffix of the configs waiting for their dependencies to start.
+func DeferredConfigs() []string {
+	deferredConfigsLock.Lock()
+	defer deferredConfigsLock.Unlock()
+	names := make([]string, 0, len(deferredConfigs))
+	for _, config := range sortedDeferredConfigs() {
+		names = append(names, config.ConfigNameWithSuffix)
+	}
+	return names
+}
+
+// discardUnstartedConfig releases the config loaded but never started, see closeUnstartedConfig.
+// A config reused from the warm pool is still running, and goes back to the pool.
+func discardUnstartedConfig(config *LogstoreConfig) {
+	if config.warmReused {
+		unloadWarmReusedConfig(config)
+		return
+	}
+	closeUnstartedConfig(config)
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const drainCheckInterval = 10 * time.Millisecond
+
+// StopAllPipelinesWithDrain works like StopAllPipelines, but before stopping it asks the flushers of
+// the configs to flush urgently and waits up to drainTimeout for the queued data to be flushed.
+// Configs not drained in time are stopped as usual.
+func StopAllPipelinesWithDrain(withInput bool, drainTimeout time.Duration) error {
+	defer panicRecover("Run plugin")
+	// if request is withinput=true, only drain configs with input, otherwise only drain configs without input.
+	toDrain := make([]*LogstoreConfig, 0)
+	RangeConfigs(func(_ string, logstoreConfig *LogstoreConfig) bool {
+		if logstoreConfig.PluginRunner.IsWithInputPlugin() == withInput {
+			toDrain = append(toDrain, logstoreConfig)
+		}
+		return true
+	})
+
+	for _, logstoreConfig := range toDrain {
+		setFlushersUrgent(logstoreConfig, true)
+	}
+	deadline := time.Now().Add(drainTimeout)
+	for _, logstoreConfig := range toDrain {
+		if !drainConfig(logstoreConfig, deadline) {
+			logger.Warning(logstoreConfig.Context.GetRuntimeContext(), "CONFIG_DRAIN_ALARM",
+				"data is not drained before stop", "timeout", drainTimeout)
+			continue
+		}
+		emitLifecycleEvent(logstoreConfig.ConfigNameWithSuffix, LifecycleDrained)
+	}
+	return StopAllPipelines(withInput)
+}
+
+// drainConfig waits until data queued in the config is flushed or the deadline is reached.
+// @return true if the config is drained.
+func drainConfig(config *LogstoreConfig, deadline time.Time) bool {
+	for !isConfigDrained(config) {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(drainCheckInterval)
+	}
+	return true
+}
+
+// isConfigDrained returns true if the queues of the config are empty and all flushers are ready.
+func isConfigDrained(config *LogstoreConfig) bool {
+	switch runner := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		if len(runner.LogsChan) > 0 || len(runner.LogGroupsChan) > 0 {
+			return false
+		}
+		for _, flusher := range runner.FlusherPlugins {
+			if !flusher.Flusher.IsReady(config.ProjectName, config.LogstoreName, config.LogstoreKey) {
+				return false
+			}
+		}
+	case *pluginv2Runner:
+		if len(runner.InputPipeContext.Collector().Observe()) > 0 || len(runner.AggregatePipeContext.Collector().Observe()) > 0 {
+			return false
+		}
+		for _, flusher := range runner.FlusherPlugins {
+			if !flusher.Flusher.IsReady(config.ProjectName, config.LogstoreName, config.LogstoreKey) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func setFlushersUrgent(config *LogstoreConfig, flag bool) {
+	switch runner := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		for _, flusher := range runner.FlusherPlugins {
+			flusher.Flusher.SetUrgent(flag)
+		}
+	case *pluginv2Runner:
+		for _, flusher := range runner.FlusherPlugins {
+			flusher.Flusher.SetUrgent(flag)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"sync"
+	"time"
+)
+
+// maxConfigEvents is the number of latest events of all configs kept by the event log.
+const maxConfigEvents = 1024
+
+// ConfigEventKind is the kind of a ConfigEvent.
+type ConfigEventKind string
+
+const (
+	ConfigEventLoaded    ConfigEventKind = "loaded"
+	ConfigEventStarted   ConfigEventKind = "started"
+	ConfigEventStopped   ConfigEventKind = "stopped"
+	ConfigEventDisabled  ConfigEventKind = "disabled"
+	ConfigEventRecovered ConfigEventKind = "recovered"
+)
+
+// ConfigEvent is an entry of the config event log, ConfigName is the config name with suffix.
+type ConfigEvent struct {
+	ConfigName string
+	Kind       ConfigEventKind
+	Timestamp  time.Time
+}
+
+// configEvents is a ring of the latest maxConfigEvents events.
+var configEventsLock sync.Mutex
+var configEvents = make([]ConfigEvent, 0, maxConfigEvents)
+var configEventsNext int
+
+func recordConfigEvent(configName string, kind ConfigEventKind) {
+	event := ConfigEvent{ConfigName: configName, Kind: kind, Timestamp: time.Now()}
+	configEventsLock.Lock()
+	defer configEventsLock.Unlock()
+	if len(configEvents) < maxConfigEvents {
+		configEvents = append(configEvents, event)
+	} else {
+		configEvents[configEventsNext] = event
+	}
+	configEventsNext = (configEventsNext + 1) % maxConfigEvents
+}
+
+// ConfigEventLog returns the latest limit events of the config, oldest first.
+// All kept events of the config are returned if limit is not positive.
+func ConfigEventLog(configName string, limit int) []ConfigEvent {
+	configEventsLock.Lock()
+	ordered := make([]ConfigEvent, 0, len(configEvents))
+	if len(configEvents) == maxConfigEvents {
+		ordered = append(ordered, configEvents[configEventsNext:]...)
+		ordered = append(ordered, configEvents[:configEventsNext]...)
+	} else {
+		ordered = append(ordered, configEvents...)
+	}
+	configEventsLock.Unlock()
+
+	events := make([]ConfigEvent, 0)
+	for _, event := range ordered {
+		if event.ConfigName == configName {
+			events = append(events, event)
+		}
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return events
+}
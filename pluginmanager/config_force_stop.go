@@ -0,0 +1,130 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+// forceStopGracePeriod is the time ForceStop waits for a config to stop after releasing it.
+var forceStopGracePeriod = 5 * time.Second
+
+const forceStopCheckInterval = 50 * time.Millisecond
+
+// ForceStop stops the given config like Stop, but the config which does not stop in time is released
+// forcibly instead of being left to leak: its runtime context is cancelled, and plugins implementing
+// io.Closer are closed, so that plugins blocked on files or network connections, or ignoring their
+// stop signal while watching the context, are unblocked. Configs of the name already moved into
+// DisabledLogtailConfig are released too. ConfigName is resolved as in Stop.
+// It is best-effort, as goroutines can not be killed. Plugins which still do not exit after
+// forceStopGracePeriod are logged and returned with ErrForceStopIncomplete, and their config stays in
+// DisabledLogtailConfig until it stops. Unsent data is kept as if the config is not removed.
+func ForceStop(configName string) (err error) {
+	defer panicRecover("Run plugin")
+	if configName, err = resolveConfigName(configName); err != nil {
+		return err
+	}
+	LogtailConfigLock.RLock()
+	config, exists := LogtailConfig[configName]
+	LogtailConfigLock.RUnlock()
+	if exists {
+		hasStopped := timeoutStop(config, false)
+		removeStoppedConfig(configName, config, false, hasStopped)
+		if hasStopped {
+			return nil
+		}
+	}
+
+	var disabled []*LogstoreConfig
+	DisabledLogtailConfigLock.RLock()
+	for _, config := range DisabledLogtailConfig {
+		if config.ConfigNameWithSuffix == configName {
+			disabled = append(disabled, config)
+		}
+	}
+	DisabledLogtailConfigLock.RUnlock()
+	if !exists && len(disabled) == 0 {
+		return fmt.Errorf("%w: %s", ErrConfigNotFound, configName)
+	}
+	var unexited []string
+	for _, config := range disabled {
+		unexited = append(unexited, forceRelease(config)...)
+	}
+	if len(unexited) > 0 {
+		return fmt.Errorf("%w: %s, plugins not exited %v", ErrForceStopIncomplete, configName, unexited)
+	}
+	return nil
+}
+
+// forceRelease cancels the runtime context of the disabled config and closes its plugins implementing
+// io.Closer, then waits forceStopGracePeriod for it to stop, see ForceStop.
+// @return types of plugins which have not exited, or nil if the config stops.
+func forceRelease(config *LogstoreConfig) []string {
+	logger.Warning(config.Context.GetRuntimeContext(), "CONFIG_FORCE_STOP_ALARM", "release config forcibly",
+		"leaked goroutines", runningGoroutines(config.PluginRunner))
+	cancelRuntimeContext(config)
+	DisabledLogtailConfigLock.RLock()
+	if _, exists := DisabledLogtailConfig[config.generation]; exists {
+		config.PluginRunner.ForEachPlugin(func(plugin Plugin) {
+			if closer, ok := plugin.pluginInstance().(io.Closer); ok {
+				// Close may block as well, the config is not waited for it.
+				go func(pluginType string) {
+					if err := closer.Close(); err != nil {
+						logger.Warning(config.Context.GetRuntimeContext(), "CONFIG_FORCE_STOP_ALARM", "close plugin error", pluginType, "error", err)
+					}
+				}(plugin.PluginType())
+			}
+		})
+	}
+	DisabledLogtailConfigLock.RUnlock()
+
+	deadline := time.Now().Add(forceStopGracePeriod)
+	for {
+		DisabledLogtailConfigLock.RLock()
+		if _, exists := DisabledLogtailConfig[config.generation]; !exists {
+			DisabledLogtailConfigLock.RUnlock()
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			unexited := unexitedPlugins(config)
+			DisabledLogtailConfigLock.RUnlock()
+			logger.Error(config.Context.GetRuntimeContext(), "CONFIG_FORCE_STOP_ALARM",
+				"plugins do not exit after force stop, goroutine might leak", "plugins", unexited)
+			return unexited
+		}
+		DisabledLogtailConfigLock.RUnlock()
+		time.Sleep(forceStopCheckInterval)
+	}
+}
+
+// unexitedPlugins returns types of plugins of the config which have not exited: the plugin hanging in Stop,
+// and plugins of the stages whose goroutines are still running.
+func unexitedPlugins(config *LogstoreConfig) []string {
+	var plugins []string
+	if stopping, _ := config.stoppingPlugin.Load().(string); stopping != "" {
+		plugins = append(plugins, stopping)
+	}
+	controls := runnerControls(config.PluginRunner)
+	config.PluginRunner.ForEachPlugin(func(plugin Plugin) {
+		if control := controls[plugin.Category()]; control != nil && control.Running() > 0 {
+			plugins = append(plugins, plugin.PluginType())
+		}
+	})
+	return plugins
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+// liveGlobalConfigFields are fields of GlobalConfig which can be applied to a running config.
+// Others, such as queue sizes, are baked into the plugins when the config is built.
+var liveGlobalConfigFields = map[string]struct{}{
+	"FlushIntervalMs":              {},
+	"DrainTimeoutMs":               {},
+	"BackpressureWatermarkPercent": {},
+}
+
+// diffGlobalConfig returns the names of fields which differ between the two global configs,
+// and whether all of them can be applied live. Nil and empty maps are considered equal.
+func diffGlobalConfig(oldConfig, newConfig *config.GlobalConfig) (changed []string, live bool) {
+	live = true
+	oldValue := reflect.ValueOf(oldConfig).Elem()
+	newValue := reflect.ValueOf(newConfig).Elem()
+	for i := 0; i < oldValue.NumField(); i++ {
+		oldField, newField := oldValue.Field(i), newValue.Field(i)
+		if oldField.Kind() == reflect.Map && oldField.Len() == 0 && newField.Len() == 0 {
+			continue
+		}
+		if reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+			continue
+		}
+		name := oldValue.Type().Field(i).Name
+		changed = append(changed, name)
+		if _, ok := liveGlobalConfigFields[name]; !ok {
+			live = false
+		}
+	}
+	return changed, live
+}
+
+// reloadGlobalConfig applies the global config of newConfig to the running oldConfig in place,
+// if newConfig differs from it only in fields of liveGlobalConfigFields. A reload without changes
+// still restarts the config.
+// @return true if the reload is done, otherwise the config should be reloaded by stop and start.
+func reloadGlobalConfig(oldConfig, newConfig *LogstoreConfig) bool {
+	if oldConfig.pluginsHash != newConfig.pluginsHash || oldConfig.Version != newConfig.Version {
+		return false
+	}
+	changed, live := diffGlobalConfig(oldConfig.GlobalConfig, newConfig.GlobalConfig)
+	if len(changed) == 0 {
+		return false
+	}
+	if !live {
+		logger.Info(oldConfig.Context.GetRuntimeContext(), "reload global config", "not live", "changed", changed)
+		return false
+	}
+	oldConfig.GlobalConfig = newConfig.GlobalConfig
+	oldConfig.configDetailHash = newConfig.configDetailHash
+	flushInterval := time.Millisecond * time.Duration(newConfig.GlobalConfig.FlushIntervalMs)
+	switch r := oldConfig.PluginRunner.(type) {
+	case *pluginv1Runner:
+		for _, flusher := range r.FlusherPlugins {
+			flusher.Interval = flushInterval
+		}
+	case *pluginv2Runner:
+		for _, flusher := range r.FlusherPlugins {
+			flusher.Interval = flushInterval
+		}
+	}
+	logger.Info(oldConfig.Context.GetRuntimeContext(), "reload global config", "live", "changed", changed)
+	return true
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+)
+
+func TestDiffGlobalConfig(t *testing.T) {
+	oldConfig := config.LoongcollectorGlobalConfig
+	newConfig := oldConfig
+	newConfig.PipelineMetaTagKey = map[string]string{}
+	changed, live := diffGlobalConfig(&oldConfig, &newConfig)
+	assert.Empty(t, changed)
+	assert.True(t, live)
+
+	newConfig.FlushIntervalMs = oldConfig.FlushIntervalMs + 1
+	newConfig.DrainTimeoutMs = oldConfig.DrainTimeoutMs + 1
+	changed, live = diffGlobalConfig(&oldConfig, &newConfig)
+	assert.Equal(t, []string{"FlushIntervalMs", "DrainTimeoutMs"}, changed)
+	assert.True(t, live)
+
+	newConfig.DefaultLogQueueSize = oldConfig.DefaultLogQueueSize + 1
+	changed, live = diffGlobalConfig(&oldConfig, &newConfig)
+	assert.Equal(t, []string{"FlushIntervalMs", "DefaultLogQueueSize", "DrainTimeoutMs"}, changed)
+	assert.False(t, live)
+}
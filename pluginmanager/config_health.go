@@ -0,0 +1,263 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var QueueHighWatermarkPercent = flag.Int("QueueHighWatermarkPercent", 80, "usage of a pipeline queue above which the config is reported unhealthy, percent")
+var StalledInputSeconds = flag.Int("StalledInputSeconds", 300, "duration without any record from inputs after which the config is reported unhealthy, second")
+var RecentPanicMinutes = flag.Int("RecentPanicMinutes", 10, "duration within which a recovered panic makes the config reported unhealthy, minute")
+
+// Reasons for which a config is unhealthy.
+const (
+	ConfigHealthReasonStopTimeout        = "stop_timeout"
+	ConfigHealthReasonFlusherNotReady    = "flusher_not_ready"
+	ConfigHealthReasonQueueOverWatermark = "queue_over_watermark"
+	ConfigHealthReasonRecentPanic        = "recent_panic"
+	ConfigHealthReasonInputStalled       = "input_stalled"
+)
+
+// ConfigHealthReason explains why a config is unhealthy, with the values supporting it.
+type ConfigHealthReason struct {
+	Reason string
+	Values map[string]string
+}
+
+// ConfigHealthReport describes the health of a config.
+type ConfigHealthReport struct {
+	ConfigNameWithSuffix string
+	Healthy              bool
+	Reasons              []ConfigHealthReason
+}
+
+// StuckConfig is a config disabled because it did not stop in time.
+type StuckConfig struct {
+	ConfigNameWithSuffix string
+	Generation           int64
+	StuckFor             time.Duration
+}
+
+// HealthReport is a snapshot of the plugin manager for liveness and readiness probes.
+type HealthReport struct {
+	RunningConfigs    int
+	DisabledConfigs   int
+	StuckConfigs      []StuckConfig
+	CheckPointRunning bool
+}
+
+// ConfigHealthStatus is the liveness of a running config, reported by CollectHealth.
+type ConfigHealthStatus struct {
+	ConfigName      string    `json:"config_name"`
+	Builtin         bool      `json:"builtin"`
+	WithInput       bool      `json:"with_input"`
+	LastFlushTime   time.Time `json:"last_flush_time"` // zero if nothing is flushed yet
+	InputQueueDepth int       `json:"input_queue_depth"`
+	FlushQueueDepth int       `json:"flush_queue_depth"`
+}
+
+// CollectHealth reports the running built-in configs and the configs in LogtailConfig, sorted by name.
+func CollectHealth() []ConfigHealthStatus {
+	var report []ConfigHealthStatus
+	builtinConfigLock.Lock()
+	for _, c := range builtinConfigs {
+		if c.config != nil {
+			report = append(report, configHealthStatus(c.config, true))
+		}
+	}
+	builtinConfigLock.Unlock()
+
+	configs := make([]ConfigHealthStatus, 0)
+	RangeConfigs(func(_ string, config *LogstoreConfig) bool {
+		if config.PluginRunner != nil {
+			configs = append(configs, configHealthStatus(config, false))
+		}
+		return true
+	})
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].ConfigName < configs[j].ConfigName
+	})
+	return append(report, configs...)
+}
+
+func configHealthStatus(config *LogstoreConfig, builtin bool) ConfigHealthStatus {
+	status := ConfigHealthStatus{
+		ConfigName: config.ConfigNameWithSuffix,
+		Builtin:    builtin,
+		WithInput:  config.PluginRunner.IsWithInputPlugin(),
+	}
+	var counters *recordCounters
+	switch runner := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		counters = runner.recordCounters
+		status.InputQueueDepth = len(runner.LogsChan)
+		status.FlushQueueDepth = len(runner.LogGroupsChan)
+	case *pluginv2Runner:
+		counters = runner.recordCounters
+		status.InputQueueDepth = len(runner.InputPipeContext.Collector().Observe())
+		status.FlushQueueDepth = len(runner.AggregatePipeContext.Collector().Observe())
+	}
+	if counters != nil {
+		status.LastFlushTime = counters.lastFlushTime()
+	}
+	return status
+}
+
+// HealthSnapshot returns the number of running and disabled configs, how long each disabled config
+// has been stuck, and whether the checkpoint manager is running.
+func HealthSnapshot() HealthReport {
+	report := HealthReport{CheckPointRunning: CheckPointManager.IsRunning()}
+	LogtailConfigLock.RLock()
+	report.RunningConfigs = len(LogtailConfig)
+	LogtailConfigLock.RUnlock()
+
+	DisabledLogtailConfigLock.RLock()
+	report.DisabledConfigs = len(DisabledLogtailConfig)
+	for generation, config := range DisabledLogtailConfig {
+		report.StuckConfigs = append(report.StuckConfigs, StuckConfig{
+			ConfigNameWithSuffix: config.ConfigNameWithSuffix,
+			Generation:           generation,
+			StuckFor:             time.Since(config.disabledTime),
+		})
+	}
+	DisabledLogtailConfigLock.RUnlock()
+	sort.Slice(report.StuckConfigs, func(i, j int) bool {
+		return report.StuckConfigs[i].StuckFor > report.StuckConfigs[j].StuckFor
+	})
+	return report
+}
+
+// ConfigHealth reports whether the config is healthy, and the reasons if not.
+// Instances of the config which did not stop in time are reported as well.
+func ConfigHealth(configName string) (*ConfigHealthReport, error) {
+	report := &ConfigHealthReport{ConfigNameWithSuffix: configName}
+	DisabledLogtailConfigLock.RLock()
+	for generation, config := range DisabledLogtailConfig {
+		if config.ConfigNameWithSuffix == configName {
+			report.addReason(ConfigHealthReasonStopTimeout,
+				"generation", strconv.FormatInt(generation, 10),
+				"disabled_seconds", strconv.FormatInt(int64(time.Since(config.disabledTime).Seconds()), 10))
+		}
+	}
+	DisabledLogtailConfigLock.RUnlock()
+
+	LogtailConfigLock.RLock()
+	config, exists := LogtailConfig[configName]
+	LogtailConfigLock.RUnlock()
+	if !exists && len(report.Reasons) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrConfigNotFound, configName)
+	}
+	if exists && config.PluginRunner != nil {
+		report.checkRunner(config)
+		report.checkPanics(config)
+	}
+	report.Healthy = len(report.Reasons) == 0
+	return report, nil
+}
+
+func (r *ConfigHealthReport) addReason(reason string, keyValues ...string) {
+	values := make(map[string]string, len(keyValues)/2)
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		values[keyValues[i]] = keyValues[i+1]
+	}
+	r.Reasons = append(r.Reasons, ConfigHealthReason{Reason: reason, Values: values})
+}
+
+func (r *ConfigHealthReport) checkRunner(config *LogstoreConfig) {
+	switch runner := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		for _, flusher := range runner.FlusherPlugins {
+			if !flusher.Flusher.IsReady(config.ProjectName, config.LogstoreName, config.LogstoreKey) {
+				r.addReason(ConfigHealthReasonFlusherNotReady, "flusher", flusher.pluginType)
+			}
+		}
+		r.checkQueue("input", len(runner.LogsChan), cap(runner.LogsChan))
+		r.checkQueue("flush", len(runner.LogGroupsChan), cap(runner.LogGroupsChan))
+		r.checkInput(config, runner.recordCounters)
+	case *pluginv2Runner:
+		for _, flusher := range runner.FlusherPlugins {
+			if !flusher.Flusher.IsReady(config.ProjectName, config.LogstoreName, config.LogstoreKey) {
+				r.addReason(ConfigHealthReasonFlusherNotReady, "flusher", flusher.pluginType)
+			}
+		}
+		inputChan := runner.InputPipeContext.Collector().Observe()
+		r.checkQueue("input", len(inputChan), cap(inputChan))
+		flushChan := runner.AggregatePipeContext.Collector().Observe()
+		r.checkQueue("flush", len(flushChan), cap(flushChan))
+		r.checkInput(config, runner.recordCounters)
+	}
+}
+
+func (r *ConfigHealthReport) checkQueue(queue string, length int, capacity int) {
+	if capacity == 0 || length*100 < capacity**QueueHighWatermarkPercent {
+		return
+	}
+	r.addReason(ConfigHealthReasonQueueOverWatermark,
+		"queue", queue,
+		"length", strconv.Itoa(length),
+		"capacity", strconv.Itoa(capacity))
+}
+
+func (r *ConfigHealthReport) checkInput(config *LogstoreConfig, counters *recordCounters) {
+	if counters == nil || !config.PluginRunner.IsWithInputPlugin() {
+		return
+	}
+	idle := time.Since(counters.lastInputTime())
+	if idle < time.Duration(*StalledInputSeconds)*time.Second {
+		return
+	}
+	r.addReason(ConfigHealthReasonInputStalled, "idle_seconds", strconv.FormatInt(int64(idle.Seconds()), 10))
+}
+
+// checkPanics reports panics recovered recently in goroutines of the config.
+// Panics in plugins are recorded with the plugin description, so a panic in another config
+// with a plugin of the same description is reported as well.
+func (r *ConfigHealthReport) checkPanics(config *LogstoreConfig) {
+	sources := map[string]struct{}{config.ConfigName: {}}
+	switch runner := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		for _, service := range runner.ServicePlugins {
+			sources[service.Input.Description()] = struct{}{}
+		}
+		for _, aggregator := range runner.AggregatorPlugins {
+			sources[aggregator.Aggregator.Description()] = struct{}{}
+		}
+	case *pluginv2Runner:
+		for _, service := range runner.ServicePlugins {
+			sources[service.Input.Description()] = struct{}{}
+		}
+	}
+	since := time.Now().Add(-time.Duration(*RecentPanicMinutes) * time.Minute)
+	count := 0
+	var last PanicRecord
+	for _, record := range RecentPanics() {
+		if _, ok := sources[record.PluginType]; ok && record.Time.After(since) {
+			count++
+			last = record
+		}
+	}
+	if count > 0 {
+		r.addReason(ConfigHealthReasonRecentPanic,
+			"count", strconv.Itoa(count),
+			"last_source", last.PluginType,
+			"last_message", last.Message,
+			"last_time", last.Time.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"sync"
+	"time"
+)
+
+// lifecycleEventBufferSize is the capacity of each subscription, events are dropped when it is full.
+const lifecycleEventBufferSize = 64
+
+// LifecycleEventKind is the transition reported by a LifecycleEvent.
+type LifecycleEventKind int
+
+const (
+	// LifecycleStarted means the config is started and added to LogtailConfig.
+	LifecycleStarted LifecycleEventKind = iota
+	// LifecycleStopped means the config is stopped, including a disabled config which finally stopped.
+	LifecycleStopped
+	// LifecycleDisabled means the config did not stop in time and was moved into DisabledLogtailConfig.
+	LifecycleDisabled
+	// LifecycleDrained means the queued data of the config is flushed before it is stopped.
+	LifecycleDrained
+)
+
+func (k LifecycleEventKind) String() string {
+	switch k {
+	case LifecycleStarted:
+		return "started"
+	case LifecycleStopped:
+		return "stopped"
+	case LifecycleDisabled:
+		return "disabled"
+	case LifecycleDrained:
+		return "drained"
+	default:
+		return "unknown"
+	}
+}
+
+// LifecycleEvent is a transition of a config, ConfigName is the config name with suffix.
+type LifecycleEvent struct {
+	ConfigName string
+	Kind       LifecycleEventKind
+	Timestamp  time.Time
+}
+
+var lifecycleSubscribersLock sync.RWMutex
+var lifecycleSubscribers []chan LifecycleEvent
+
+// SubscribeLifecycle returns a channel receiving lifecycle events of all configs.
+// Events are sent without blocking, so they are dropped if the subscriber does not receive in time.
+func SubscribeLifecycle() <-chan LifecycleEvent {
+	ch := make(chan LifecycleEvent, lifecycleEventBufferSize)
+	lifecycleSubscribersLock.Lock()
+	lifecycleSubscribers = append(lifecycleSubscribers, ch)
+	lifecycleSubscribersLock.Unlock()
+	return ch
+}
+
+// UnsubscribeLifecycle stops sending events to the channel returned by SubscribeLifecycle and closes it.
+func UnsubscribeLifecycle(ch <-chan LifecycleEvent) {
+	lifecycleSubscribersLock.Lock()
+	defer lifecycleSubscribersLock.Unlock()
+	for i, subscriber := range lifecycleSubscribers {
+		if subscriber == ch {
+			lifecycleSubscribers = append(lifecycleSubscribers[:i:i], lifecycleSubscribers[i+1:]...)
+			close(subscriber)
+			return
+		}
+	}
+}
+
+func emitLifecycleEvent(configName string, kind LifecycleEventKind) {
+	event := LifecycleEvent{ConfigName: configName, Kind: kind, Timestamp: time.Now()}
+	lifecycleSubscribersLock.RLock()
+	defer lifecycleSubscribersLock.RUnlock()
+	for _, subscriber := range lifecycleSubscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"runtime"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+var StartMemoryBudgetMB = flag.Int("StartMemoryBudgetMB", 0, "heap in use above which a config is refused to start if its queues may exceed it when full, MB, 0 to disable")
+var SkipStartMemoryBudget = flag.Bool("SkipStartMemoryBudget", false, "start configs even if StartMemoryBudgetMB would be exceeded")
+
+// ErrMemoryBudgetExceeded is returned by Start when the config may exceed StartMemoryBudgetMB.
+var ErrMemoryBudgetExceeded = errors.New("memory budget exceeded")
+
+// Rough sizes of queued items to estimate memory of a config which has not run yet.
+const (
+	estimatedRecordBytes = 1 << 10
+	estimatedGroupBytes  = 64 << 10
+)
+
+// ConfigMemUsage is a sample of data held in the queues and buffers of a config.
+type ConfigMemUsage struct {
+	InputQueueDepth      int   // records (v1) or groups (v2) waiting for processors
+	AggregatorQueueDepth int   // groups from aggregators waiting for flushers
+	BufferedGroups       int   // groups left unflushed when the config stopped, see FlushOutStore
+	EstimatedBytes       int64 // estimated with the size of groups recently passed to flushers
+}
+
+// ConfigMemoryStats samples the memory held by each running config, keyed by config name.
+// It helps to find the config responsible for most memory, data buffered inside plugins are not included.
+func ConfigMemoryStats() map[string]ConfigMemUsage {
+	stats := make(map[string]ConfigMemUsage)
+	RangeConfigs(func(configName string, config *LogstoreConfig) bool {
+		stats[configName] = sampleConfigMemUsage(config)
+		return true
+	})
+	return stats
+}
+
+func sampleConfigMemUsage(config *LogstoreConfig) ConfigMemUsage {
+	var usage ConfigMemUsage
+	switch runner := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		usage.InputQueueDepth = len(runner.LogsChan)
+		usage.AggregatorQueueDepth = len(runner.LogGroupsChan)
+		usage.BufferedGroups = runner.FlushOutStore.Len()
+		if runner.recordCounters != nil {
+			usage.EstimatedBytes = int64(usage.InputQueueDepth)*runner.recordCounters.recordSize.Load() +
+				int64(usage.AggregatorQueueDepth+usage.BufferedGroups)*runner.recordCounters.groupSize.Load()
+		}
+	case *pluginv2Runner:
+		usage.InputQueueDepth = len(runner.InputPipeContext.Collector().Observe())
+		usage.AggregatorQueueDepth = len(runner.AggregatePipeContext.Collector().Observe())
+		usage.BufferedGroups = runner.FlushOutStore.Len()
+		if runner.recordCounters != nil {
+			usage.EstimatedBytes = int64(usage.InputQueueDepth+usage.AggregatorQueueDepth+usage.BufferedGroups) *
+				runner.recordCounters.groupSize.Load()
+		}
+	}
+	return usage
+}
+
+// estimateConfigMemory returns the memory held by the queues of the config when they are full.
+func estimateConfigMemory(config *LogstoreConfig) int64 {
+	switch runner := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		return int64(cap(runner.LogsChan))*estimatedRecordBytes + int64(cap(runner.LogGroupsChan))*estimatedGroupBytes
+	case *pluginv2Runner:
+		return int64(cap(runner.InputPipeContext.Collector().Observe())+cap(runner.AggregatePipeContext.Collector().Observe())) *
+			estimatedGroupBytes
+	}
+	return 0
+}
+
+// checkMemoryBudget returns ErrMemoryBudgetExceeded if the heap in use plus the estimated memory of the config
+// is above StartMemoryBudgetMB.
+func checkMemoryBudget(config *LogstoreConfig) error {
+	if *StartMemoryBudgetMB <= 0 || *SkipStartMemoryBudget {
+		return nil
+	}
+	var memStat runtime.MemStats
+	runtime.ReadMemStats(&memStat)
+	budget := int64(*StartMemoryBudgetMB) * 1024 * 1024
+	estimated := estimateConfigMemory(config)
+	if int64(memStat.HeapInuse)+estimated <= budget {
+		return nil
+	}
+	logger.Warning(config.Context.GetRuntimeContext(), "CONFIG_MEMORY_BUDGET_ALARM", "refuse to start config",
+		"heap in use", memStat.HeapInuse, "estimated", estimated, "budget", budget)
+	return fmt.Errorf("%w: %s, heap in use %d bytes, estimated %d bytes, budget %d bytes",
+		ErrMemoryBudgetExceeded, config.ConfigNameWithSuffix, memStat.HeapInuse, estimated, budget)
+}
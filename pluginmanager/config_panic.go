@@ -0,0 +1,88 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+var PanicDisableThreshold = flag.Int("PanicDisableThreshold", 10, "disable a config when its goroutines panic this many times within PanicDisableWindowSec, 0 to never disable")
+var PanicDisableWindowSec = flag.Int("PanicDisableWindowSec", 60, "window in which panics of a config are counted, second")
+
+// configPanics records recent panics of a config.
+type configPanics struct {
+	lock     sync.Mutex
+	times    []time.Time
+	disabled bool
+}
+
+// recordConfigPanic counts a panic recovered from a goroutine of the config, and disables the config
+// if it panics PanicDisableThreshold times within PanicDisableWindowSec.
+func recordConfigPanic(config *LogstoreConfig) {
+	var counters *recordCounters
+	switch r := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		counters = r.recordCounters
+	case *pluginv2Runner:
+		counters = r.recordCounters
+	}
+	if counters != nil {
+		counters.panics.Add(1)
+	}
+	if *PanicDisableThreshold <= 0 {
+		return
+	}
+
+	now := time.Now()
+	since := now.Add(-time.Duration(*PanicDisableWindowSec) * time.Second)
+	config.panics.lock.Lock()
+	times := append(config.panics.times, now)
+	for len(times) > 0 && times[0].Before(since) {
+		times = times[1:]
+	}
+	config.panics.times = times
+	disable := !config.panics.disabled && len(times) >= *PanicDisableThreshold
+	if disable {
+		config.panics.disabled = true
+	}
+	config.panics.lock.Unlock()
+	if disable {
+		go disablePanickingConfig(config, len(times))
+	}
+}
+
+// disablePanickingConfig removes the config from LogtailConfig and stops it. The config is kept in
+// DisabledLogtailConfig until it stops.
+func disablePanickingConfig(config *LogstoreConfig, panics int) {
+	configName := config.ConfigNameWithSuffix
+	LogtailConfigLock.Lock()
+	if LogtailConfig[configName] != config {
+		LogtailConfigLock.Unlock()
+		return
+	}
+	delete(LogtailConfig, configName)
+	LogtailConfigLock.Unlock()
+	quarantineLogstoreConfig(config, ConfigHealthReasonRecentPanic)
+	logger.Error(context.Background(), "CONFIG_PANIC_ALARM", "disable config because it keeps panicking", configName,
+		"panics", panics, "window", time.Duration(*PanicDisableWindowSec)*time.Second)
+	notifyConfigState(configName, ConfigStateDisabled)
+	// DeleteLogstoreConfig is called once it stops, see timeoutStop.
+	timeoutStop(config, true)
+}
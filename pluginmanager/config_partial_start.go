@@ -0,0 +1,119 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+// A config may have two pipelines in the plugin manager, one with inputs and one without, with the same name
+// without suffix. Both are loaded before either is started. By default they start separately: if one of them
+// fails to start, the other is left alone and runs by itself. With ReleaseSiblingOnStartFailure, they start as
+// a whole: if one of them fails to start and is dropped, the other is stopped if running, or unloaded if
+// waiting to start, and Start of it returns ErrSiblingStartFailed. A pipeline refused but kept loaded to
+// retry, e.g. by ErrDuplicateConfig or ErrMemoryBudgetExceeded, does not release the other.
+var ReleaseSiblingOnStartFailure = flag.Bool("ReleaseSiblingOnStartFailure", false, "release the other pipeline of a config when one of its pipelines fails to start and is dropped")
+
+// ErrSiblingStartFailed is returned by Start for the pipeline unloaded as the other pipeline of its config failed to start.
+var ErrSiblingStartFailed = errors.New("the other pipeline of the config failed to start")
+
+// Errors to return by Start for the pipelines unloaded, keyed by config name with suffix.
+// An entry is removed when it is returned, or the pipeline is loaded again.
+var siblingStartFailuresLock sync.Mutex
+var siblingStartFailures = make(map[string]error)
+
+// isSiblingPipeline returns true if lc is the other pipeline of the config. The config may be released already,
+// so only names are compared.
+func isSiblingPipeline(config *LogstoreConfig, lc *LogstoreConfig) bool {
+	return lc != nil && lc != config && lc.PluginRunner != nil &&
+		lc.ConfigName == config.ConfigName && lc.ProjectName == config.ProjectName &&
+		lc.ConfigNameWithSuffix != config.ConfigNameWithSuffix
+}
+
+// releaseSiblingPipeline releases the other pipeline of the config which fails to start with startErr and is
+// dropped, if ReleaseSiblingOnStartFailure is set. The config may be released already, so alarms are logged with
+// the context of the other pipeline. The other pipeline is unloaded if waiting to start, dropped if deferred,
+// or stopped if running. Unsent data of a stopped pipeline is kept as if the config is not removed.
+func releaseSiblingPipeline(config *LogstoreConfig, startErr error) {
+	if !*ReleaseSiblingOnStartFailure {
+		return
+	}
+	siblingErr := fmt.Errorf("%w: %s, %v", ErrSiblingStartFailed, config.ConfigNameWithSuffix, startErr)
+	for _, slot := range []**LogstoreConfig{&ToStartPipelineConfigWithInput, &ToStartPipelineConfigWithoutInput} {
+		if sibling := *slot; isSiblingPipeline(config, sibling) {
+			logger.Warning(sibling.Context.GetRuntimeContext(), "CONFIG_PARTIAL_START_ALARM",
+				"unload the other pipeline of the config", sibling.ConfigNameWithSuffix, "error", startErr)
+			discardUnstartedConfig(sibling)
+			*slot = nil
+			siblingStartFailuresLock.Lock()
+			siblingStartFailures[sibling.ConfigNameWithSuffix] = siblingErr
+			siblingStartFailuresLock.Unlock()
+			return
+		}
+	}
+
+	deferredConfigsLock.Lock()
+	var deferred *LogstoreConfig
+	for _, lc := range deferredConfigs {
+		if isSiblingPipeline(config, lc) {
+			deferred = lc
+			break
+		}
+	}
+	deferredConfigsLock.Unlock()
+	if deferred != nil {
+		logger.Warning(deferred.Context.GetRuntimeContext(), "CONFIG_PARTIAL_START_ALARM",
+			"drop the other pipeline of the config", deferred.ConfigNameWithSuffix, "error", startErr)
+		dropDeferredConfig(deferred.ConfigNameWithSuffix)
+		return
+	}
+
+	var runningName string
+	var running *LogstoreConfig
+	RangeConfigs(func(name string, lc *LogstoreConfig) bool {
+		if isSiblingPipeline(config, lc) {
+			runningName, running = name, lc
+			return false
+		}
+		return true
+	})
+	if running != nil {
+		logger.Warning(running.Context.GetRuntimeContext(), "CONFIG_PARTIAL_START_ALARM",
+			"stop the other pipeline of the config", runningName, "error", startErr)
+		hasStopped := timeoutStop(running, false)
+		removeStoppedConfig(runningName, running, false, hasStopped)
+	}
+}
+
+// takeSiblingStartFailure returns the error recorded for the pipeline unloaded by releaseSiblingPipeline, or nil.
+func takeSiblingStartFailure(configName string) error {
+	siblingStartFailuresLock.Lock()
+	defer siblingStartFailuresLock.Unlock()
+	err := siblingStartFailures[configName]
+	delete(siblingStartFailures, configName)
+	return err
+}
+
+// forgetSiblingStartFailure removes the error recorded for the pipeline, as it is loaded again.
+func forgetSiblingStartFailure(configName string) {
+	siblingStartFailuresLock.Lock()
+	delete(siblingStartFailures, configName)
+	siblingStartFailuresLock.Unlock()
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+var QuarantineBackoffSec = flag.Int("QuarantineBackoffSec", 600, "duration a config disabled before the last restart is refused to start after the restart, second")
+
+// quarantineCheckpointName is the config name in CheckPointManager under which disabled configs are persisted.
+const quarantineCheckpointName = "__disabled_config__"
+
+// ErrConfigQuarantined is returned by Start when the config was disabled before the last restart and the
+// backoff of QuarantineBackoffSec is not over. The config is started once the backoff is over.
+var ErrConfigQuarantined = errors.New("config is quarantined")
+
+// QuarantinedConfig is a config which was disabled when the agent exited last time.
+type QuarantinedConfig struct {
+	ConfigNameWithSuffix string
+	Reason               string
+	DisabledTime         time.Time
+	// Until is the time after which the config is allowed to start again.
+	Until time.Time
+}
+
+type disabledConfigRecord struct {
+	Reason       string    `json:"reason"`
+	DisabledTime time.Time `json:"disabled_time"`
+}
+
+var quarantinedConfigsLock sync.Mutex
+var quarantinedConfigs = make(map[string]*QuarantinedConfig)
+
+// quarantineLogstoreConfig disables the config and persists it, so that the config is not started
+// at once if the agent is restarted before the config stops.
+func quarantineLogstoreConfig(config *LogstoreConfig, reason string) {
+	disableLogstoreConfig(config)
+	persistDisabledConfig(config, reason)
+}
+
+// persistDisabledConfig saves the record of the disabled config to be quarantined after restart.
+func persistDisabledConfig(config *LogstoreConfig, reason string) {
+	record, _ := json.Marshal(disabledConfigRecord{Reason: reason, DisabledTime: time.Now()})
+	if err := CheckPointManager.SaveCheckpoint(quarantineCheckpointName, config.ConfigNameWithSuffix, record); err != nil {
+		logger.Warning(context.Background(), "CONFIG_QUARANTINE_ALARM", "persist disabled config error", config.ConfigNameWithSuffix, "error", err)
+	}
+}
+
+// forgetDisabledConfig removes the persisted record of the config once no instance of it is disabled.
+func forgetDisabledConfig(configName string) {
+	DisabledLogtailConfigLock.RLock()
+	for _, config := range DisabledLogtailConfig {
+		if config.ConfigNameWithSuffix == configName {
+			DisabledLogtailConfigLock.RUnlock()
+			return
+		}
+	}
+	DisabledLogtailConfigLock.RUnlock()
+	_ = CheckPointManager.DeleteCheckpoint(quarantineCheckpointName, configName)
+}
+
+// loadQuarantinedConfigs quarantines the configs persisted by the last run for QuarantineBackoffSec.
+// Configs already quarantined keep their backoff.
+func loadQuarantinedConfigs() {
+	records, err := CheckPointManager.listCheckpoints(quarantineCheckpointName)
+	if err != nil {
+		logger.Warning(context.Background(), "CONFIG_QUARANTINE_ALARM", "load disabled configs error", err)
+		return
+	}
+	until := time.Now().Add(time.Duration(*QuarantineBackoffSec) * time.Second)
+	quarantinedConfigsLock.Lock()
+	defer quarantinedConfigsLock.Unlock()
+	for configName, value := range records {
+		if _, exists := quarantinedConfigs[configName]; exists {
+			continue
+		}
+		var record disabledConfigRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			logger.Warning(context.Background(), "CONFIG_QUARANTINE_ALARM", "invalid disabled config record", configName, "error", err)
+			continue
+		}
+		quarantinedConfigs[configName] = &QuarantinedConfig{
+			ConfigNameWithSuffix: configName,
+			Reason:               record.Reason,
+			DisabledTime:         record.DisabledTime,
+			Until:                until,
+		}
+		logger.Warning(context.Background(), "CONFIG_QUARANTINE_ALARM", "config was disabled before restart", configName,
+			"reason", record.Reason, "until", until)
+	}
+}
+
+// checkQuarantine returns ErrConfigQuarantined if the config is quarantined and the backoff is not over.
+// Once the backoff is over, the quarantine is cleared.
+func checkQuarantine(configName string) error {
+	quarantinedConfigsLock.Lock()
+	quarantined, exists := quarantinedConfigs[configName]
+	if exists && time.Now().Before(quarantined.Until) {
+		quarantinedConfigsLock.Unlock()
+		return fmt.Errorf("%w: %s, disabled for %s before restart, start at %s", ErrConfigQuarantined, configName,
+			quarantined.Reason, quarantined.Until.Format(time.RFC3339))
+	}
+	quarantinedConfigsLock.Unlock()
+	if exists {
+		clearQuarantine(configName)
+	}
+	return nil
+}
+
+// isQuarantined returns true if the config is quarantined and the backoff is not over.
+func isQuarantined(configName string) bool {
+	quarantinedConfigsLock.Lock()
+	defer quarantinedConfigsLock.Unlock()
+	quarantined, exists := quarantinedConfigs[configName]
+	return exists && time.Now().Before(quarantined.Until)
+}
+
+// deferQuarantinedStart moves the config loaded with the name, which is refused by checkQuarantine, into the
+// deferred configs, and schedules to start it once the backoff is over, see startDeferredConfigs.
+func deferQuarantinedStart(configName string) {
+	var config *LogstoreConfig
+	for _, slot := range []**LogstoreConfig{&ToStartPipelineConfigWithInput, &ToStartPipelineConfigWithoutInput} {
+		if *slot != nil && (*slot).ConfigNameWithSuffix == configName {
+			config = *slot
+			*slot = nil
+			break
+		}
+	}
+	quarantinedConfigsLock.Lock()
+	quarantined, exists := quarantinedConfigs[configName]
+	quarantinedConfigsLock.Unlock()
+	if config == nil || !exists {
+		return
+	}
+	deferredConfigsLock.Lock()
+	if previous, exists := deferredConfigs[configName]; exists && previous != config {
+		discardUnstartedConfig(previous)
+	}
+	deferredConfigs[configName] = config
+	deferredConfigsLock.Unlock()
+	logger.Info(context.Background(), "defer config start until quarantine is over", configName, "until", quarantined.Until)
+	time.AfterFunc(time.Until(quarantined.Until), startDeferredConfigs)
+}
+
+// QuarantinedConfigs returns the quarantined configs sorted by name.
+func QuarantinedConfigs() []QuarantinedConfig {
+	quarantinedConfigsLock.Lock()
+	configs := make([]QuarantinedConfig, 0, len(quarantinedConfigs))
+	for _, config := range quarantinedConfigs {
+		configs = append(configs, *config)
+	}
+	quarantinedConfigsLock.Unlock()
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].ConfigNameWithSuffix < configs[j].ConfigNameWithSuffix
+	})
+	return configs
+}
+
+// ClearQuarantine allows the config to start at once, and removes its persisted record.
+// The config deferred by Start for the quarantine is started.
+// @return true if the config was quarantined.
+func ClearQuarantine(configName string) bool {
+	cleared := clearQuarantine(configName)
+	if cleared {
+		startDeferredConfigs()
+	}
+	return cleared
+}
+
+func clearQuarantine(configName string) bool {
+	quarantinedConfigsLock.Lock()
+	_, exists := quarantinedConfigs[configName]
+	delete(quarantinedConfigs, configName)
+	quarantinedConfigsLock.Unlock()
+	_ = CheckPointManager.DeleteCheckpoint(quarantineCheckpointName, configName)
+	return exists
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+)
+
+// ConfigInfo is a snapshot of a loaded config and its plugin topology.
+type ConfigInfo struct {
+	ConfigNameWithSuffix string
+	ProjectName          string
+	LogstoreName         string
+	IsWithInputPlugin    bool
+	// Disabled is true if the config did not stop in time and is still stopping.
+	Disabled bool
+
+	MetricPlugins     []string
+	ServicePlugins    []string
+	ProcessorPlugins  []string
+	AggregatorPlugins []string
+	FlusherPlugins    []string
+}
+
+// RangeConfigs calls fn for each running config in LogtailConfig while holding LogtailConfigLock for read,
+// and stops when fn returns false. fn must not acquire LogtailConfigLock for write.
+func RangeConfigs(fn func(name string, cfg *LogstoreConfig) bool) {
+	LogtailConfigLock.RLock()
+	defer LogtailConfigLock.RUnlock()
+	for name, cfg := range LogtailConfig {
+		if !fn(name, cfg) {
+			return
+		}
+	}
+}
+
+// ListRunningConfigs returns snapshots of running configs and configs in DisabledLogtailConfig,
+// ordered by config name.
+func ListRunningConfigs() []ConfigInfo {
+	infos := make([]ConfigInfo, 0)
+	RangeConfigs(func(_ string, config *LogstoreConfig) bool {
+		infos = append(infos, newConfigInfo(config, false))
+		return true
+	})
+	DisabledLogtailConfigLock.RLock()
+	for _, config := range DisabledLogtailConfig {
+		infos = append(infos, newConfigInfo(config, true))
+	}
+	DisabledLogtailConfigLock.RUnlock()
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].ConfigNameWithSuffix < infos[j].ConfigNameWithSuffix
+	})
+	return infos
+}
+
+func newConfigInfo(config *LogstoreConfig, disabled bool) ConfigInfo {
+	info := ConfigInfo{
+		ConfigNameWithSuffix: config.ConfigNameWithSuffix,
+		ProjectName:          config.ProjectName,
+		LogstoreName:         config.LogstoreName,
+		Disabled:             disabled,
+	}
+	if config.PluginRunner == nil {
+		return info
+	}
+	info.IsWithInputPlugin = config.PluginRunner.IsWithInputPlugin()
+	if r, ok := config.PluginRunner.(*pluginv1Runner); ok {
+		for _, p := range r.MetricPlugins {
+			info.MetricPlugins = append(info.MetricPlugins, p.pluginType)
+		}
+		for _, p := range r.ServicePlugins {
+			info.ServicePlugins = append(info.ServicePlugins, p.pluginType)
+		}
+		for _, p := range r.ProcessorPlugins {
+			info.ProcessorPlugins = append(info.ProcessorPlugins, p.pluginType)
+		}
+		for _, p := range r.AggregatorPlugins {
+			info.AggregatorPlugins = append(info.AggregatorPlugins, p.pluginType)
+		}
+		for _, p := range r.FlusherPlugins {
+			info.FlusherPlugins = append(info.FlusherPlugins, p.pluginType)
+		}
+	} else if r, ok := config.PluginRunner.(*pluginv2Runner); ok {
+		for _, p := range r.MetricPlugins {
+			info.MetricPlugins = append(info.MetricPlugins, p.pluginType)
+		}
+		for _, p := range r.ServicePlugins {
+			info.ServicePlugins = append(info.ServicePlugins, p.pluginType)
+		}
+		for _, p := range r.ProcessorPlugins {
+			info.ProcessorPlugins = append(info.ProcessorPlugins, p.pluginType)
+		}
+		for _, p := range r.AggregatorPlugins {
+			info.AggregatorPlugins = append(info.AggregatorPlugins, p.pluginType)
+		}
+		for _, p := range r.FlusherPlugins {
+			info.FlusherPlugins = append(info.FlusherPlugins, p.pluginType)
+		}
+	}
+	return info
+}
+
+// ConfigStatus is the live state of a config.
+type ConfigStatus struct {
+	State ConfigState
+	// Info is empty if State is ConfigStateUnknown.
+	Info ConfigInfo
+	// HasUnsentBuffer is true if data of a stopped version of the config is kept in LastUnsendBuffer.
+	HasUnsentBuffer bool
+}
+
+// GetConfigState returns whether the config is running, loaded and waiting to be started or disabled,
+// with its plugin topology. ConfigName is with suffix.
+// @return false if the config is neither in any of them nor has unsent data.
+func GetConfigState(configName string) (ConfigStatus, bool) {
+	status := ConfigStatus{State: ConfigStateUnknown}
+	LogtailConfigLock.RLock()
+	config, running := LogtailConfig[configName]
+	LogtailConfigLock.RUnlock()
+	deferred := deferredConfig(configName)
+	switch {
+	case running:
+		status.State = ConfigStateRunning
+		status.Info = newConfigInfo(config, false)
+	case ToStartPipelineConfigWithInput != nil && ToStartPipelineConfigWithInput.ConfigNameWithSuffix == configName:
+		status.State = ConfigStatePendingStart
+		status.Info = newConfigInfo(ToStartPipelineConfigWithInput, false)
+	case ToStartPipelineConfigWithoutInput != nil && ToStartPipelineConfigWithoutInput.ConfigNameWithSuffix == configName:
+		status.State = ConfigStatePendingStart
+		status.Info = newConfigInfo(ToStartPipelineConfigWithoutInput, false)
+	case deferred != nil:
+		status.State = ConfigStatePendingStart
+		status.Info = newConfigInfo(deferred, false)
+	default:
+		// report the latest disabled instance of the config
+		var latest *LogstoreConfig
+		DisabledLogtailConfigLock.RLock()
+		for generation, disabled := range DisabledLogtailConfig {
+			if disabled.ConfigNameWithSuffix == configName && (latest == nil || generation > latest.generation) {
+				latest = disabled
+			}
+		}
+		DisabledLogtailConfigLock.RUnlock()
+		if latest != nil {
+			status.State = ConfigStateDisabled
+			status.Info = newConfigInfo(latest, true)
+		}
+	}
+
+	LastUnsendBufferLock.Lock()
+	for key := range LastUnsendBuffer {
+		if unsendBufferConfigName(key) == configName {
+			status.HasUnsentBuffer = true
+			break
+		}
+	}
+	LastUnsendBufferLock.Unlock()
+	return status, status.State != ConfigStateUnknown || status.HasUnsentBuffer
+}
+
+// ConfigPipelineLayout reports which Go pipelines the config is split into, among the configs loaded
+// and waiting to be started and the running configs. ConfigName is with or without suffix.
+// It returns ErrConfigNotFound if the config has no pipeline.
+func ConfigPipelineLayout(configName string) (hasInputPipeline, hasNonInputPipeline bool, err error) {
+	realName := config.GetRealConfigName(configName)
+	check := func(lc *LogstoreConfig) {
+		if lc == nil || lc.PluginRunner == nil || lc.ConfigName != realName {
+			return
+		}
+		if lc.PluginRunner.IsWithInputPlugin() {
+			hasInputPipeline = true
+		} else {
+			hasNonInputPipeline = true
+		}
+	}
+	check(ToStartPipelineConfigWithInput)
+	check(ToStartPipelineConfigWithoutInput)
+	RangeConfigs(func(_ string, lc *LogstoreConfig) bool {
+		check(lc)
+		return true
+	})
+	if !hasInputPipeline && !hasNonInputPipeline {
+		return false, false, fmt.Errorf("%w: %s", ErrConfigNotFound, configName)
+	}
+	return hasInputPipeline, hasNonInputPipeline, nil
+}
+
+// resolveConfigName returns the name with suffix of the given config, which is with or without suffix.
+// A name loaded or running as it is returns at once. Otherwise the config is looked up by its real name
+// among the loaded and running configs, and ErrAmbiguousConfigName is returned if several pipelines match,
+// e.g. both pipelines of a config split into ones with and without input.
+// The name is returned unchanged if nothing matches.
+func resolveConfigName(configName string) (string, error) {
+	for _, lc := range []*LogstoreConfig{ToStartPipelineConfigWithInput, ToStartPipelineConfigWithoutInput} {
+		if lc != nil && lc.ConfigNameWithSuffix == configName {
+			return configName, nil
+		}
+	}
+	LogtailConfigLock.RLock()
+	_, running := LogtailConfig[configName]
+	LogtailConfigLock.RUnlock()
+	if running {
+		return configName, nil
+	}
+
+	matched := make(map[string]struct{})
+	match := func(lc *LogstoreConfig) {
+		if lc != nil && lc.ConfigName == configName {
+			matched[lc.ConfigNameWithSuffix] = struct{}{}
+		}
+	}
+	match(ToStartPipelineConfigWithInput)
+	match(ToStartPipelineConfigWithoutInput)
+	RangeConfigs(func(_ string, lc *LogstoreConfig) bool {
+		match(lc)
+		return true
+	})
+	switch len(matched) {
+	case 0:
+		return configName, nil
+	case 1:
+		for nameWithSuffix := range matched {
+			return nameWithSuffix, nil
+		}
+	}
+	names := make([]string, 0, len(matched))
+	for nameWithSuffix := range matched {
+		names = append(names, nameWithSuffix)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("%w: %s matches %v", ErrAmbiguousConfigName, configName, names)
+}
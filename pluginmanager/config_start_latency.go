@@ -0,0 +1,105 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+// startLatencyBounds are the upper bounds of the buckets of StartLatencySummary, the last bucket is unbounded.
+var startLatencyBounds = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// StartLatencyBucket counts the starts taking no longer than UpperBound and longer than the bound of the
+// previous bucket. UpperBound is 0 for the last bucket, which is unbounded.
+type StartLatencyBucket struct {
+	UpperBound time.Duration
+	Count      int64
+}
+
+// StartLatencySummary summarizes the time Start takes since the process started, from building the
+// pipeline to registering the config in LogtailConfig.
+type StartLatencySummary struct {
+	Count   int64
+	Total   time.Duration
+	Max     time.Duration
+	Buckets []StartLatencyBucket
+	// Latest is the latency of the latest start of each config, keyed by config name with suffix.
+	Latest map[string]time.Duration
+}
+
+var startLatencyLock sync.Mutex
+var startLatencyCount int64
+var startLatencyTotal time.Duration
+var startLatencyMax time.Duration
+var startLatencyBuckets = make([]int64, len(startLatencyBounds)+1)
+var latestStartLatency = make(map[string]time.Duration)
+
+// recordStartLatency records the time the config takes to start, and exports it in the metrics of the config.
+func recordStartLatency(config *LogstoreConfig, latency time.Duration) {
+	if counters := config.recordCounters(); counters != nil {
+		counters.startLatencyMs.Set(float64(latency.Milliseconds()))
+	}
+	logger.Info(config.Context.GetRuntimeContext(), "config start", "registered", "latency", latency)
+
+	startLatencyLock.Lock()
+	defer startLatencyLock.Unlock()
+	startLatencyCount++
+	startLatencyTotal += latency
+	if latency > startLatencyMax {
+		startLatencyMax = latency
+	}
+	bucket := len(startLatencyBounds)
+	for i, bound := range startLatencyBounds {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+	startLatencyBuckets[bucket]++
+	latestStartLatency[config.ConfigNameWithSuffix] = latency
+}
+
+// StartLatencies returns the summary of the time configs take to start, see Start.
+func StartLatencies() StartLatencySummary {
+	startLatencyLock.Lock()
+	defer startLatencyLock.Unlock()
+	summary := StartLatencySummary{
+		Count:   startLatencyCount,
+		Total:   startLatencyTotal,
+		Max:     startLatencyMax,
+		Buckets: make([]StartLatencyBucket, len(startLatencyBuckets)),
+		Latest:  make(map[string]time.Duration, len(latestStartLatency)),
+	}
+	for i, count := range startLatencyBuckets {
+		summary.Buckets[i].Count = count
+		if i < len(startLatencyBounds) {
+			summary.Buckets[i].UpperBound = startLatencyBounds[i]
+		}
+	}
+	for configName, latency := range latestStartLatency {
+		summary.Latest[configName] = latency
+	}
+	return summary
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+// ConfigState is the state of a config. Listeners are notified when a config is stopped, disabled or recovered.
+type ConfigState int
+
+const (
+	// ConfigStateUnknown means the config is not known by the plugin manager.
+	ConfigStateUnknown ConfigState = iota
+	// ConfigStateStopped means the config stopped in time.
+	ConfigStateStopped
+	// ConfigStateDisabled means the config did not stop in time and was moved into DisabledLogtailConfig.
+	ConfigStateDisabled
+	// ConfigStateRecovered means a disabled config finally stopped and was removed from DisabledLogtailConfig.
+	ConfigStateRecovered
+	// ConfigStateRunning means the config is in LogtailConfig.
+	ConfigStateRunning
+	// ConfigStatePendingStart means the config is loaded into ToStartPipelineConfigWithInput or
+	// ToStartPipelineConfigWithoutInput and waits to be started, or waits for its dependencies to start.
+	ConfigStatePendingStart
+)
+
+func (s ConfigState) String() string {
+	switch s {
+	case ConfigStateStopped:
+		return "stopped"
+	case ConfigStateDisabled:
+		return "disabled"
+	case ConfigStateRecovered:
+		return "recovered"
+	case ConfigStateRunning:
+		return "running"
+	case ConfigStatePendingStart:
+		return "pending_start"
+	default:
+		return "unknown"
+	}
+}
+
+// ConfigStateListener is called with the config name with suffix when the config transitions to state.
+type ConfigStateListener func(configName string, state ConfigState)
+
+var configStateListenersLock sync.RWMutex
+var configStateListeners []ConfigStateListener
+
+// RegisterConfigStateListener registers a listener called whenever a config is stopped, disabled or recovered.
+// Listeners are called synchronously without any lock of plugin manager held, so they should return quickly.
+func RegisterConfigStateListener(listener ConfigStateListener) {
+	configStateListenersLock.Lock()
+	defer configStateListenersLock.Unlock()
+	configStateListeners = append(configStateListeners, listener)
+}
+
+// notifyConfigState calls all listeners, it must not be called with LogtailConfigLock or
+// DisabledLogtailConfigLock held.
+func notifyConfigState(configName string, state ConfigState) {
+	configStateListenersLock.RLock()
+	listeners := configStateListeners
+	configStateListenersLock.RUnlock()
+	for _, listener := range listeners {
+		callConfigStateListener(listener, configName, state)
+	}
+	switch state {
+	case ConfigStateStopped:
+		emitLifecycleEvent(configName, LifecycleStopped)
+		recordConfigEvent(configName, ConfigEventStopped)
+	case ConfigStateRecovered:
+		emitLifecycleEvent(configName, LifecycleStopped)
+		recordConfigEvent(configName, ConfigEventRecovered)
+	case ConfigStateDisabled:
+		emitLifecycleEvent(configName, LifecycleDisabled)
+		recordConfigEvent(configName, ConfigEventDisabled)
+	}
+}
+
+func callConfigStateListener(listener ConfigStateListener, configName string, state ConfigState) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Error(context.Background(), "PLUGIN_RUNTIME_ALARM", "config state listener panicked", err,
+				"config", configName, "state", state)
+		}
+	}()
+	listener(configName, state)
+}
+
+// PostStartHook is called with the config name with suffix after the config is started and added to LogtailConfig.
+type PostStartHook func(configName string, withInput bool)
+
+var postStartHooksLock sync.RWMutex
+var postStartHooks []PostStartHook
+
+// RegisterPostStartHook registers a hook called by Start after a config is started.
+// Hooks are called synchronously without any lock of plugin manager held, so they should return quickly.
+func RegisterPostStartHook(hook PostStartHook) {
+	postStartHooksLock.Lock()
+	defer postStartHooksLock.Unlock()
+	postStartHooks = append(postStartHooks, hook)
+}
+
+// notifyConfigStarted calls all post start hooks, it must not be called with LogtailConfigLock held.
+func notifyConfigStarted(configName string, withInput bool) {
+	postStartHooksLock.RLock()
+	hooks := postStartHooks
+	postStartHooksLock.RUnlock()
+	for _, hook := range hooks {
+		callPostStartHook(hook, configName, withInput)
+	}
+	emitLifecycleEvent(configName, LifecycleStarted)
+	recordConfigEvent(configName, ConfigEventStarted)
+}
+
+func callPostStartHook(hook PostStartHook, configName string, withInput bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Error(context.Background(), "PLUGIN_RUNTIME_ALARM", "post start hook panicked", err, "config", configName)
+		}
+	}()
+	hook(configName, withInput)
+}
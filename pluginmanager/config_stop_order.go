@@ -0,0 +1,51 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+var StopPhasePauseMs = flag.Int("StopPhasePauseMs", 0, "pause between stopping configs with input and configs without input in StopInOrder, ms, 0 means not to pause")
+
+// StopInOrder stops all configs in two phases: configs with input are stopped first by StopAllPipelines(true),
+// then configs without input by StopAllPipelines(false). Configs without input only receive data from configs
+// with input, e.g. by the C++ part, so stopping inputs first lets the downstream configs flush what is sent to them.
+// Between the phases it pauses StopPhasePauseMs for the queues of downstream configs to drain.
+//
+// Both phases stop waiting when ctx is done, like StopAllPipelinesContext. The second phase runs even if the first
+// one fails, and the first error is returned.
+func StopInOrder(ctx context.Context) error {
+	err := StopAllPipelinesContext(ctx, true)
+	if err != nil {
+		logger.Warning(ctx, "PLUGIN_ALARM", "stop configs with input error", err)
+	}
+	if pause := time.Duration(*StopPhasePauseMs) * time.Millisecond; pause > 0 {
+		timer := time.NewTimer(pause)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+	if stopErr := StopAllPipelinesContext(ctx, false); err == nil {
+		err = stopErr
+	}
+	return err
+}
@@ -56,6 +56,8 @@ func (s *configUpdateTestSuite) AfterTest(suiteName, testName string) {
 	LogtailConfigLock.Lock()
 	LogtailConfig = make(map[string]*LogstoreConfig)
 	LogtailConfigLock.Unlock()
+	// the block config never stops, do not quarantine it in later tests
+	ClearQuarantine(updateConfigName)
 }
 
 func (s *configUpdateTestSuite) TestConfigUpdate() {
@@ -204,6 +206,37 @@ func (s *configUpdateTestSuite) TestConfigUpdateName() {
 	}
 }
 
+func (s *configUpdateTestSuite) TestConfigReload() {
+	LogtailConfigLock.RLock()
+	config := LogtailConfig[updateConfigName]
+	LogtailConfigLock.RUnlock()
+	s.NotNil(config, "%s logstrore config should exist", updateConfigName)
+	checkFlusher, ok := GetConfigFlushers(config.PluginRunner)[0].(*checker.FlusherChecker)
+	s.True(ok)
+	mockInput, ok := GetConfigInputs(config.PluginRunner)[0].(*mockd.ServiceMock)
+	s.True(ok)
+
+	// make flusher unready so that data is left unsent
+	checkFlusher.Ready = false
+	mockInput.Block = false
+	time.Sleep(time.Second * time.Duration(3))
+
+	// the new config has a different flusher instance, unsent data of the old one should be moved to it
+	s.NoError(Reload(updateConfigName, GetTestConfig(noblockUpdateConfigName)))
+	s.Equal(0, checkFlusher.GetLogCount())
+	LogtailConfigLock.RLock()
+	newConfig := LogtailConfig[updateConfigName]
+	LogtailConfigLock.RUnlock()
+	s.NotEqual(config, newConfig)
+	newCheckFlusher, ok := GetConfigFlushers(newConfig.PluginRunner)[0].(*checker.FlusherChecker)
+	s.True(ok)
+	time.Sleep(time.Second * time.Duration(5))
+	s.Greater(newCheckFlusher.GetLogCount(), 20000, "the new flusher checker should merge the old logs")
+	LastUnsendBufferLock.Lock()
+	s.Equal(0, len(LastUnsendBuffer))
+	LastUnsendBufferLock.Unlock()
+}
+
 func (s *configUpdateTestSuite) TestStopAllExit() {
 	LogtailConfigLock.RLock()
 	config := LogtailConfig[updateConfigName]
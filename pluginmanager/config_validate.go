@@ -0,0 +1,95 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+// validateConfigName is the name of configs validated by ValidateConfig, seen in logs of the plugins.
+const validateConfigName = "__validate_config__"
+
+// ValidateConfig builds the config from jsonStr the same way LoadLogstoreConfig does, without starting
+// it or replacing any loaded config, and tears it down afterwards, see closeUnstartedConfig.
+// It returns an error for invalid json, unknown plugin types, bad plugin details and plugins not
+// supported by the version of the config.
+func ValidateConfig(jsonStr string) error {
+	return ValidateNamedConfig(validateConfigName, jsonStr)
+}
+
+// ValidateNamedConfig validates the config like ValidateConfig, with plugins logging under configName.
+func ValidateNamedConfig(configName, jsonStr string) error {
+	if err := checkConfigPluginTypes(jsonStr); err != nil {
+		return err
+	}
+	logstoreC, err := buildLogstoreConfig("", "", configName, -1, jsonStr)
+	if err != nil {
+		return err
+	}
+	closeUnstartedConfig(logstoreC)
+	return nil
+}
+
+// checkConfigPluginTypes returns an error for invalid json and unknown inputs, processors and aggregators,
+// which are skipped by buildLogstoreConfig.
+func checkConfigPluginTypes(jsonStr string) error {
+	var plugins map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonStr), &plugins); err != nil {
+		return fmt.Errorf("invalid config json: %w", err)
+	}
+	if err := checkPluginTypes(plugins, "inputs", func(pluginType string) bool {
+		_, isMetricInput := pipeline.MetricInputs[pluginType]
+		_, isServiceInput := pipeline.ServiceInputs[pluginType]
+		return isMetricInput || isServiceInput
+	}); err != nil {
+		return err
+	}
+	if err := checkPluginTypes(plugins, "processors", func(pluginType string) bool {
+		_, exists := pipeline.Processors[pluginType]
+		return exists
+	}); err != nil {
+		return err
+	}
+	return checkPluginTypes(plugins, "aggregators", func(pluginType string) bool {
+		_, exists := pipeline.Aggregators[pluginType]
+		return exists
+	})
+}
+
+// checkPluginTypes returns an error if a plugin of the section has an unknown type.
+// Malformed sections are left to buildLogstoreConfig.
+func checkPluginTypes(plugins map[string]interface{}, section string, known func(pluginType string) bool) error {
+	list, ok := plugins[section].([]interface{})
+	if !ok {
+		return nil
+	}
+	for _, item := range list {
+		plugin, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		pluginTypeWithID, ok := plugin["type"].(string)
+		if !ok {
+			continue
+		}
+		if pluginType := getPluginType(pluginTypeWithID); !known(pluginType) {
+			return fmt.Errorf("unknown plugin type %s in %s", pluginType, section)
+		}
+	}
+	return nil
+}
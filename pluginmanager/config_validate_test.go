@@ -0,0 +1,59 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	cases := []struct {
+		name    string
+		jsonStr string
+		valid   bool
+	}{
+		{"valid", `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 10}}], "flushers": [{"type": "flusher_checker"}]}`, true},
+		{"invalid json", `{"inputs": [`, false},
+		{"unknown input", `{"inputs": [{"type": "service_not_exist"}], "flushers": [{"type": "flusher_checker"}]}`, false},
+		{"unknown processor", `{"inputs": [{"type": "service_mock"}], "processors": [{"type": "processor_not_exist"}], "flushers": [{"type": "flusher_checker"}]}`, false},
+		{"unknown flusher", `{"inputs": [{"type": "service_mock"}], "flushers": [{"type": "flusher_not_exist"}]}`, false},
+		{"bad detail", `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": "ten"}}], "flushers": [{"type": "flusher_checker"}]}`, false},
+		{"v1 plugin in v2 config", `{"global": {"StructureType": "v2"}, "inputs": [{"type": "service_mock"}], "flushers": [{"type": "flusher_checker"}]}`, false},
+	}
+	for _, c := range cases {
+		err := ValidateConfig(c.jsonStr)
+		if c.valid {
+			assert.NoError(t, err, c.name)
+		} else {
+			assert.Error(t, err, c.name)
+		}
+	}
+	// flushers initialized for validation are stopped
+	stopped := stoppedRecordFlushers.Load()
+	assert.NoError(t, ValidateConfig(`{"inputs": [{"type": "service_mock"}], "flushers": [{"type": "flusher_stopped_record_test"}]}`))
+	assert.Equal(t, stopped+1, stoppedRecordFlushers.Load())
+
+	// validating does not load the config
+	assert.Error(t, ValidateNamedConfig("validate_config", `{"inputs": [{"type": "service_not_exist"}], "flushers": [{"type": "flusher_checker"}]}`))
+	assert.NoError(t, ValidateNamedConfig("validate_config", cases[0].jsonStr))
+	LogtailConfigLock.RLock()
+	assert.NotContains(t, LogtailConfig, "validate_config")
+	LogtailConfigLock.RUnlock()
+	if ToStartPipelineConfigWithInput != nil {
+		assert.NotEqual(t, "validate_config", ToStartPipelineConfigWithInput.ConfigNameWithSuffix)
+	}
+}
@@ -35,6 +35,7 @@ type ContextImp struct {
 	common      *pkg.LogtailContextMeta
 	pluginNames string
 	ctx         context.Context
+	cancel      context.CancelFunc
 	logstoreC   *LogstoreConfig
 }
 
@@ -97,8 +98,15 @@ func (p *ContextImp) AddPlugin(name string) {
 }
 
 func (p *ContextImp) InitContext(project, logstore, configName string) {
+	p.initContext(project, logstore, configName, newRuntimeContext)
+}
+
+// initContext works like InitContext, and derives the runtime context from the one returned by newContext.
+func (p *ContextImp) initContext(project, logstore, configName string, newContext func() (context.Context, context.CancelFunc)) {
 	// bind metadata information.
-	p.ctx, p.common = pkg.NewLogtailContextMeta(project, logstore, configName)
+	_, p.common = pkg.NewLogtailContextMeta(project, logstore, configName)
+	parent, cancel := newContext()
+	p.ctx, p.cancel = context.WithValue(parent, pkg.LogTailMeta, p.common), cancel
 }
 
 func (p *ContextImp) RegisterMetricRecord(labels []selfmonitor.LabelPair) *selfmonitor.MetricsRecord {
@@ -0,0 +1,121 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shirou/gopsutil/disk"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+var MinFreeDiskSpaceMB = flag.Int("MinFreeDiskSpaceMB", 0, "free space of data dir below which disk writes are refused, MB, 0 to disable")
+var DiskSpaceCheckInterval = flag.Int("DiskSpaceCheckInterval", 10, "disk space check interval, second")
+
+var ErrDiskSpaceLow = errors.New("disk space is low")
+
+// diskSpaceAlarmInterval is the min interval of alarms for writes refused while disk space is low.
+const diskSpaceAlarmInterval = time.Minute
+
+// diskSpaceWatchdog checks free space of the data dir periodically.
+// When free space is below MinFreeDiskSpaceMB, checkpoints are not saved, and plugins
+// buffering data on disk drop data with alarm instead, see util.IsDiskSpaceLow.
+type diskSpaceWatchdog struct {
+	shutdown  chan struct{}
+	waitgroup sync.WaitGroup
+}
+
+var DiskSpaceWatchdog diskSpaceWatchdog
+
+// IsDiskSpaceLow returns true if free space of the data dir is below MinFreeDiskSpaceMB.
+func IsDiskSpaceLow() bool {
+	return util.IsDiskSpaceLow()
+}
+
+// diskSpaceAlarmLimiter admits one alarm per diskSpaceAlarmInterval, and counts the refused writes in between,
+// so that writes refused on every call do not flood the log.
+type diskSpaceAlarmLimiter struct {
+	lastAlarm  atomic.Int64
+	suppressed atomic.Int64
+}
+
+// admit returns true and the number of writes refused since the last alarm if an alarm should be logged now.
+func (l *diskSpaceAlarmLimiter) admit(now time.Time) (bool, int64) {
+	last := l.lastAlarm.Load()
+	if now.UnixNano()-last < int64(diskSpaceAlarmInterval) || !l.lastAlarm.CompareAndSwap(last, now.UnixNano()) {
+		l.suppressed.Add(1)
+		return false, 0
+	}
+	return true, l.suppressed.Swap(0)
+}
+
+func (w *diskSpaceWatchdog) Start() {
+	if *MinFreeDiskSpaceMB <= 0 {
+		return
+	}
+	w.shutdown = make(chan struct{}, 1)
+	w.check()
+	w.waitgroup.Add(1)
+	go w.run()
+}
+
+func (w *diskSpaceWatchdog) Stop() {
+	if w.shutdown == nil {
+		return
+	}
+	w.shutdown <- struct{}{}
+	w.waitgroup.Wait()
+	w.shutdown = nil
+	util.SetDiskSpaceLow(false)
+}
+
+func (w *diskSpaceWatchdog) run() {
+	defer w.waitgroup.Done()
+	for {
+		if util.RandomSleep(time.Second*time.Duration(*DiskSpaceCheckInterval), 0, w.shutdown) {
+			return
+		}
+		w.check()
+	}
+}
+
+func (w *diskSpaceWatchdog) check() {
+	dataDir := config.LoongcollectorGlobalConfig.LoongCollectorGoCheckPointDir
+	usage, err := disk.Usage(dataDir)
+	if err != nil {
+		logger.Warning(context.Background(), "DISK_SPACE_ALARM", "get disk usage error", err, "dir", dataDir)
+		return
+	}
+	freeMB := usage.Free / 1024 / 1024
+	low := freeMB < uint64(*MinFreeDiskSpaceMB)
+	if low == util.IsDiskSpaceLow() {
+		return
+	}
+	util.SetDiskSpaceLow(low)
+	if low {
+		logger.Error(context.Background(), "DISK_SPACE_ALARM", "disk space is low, stop writing checkpoint and disk buffer",
+			"dir", dataDir, "free MB", freeMB, "threshold MB", *MinFreeDiskSpaceMB)
+	} else {
+		logger.Info(context.Background(), "disk space recovered", dataDir, "free MB", freeMB)
+	}
+}
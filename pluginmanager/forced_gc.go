@@ -0,0 +1,160 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"flag"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+// Strategies to force gc.
+const (
+	// GCStrategyInterval forces gc on each cycle.
+	GCStrategyInterval = "interval"
+	// GCStrategyPressure forces gc only when the heap in use exceeds GCPressureThresholdMB.
+	GCStrategyPressure = "pressure"
+)
+
+var ForcedGCIntervalSec = flag.Int("ForcedGCIntervalSec", 180, "interval to force gc and free memory to OS, second, 0 to disable")
+var GCStrategy = flag.String("GCStrategy", GCStrategyInterval, "strategy to force gc, interval or pressure")
+var GCPressureThresholdMB = flag.Int("GCPressureThresholdMB", 512, "heap in use above which gc is forced with the pressure strategy, MB")
+
+var forcedGCLock sync.Mutex
+var forcedGCInterval time.Duration
+var forcedGCIntervalSet bool
+var forcedGCReset = make(chan struct{}, 1)
+var forcedGCStop chan struct{}
+var forcedGCWaitGroup sync.WaitGroup
+
+// SetForcedGCInterval changes the interval to force gc, which takes effect on the next cycle.
+// Forced gc is disabled if d <= 0.
+func SetForcedGCInterval(d time.Duration) {
+	forcedGCLock.Lock()
+	forcedGCInterval = d
+	forcedGCIntervalSet = true
+	forcedGCLock.Unlock()
+	select {
+	case forcedGCReset <- struct{}{}:
+	default:
+	}
+}
+
+// getForcedGCInterval returns the interval set by SetForcedGCInterval, or ForcedGCIntervalSec if not set.
+func getForcedGCInterval() time.Duration {
+	forcedGCLock.Lock()
+	defer forcedGCLock.Unlock()
+	if forcedGCIntervalSet {
+		return forcedGCInterval
+	}
+	return time.Duration(*ForcedGCIntervalSec) * time.Second
+}
+
+// startForcedGC starts the forced gc goroutine if it is not running.
+func startForcedGC() {
+	forcedGCLock.Lock()
+	defer forcedGCLock.Unlock()
+	if forcedGCStop != nil {
+		return
+	}
+	forcedGCStop = make(chan struct{})
+	forcedGCWaitGroup.Add(1)
+	go runForcedGC(forcedGCStop)
+}
+
+// stopForcedGC stops the forced gc goroutine when the agent shuts down.
+func stopForcedGC() {
+	forcedGCLock.Lock()
+	stop := forcedGCStop
+	forcedGCStop = nil
+	forcedGCLock.Unlock()
+	if stop != nil {
+		close(stop)
+		forcedGCWaitGroup.Wait()
+	}
+}
+
+func runForcedGC(stop chan struct{}) {
+	defer forcedGCWaitGroup.Done()
+	// Flags may be changed after the goroutine starts, so check the interval again on each tick.
+	interval := getForcedGCInterval()
+	ticker := time.NewTicker(time.Hour)
+	resetTicker := func() {
+		if interval > 0 {
+			ticker.Reset(interval)
+		} else {
+			ticker.Stop()
+		}
+	}
+	resetTicker()
+	for {
+		select {
+		case <-stop:
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			if current := getForcedGCInterval(); current != interval {
+				interval = current
+				resetTicker()
+				continue
+			}
+			if shouldForceGC() {
+				forceGC()
+			}
+		case <-forcedGCReset:
+			interval = getForcedGCInterval()
+			resetTicker()
+		}
+	}
+}
+
+// shouldForceGC checks the memory pressure if GCStrategy is pressure, the heap is checked on each cycle.
+func shouldForceGC() bool {
+	if *GCStrategy != GCStrategyPressure {
+		return true
+	}
+	memStat := runtime.MemStats{}
+	runtime.ReadMemStats(&memStat)
+	return memStat.HeapInuse >= uint64(*GCPressureThresholdMB)*1024*1024
+}
+
+func forceGC() {
+	logger.Debug(context.Background(), "force gc done", time.Now())
+	runtime.GC()
+	logger.Debug(context.Background(), "force gc done", time.Now())
+	debug.FreeOSMemory()
+	logger.Debug(context.Background(), "free os memory done", time.Now())
+	if logger.DebugFlag() {
+		gcStat := debug.GCStats{}
+		debug.ReadGCStats(&gcStat)
+		logger.Debug(context.Background(), "gc stats", gcStat)
+		memStat := runtime.MemStats{}
+		runtime.ReadMemStats(&memStat)
+		logger.Debug(context.Background(), "mem stats", memStat)
+	}
+}
+
+func init() {
+	_ = util.InitFromEnvInt("FORCE_GC_INTERVAL_SECONDS", ForcedGCIntervalSec, *ForcedGCIntervalSec)
+	_ = util.InitFromEnvString("GC_STRATEGY", GCStrategy, *GCStrategy)
+	_ = util.InitFromEnvInt("GC_PRESSURE_THRESHOLD_MB", GCPressureThresholdMB, *GCPressureThresholdMB)
+	startForcedGC()
+}
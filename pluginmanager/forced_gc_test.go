@@ -0,0 +1,64 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func numForcedGC() uint32 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.NumForcedGC
+}
+
+func TestForcedGC(t *testing.T) {
+	defer func() {
+		forcedGCLock.Lock()
+		forcedGCIntervalSet = false
+		forcedGCLock.Unlock()
+		startForcedGC()
+	}()
+	startForcedGC()
+	SetForcedGCInterval(10 * time.Millisecond)
+	before := numForcedGC()
+	assert.Eventually(t, func() bool { return numForcedGC() > before }, 5*time.Second, 10*time.Millisecond)
+
+	stopForcedGC()
+	stopForcedGC()
+	time.Sleep(50 * time.Millisecond)
+	before = numForcedGC()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, before, numForcedGC())
+}
+
+func TestShouldForceGC(t *testing.T) {
+	strategy, threshold := *GCStrategy, *GCPressureThresholdMB
+	defer func() {
+		*GCStrategy, *GCPressureThresholdMB = strategy, threshold
+	}()
+	*GCStrategy = GCStrategyInterval
+	*GCPressureThresholdMB = 1 << 20
+	assert.True(t, shouldForceGC())
+
+	*GCStrategy = GCStrategyPressure
+	assert.False(t, shouldForceGC())
+	*GCPressureThresholdMB = 0
+	assert.True(t, shouldForceGC())
+}
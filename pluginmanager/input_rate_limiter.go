@@ -0,0 +1,119 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"math"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+)
+
+// tokenBucket holds up to rate tokens, refilled by rate tokens per second.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: float64(rate), tokens: float64(rate), last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// allows returns true if n tokens are available. More than rate tokens are never available,
+// so n is capped at rate, and data larger than the limit passes when the bucket is full.
+func (b *tokenBucket) allows(n float64) bool {
+	return b.tokens >= math.Min(n, b.rate)
+}
+
+// delay returns the time to wait until n tokens are taken, the bucket goes into debt for them.
+func (b *tokenBucket) delay(n float64) time.Duration {
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// inputRateLimiter limits the events and bytes passed from inputs to processors of a config, see
+// MaxEventsPerSecond and MaxBytesPerSecond of GlobalConfig. It is used by the processor goroutine only.
+type inputRateLimiter struct {
+	// events and bytes are nil if not limited.
+	events *tokenBucket
+	bytes  *tokenBucket
+	drop   bool
+}
+
+// newInputRateLimiter returns nil if the global config sets no limit.
+func newInputRateLimiter(globalConfig *config.GlobalConfig) *inputRateLimiter {
+	if globalConfig.MaxEventsPerSecond <= 0 && globalConfig.MaxBytesPerSecond <= 0 {
+		return nil
+	}
+	now := time.Now()
+	limiter := &inputRateLimiter{drop: globalConfig.LimitStrategy == config.LimitStrategyDrop}
+	if globalConfig.MaxEventsPerSecond > 0 {
+		limiter.events = newTokenBucket(globalConfig.MaxEventsPerSecond, now)
+	}
+	if globalConfig.MaxBytesPerSecond > 0 {
+		limiter.bytes = newTokenBucket(globalConfig.MaxBytesPerSecond, now)
+	}
+	return limiter
+}
+
+// admit decides whether count events of size bytes can be passed to processors. With LimitStrategyDrop,
+// it returns false if they are over the limit. Otherwise it waits until the rate allows, so that the input
+// queue fills up and inputs are blocked, unless cancel is closed as the config is stopping.
+func (l *inputRateLimiter) admit(count int, size int64, cancel <-chan struct{}) bool {
+	if l == nil {
+		return true
+	}
+	now := time.Now()
+	buckets := []*tokenBucket{l.events, l.bytes}
+	amounts := []float64{float64(count), float64(size)}
+	for i, bucket := range buckets {
+		if bucket == nil {
+			continue
+		}
+		bucket.refill(now)
+		if l.drop && !bucket.allows(amounts[i]) {
+			return false
+		}
+	}
+	var wait time.Duration
+	for i, bucket := range buckets {
+		if bucket == nil {
+			continue
+		}
+		if l.drop {
+			bucket.tokens = math.Max(0, bucket.tokens-amounts[i])
+		} else if delay := bucket.delay(amounts[i]); delay > wait {
+			wait = delay
+		}
+	}
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-cancel:
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+)
+
+func TestInputRateLimiterDrop(t *testing.T) {
+	assert.Nil(t, newInputRateLimiter(&config.GlobalConfig{}))
+	assert.True(t, (*inputRateLimiter)(nil).admit(1, 1, nil))
+
+	limiter := newInputRateLimiter(&config.GlobalConfig{MaxEventsPerSecond: 10, MaxBytesPerSecond: 100, LimitStrategy: config.LimitStrategyDrop})
+	require.NotNil(t, limiter)
+	for i := 0; i < 10; i++ {
+		assert.True(t, limiter.admit(1, 5, nil))
+	}
+	assert.False(t, limiter.admit(1, 5, nil))
+
+	// tokens are refilled as time goes by
+	limiter.events.last = limiter.events.last.Add(-time.Second)
+	limiter.bytes.last = limiter.bytes.last.Add(-time.Second)
+	assert.True(t, limiter.admit(2, 50, nil))
+	// bytes are limited as well
+	assert.False(t, limiter.admit(1, 60, nil))
+	// data larger than the limit passes when the bucket is full
+	limiter.bytes.last = limiter.bytes.last.Add(-time.Second)
+	assert.True(t, limiter.admit(1, 1000, nil))
+	assert.False(t, limiter.admit(1, 1, nil))
+}
+
+func TestInputRateLimiterPause(t *testing.T) {
+	limiter := newInputRateLimiter(&config.GlobalConfig{MaxEventsPerSecond: 100})
+	require.NotNil(t, limiter)
+	begin := time.Now()
+	assert.True(t, limiter.admit(100, 0, nil))
+	assert.Less(t, time.Since(begin), 50*time.Millisecond)
+	// data over the limit waits until the rate allows
+	assert.True(t, limiter.admit(20, 0, nil))
+	assert.GreaterOrEqual(t, time.Since(begin), 150*time.Millisecond)
+
+	// a stopping config does not wait
+	cancel := make(chan struct{})
+	close(cancel)
+	begin = time.Now()
+	assert.True(t, limiter.admit(100, 0, cancel))
+	assert.Less(t, time.Since(begin), 50*time.Millisecond)
+}
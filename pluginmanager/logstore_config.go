@@ -19,10 +19,12 @@ import (
 	"context"
 	"crypto/md5" //nolint:gosec
 	"encoding/json"
+	"flag"
 	"fmt"
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/alibaba/ilogtail/pkg/config"
 	"github.com/alibaba/ilogtail/pkg/logger"
@@ -34,6 +36,28 @@ import (
 
 var maxFlushOutTime = 5
 
+var StopCollectWaitTimeoutMs = flag.Int("StopCollectWaitTimeoutMs", 0, "time to wait for metric inputs in the middle of a collection when stopping a config, ms, 0 means not to wait")
+
+// collectWaitInterval is the interval to check if metric inputs finish collecting.
+const collectWaitInterval = 10 * time.Millisecond
+
+// StopOptions tunes how LogstoreConfig.StopWithOptions stops the config.
+type StopOptions struct {
+	// CollectWaitTimeout is the longest time to wait for metric inputs in the middle of a collection
+	// before inputs are cancelled, 0 means not to wait.
+	CollectWaitTimeout time.Duration
+	// shutdown is set when the config is stopped by StopAllPipelines, so that it is not quarantined after
+	// restart if it does not stop in time.
+	shutdown bool
+}
+
+func defaultStopOptions() StopOptions {
+	return StopOptions{CollectWaitTimeout: time.Duration(*StopCollectWaitTimeoutMs) * time.Millisecond}
+}
+
+// configGeneration is increased every time a LogstoreConfig is created.
+var configGeneration int64
+
 const mixProcessModeFlag = "mix_process_mode"
 
 type mixProcessMode int
@@ -90,34 +114,67 @@ type LogstoreConfig struct {
 	PluginRunner PluginRunner
 	// private fields
 	configDetailHash string
+	// pluginsHash is the hash of the config detail without the global block.
+	pluginsHash string
 
 	K8sLabelSet              map[string]struct{}
 	ContainerLabelSet        map[string]struct{}
 	EnvSet                   map[string]struct{}
 	CollectingContainersMeta bool
 	pluginID                 int32
+	// generation identifies this instance among all versions of the config.
+	generation int64
+	// warmReused is set when the config is taken from the warm pool and still running.
+	warmReused bool
+	// disabledTime is the time the config was disabled, protected by DisabledLogtailConfigLock.
+	disabledTime time.Time
+	// stopFinished is set once Stop called by timeoutStop returns, protected by DisabledLogtailConfigLock.
+	stopFinished bool
+	// panics records recent panics of the config to disable it when it keeps panicking.
+	panics configPanics
+	// collecting is the number of metric inputs in the middle of a collection.
+	collecting atomic.Int32
+	// spawnedGoroutines is the number of goroutines started by the runner in Start.
+	spawnedGoroutines atomic.Int64
+	// stoppingPlugin is the type of the plugin whose Stop has not returned yet, see stopPlugin.
+	stoppingPlugin atomic.Value
+	// deleted is set by the first DeleteLogstoreConfig of the config.
+	deleted atomic.Bool
+}
+
+// Generation returns the id of this instance, which increases monotonically each time a config is created.
+// It is the key of the instance in DisabledLogtailConfig.
+func (lc *LogstoreConfig) Generation() int64 {
+	return lc.generation
 }
 
 // Start initializes plugin instances in config and starts them.
 // Procedures:
-//  1. Start flusher goroutine and push FlushOutLogGroups inherited from last config
-//     instance to LogGroupsChan, so that they can be flushed to flushers.
+//  1. Start flusher goroutine, which flushes FlushOutLogGroups inherited from last config
+//     instance first.
 //  2. Start aggregators, allocate new goroutine for each one.
 //  3. Start processor goroutine to process logs from LogsChan.
 //  4. Start inputs (including metrics and services), just like aggregator, each input
 //     has its own goroutine.
 func (lc *LogstoreConfig) Start() {
+	if lc.warmReused {
+		lc.warmReused = false
+		logger.Info(lc.Context.GetRuntimeContext(), "config start", "skipped, reuse running plugins")
+		return
+	}
 	lc.FlushOutFlag.Store(false)
 	logger.Info(lc.Context.GetRuntimeContext(), "config start", "begin")
 
 	lc.PluginRunner.Run()
+	lc.spawnedGoroutines.Store(runningGoroutines(lc.PluginRunner))
 
-	logger.Info(lc.Context.GetRuntimeContext(), "config start", "success")
+	logger.Info(lc.Context.GetRuntimeContext(), "config start", "success", "goroutines", lc.spawnedGoroutines.Load())
 }
 
 // Stop stops plugin instances and corresponding goroutines of config.
 // @removedFlag passed from C++, indicates that if config will be removed after this.
 // Procedures:
+// 0. If DrainTimeoutMs is set, SetUrgent to all flushers and wait for queued data to be flushed.
 // 1. SetUrgent to all flushers to indicate them current state.
 // 2. Stop all input plugins, stop generating logs.
 // 3. Stop processor goroutine, pass all existing logs to aggregator.
@@ -126,7 +183,24 @@ func (lc *LogstoreConfig) Start() {
 // 6. If config will be removed and there are remaining data, try to flush once.
 // 7. Stop flusher plugins.
 func (lc *LogstoreConfig) Stop(removedFlag bool) error {
+	return lc.StopWithOptions(removedFlag, defaultStopOptions())
+}
+
+// StopWithOptions works like Stop. If opts.CollectWaitTimeout is set, it waits for metric inputs in the
+// middle of a collection to finish before inputs are cancelled, so that a collection is not cut in half.
+func (lc *LogstoreConfig) StopWithOptions(removedFlag bool, opts StopOptions) error {
 	logger.Info(lc.Context.GetRuntimeContext(), "config stop", "begin", "removing", removedFlag)
+	if lc.GlobalConfig != nil && lc.GlobalConfig.DrainTimeoutMs > 0 {
+		setFlushersUrgent(lc, removedFlag)
+		drainTimeout := time.Duration(lc.GlobalConfig.DrainTimeoutMs) * time.Millisecond
+		if drained := drainConfig(lc, time.Now().Add(drainTimeout)); !drained {
+			logger.Warning(lc.Context.GetRuntimeContext(), "CONFIG_DRAIN_ALARM", "data is not drained before stop", "timeout", drainTimeout)
+		}
+	}
+	if opts.CollectWaitTimeout > 0 && !lc.waitCollecting(time.Now().Add(opts.CollectWaitTimeout)) {
+		logger.Warning(lc.Context.GetRuntimeContext(), "CONFIG_STOP_ALARM", "collection is not finished before stop",
+			"timeout", opts.CollectWaitTimeout)
+	}
 	if err := lc.PluginRunner.Stop(removedFlag); err != nil {
 		return err
 	}
@@ -135,6 +209,47 @@ func (lc *LogstoreConfig) Stop(removedFlag bool) error {
 	return nil
 }
 
+// collect runs a collection of a metric input, so that Stop can wait for it. It is safe on a nil config.
+// The collection is delayed while the config is under backpressure, so that no collection is lost.
+func (lc *LogstoreConfig) collect(fn func() error) error {
+	if lc == nil {
+		return fn()
+	}
+	// blocks the same way as waitResumed of service inputs, it does not count as collecting for Stop to wait
+	for lc.underBackpressure() {
+		time.Sleep(inputPausedCheckInterval)
+	}
+	lc.collecting.Add(1)
+	defer lc.collecting.Add(-1)
+	return fn()
+}
+
+// overWatermark returns true if the queue usage is above BackpressureWatermarkPercent of the config.
+func (lc *LogstoreConfig) overWatermark(length, capacity int) bool {
+	if lc == nil || lc.GlobalConfig == nil || lc.GlobalConfig.BackpressureWatermarkPercent <= 0 || capacity == 0 {
+		return false
+	}
+	return length*100 >= capacity*lc.GlobalConfig.BackpressureWatermarkPercent
+}
+
+// underBackpressure returns true if inputs of the config should pause. Inputs are never paused
+// while the config is stopping, so that they can exit.
+func (lc *LogstoreConfig) underBackpressure() bool {
+	return lc.PluginRunner != nil && !lc.FlushOutFlag.Load() && lc.PluginRunner.ShouldPause()
+}
+
+// waitCollecting waits until no metric input is in the middle of a collection or the deadline is reached.
+// @return true if no collection is in progress.
+func (lc *LogstoreConfig) waitCollecting(deadline time.Time) bool {
+	for lc.collecting.Load() > 0 {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(collectWaitInterval)
+	}
+	return true
+}
+
 const (
 	rawStringKey     = "content"
 	defaultTagPrefix = "__tag__:__prefix__"
@@ -304,9 +419,43 @@ func hasDockerStdoutInput(plugins map[string]interface{}) bool {
 }
 
 func createLogstoreConfig(project string, logstore string, configName string, logstoreKey int64, jsonStr string) (*LogstoreConfig, error) {
-	var err error
+	logstoreC, err := buildLogstoreConfig(project, logstore, configName, logstoreKey, jsonStr)
+	if err != nil {
+		return nil, err
+	}
+	// Move unsent LogGroups from last config to new config, only when the new config is built successfully
+	// so that they are kept for the next load otherwise.
+	adoptUnsendBuffer(logstoreC)
+	return logstoreC, nil
+}
+
+// hashPlugins returns the hash of the config detail except the global block.
+func hashPlugins(plugins map[string]interface{}) (string, error) {
+	withoutGlobal := make(map[string]interface{}, len(plugins))
+	for key, value := range plugins {
+		if key != "global" {
+			withoutGlobal[key] = value
+		}
+	}
+	// map keys are sorted when marshaled, so the hash does not depend on the order of fields.
+	data, err := json.Marshal(withoutGlobal)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", md5.Sum(data)), nil //nolint:gosec
+}
+
+// buildLogstoreConfig parses jsonStr and creates the plugins of the config without starting them.
+func buildLogstoreConfig(project string, logstore string, configName string, logstoreKey int64, jsonStr string) (*LogstoreConfig, error) {
+	return buildLogstoreConfigWithContext(project, logstore, configName, logstoreKey, jsonStr, newRuntimeContext)
+}
+
+// buildLogstoreConfigWithContext works like buildLogstoreConfig, and derives the runtime context of the config
+// from the one returned by newContext, see newBuiltinRuntimeContext.
+func buildLogstoreConfigWithContext(project string, logstore string, configName string, logstoreKey int64, jsonStr string,
+	newContext func() (context.Context, context.CancelFunc)) (_ *LogstoreConfig, err error) {
 	contextImp := &ContextImp{}
-	contextImp.InitContext(project, logstore, configName)
+	contextImp.initContext(project, logstore, configName, newContext)
 	logstoreC := &LogstoreConfig{
 		ProjectName:          project,
 		LogstoreName:         logstore,
@@ -315,22 +464,34 @@ func createLogstoreConfig(project string, logstore string, configName string, lo
 		LogstoreKey:          logstoreKey,
 		Context:              contextImp,
 		configDetailHash:     fmt.Sprintf("%x", md5.Sum([]byte(jsonStr))), //nolint:gosec
+		generation:           atomic.AddInt64(&configGeneration, 1),
 	}
 	contextImp.logstoreC = logstoreC
+	// flushers and extensions loaded before the failure are stopped once the runner is inited
+	inited := false
+	defer func() {
+		if err == nil {
+			return
+		}
+		if inited {
+			closeUnstartedConfig(logstoreC)
+		} else {
+			contextImp.cancel()
+		}
+	}()
 
 	var plugins = make(map[string]interface{})
 	if err = json.Unmarshal([]byte(jsonStr), &plugins); err != nil {
 		return nil, err
 	}
+	if logstoreC.pluginsHash, err = hashPlugins(plugins); err != nil {
+		return nil, err
+	}
 
 	logstoreC.Version = fetchPluginVersion(plugins)
 	if logstoreC.PluginRunner, err = initPluginRunner(logstoreC); err != nil {
 		return nil, err
 	}
-	if lastConfigRunner, hasLastConfig := LastUnsendBuffer[configName]; hasLastConfig {
-		// Move unsent LogGroups from last config to new config.
-		logstoreC.PluginRunner.Merge(lastConfigRunner)
-	}
 
 	logstoreC.ContainerLabelSet = make(map[string]struct{})
 	logstoreC.EnvSet = make(map[string]struct{})
@@ -447,6 +608,7 @@ func createLogstoreConfig(project string, logstore string, configName string, lo
 	if err = logstoreC.PluginRunner.Init(logQueueSize, logGroupSize); err != nil {
 		return nil, err
 	}
+	inited = true
 
 	// extensions should be initialized first
 	pluginConfig, ok := plugins["extensions"]
@@ -631,39 +793,52 @@ func LoadLogstoreConfig(project string, logstore string, configName string, logs
 	if len(jsonStr) == 0 {
 		logger.Info(context.Background(), "delete config", configName, "logstore", logstore)
 		DeleteLogstoreConfigFromLogtailConfig(configName, true)
+		discardWarmConfig(configName, true)
 		return nil
 	}
 	logger.Info(context.Background(), "load config", configName, "logstore", logstore)
-	logstoreC, err := createLogstoreConfig(project, logstore, configName, logstoreKey, jsonStr)
-	if err != nil {
-		return err
+	logstoreC := reuseWarmConfig(project, logstore, configName, logstoreKey, jsonStr)
+	if logstoreC == nil {
+		var err error
+		if logstoreC, err = createLogstoreConfig(project, logstore, configName, logstoreKey, jsonStr); err != nil {
+			return err
+		}
 	}
+	forgetSiblingStartFailure(configName)
 	if logstoreC.PluginRunner.IsWithInputPlugin() {
 		ToStartPipelineConfigWithInput = logstoreC
 	} else {
 		ToStartPipelineConfigWithoutInput = logstoreC
 	}
+	recordConfigEvent(configName, ConfigEventLoaded)
 	return nil
 }
 
 func UnloadPartiallyLoadedConfig(configName string) error {
 	logger.Info(context.Background(), "unload config", configName)
-	if ToStartPipelineConfigWithInput.ConfigNameWithSuffix == configName {
+	if ToStartPipelineConfigWithInput != nil && ToStartPipelineConfigWithInput.ConfigNameWithSuffix == configName {
+		unloadWarmReusedConfig(ToStartPipelineConfigWithInput)
 		ToStartPipelineConfigWithInput = nil
 		return nil
 	}
-	if ToStartPipelineConfigWithoutInput.ConfigNameWithSuffix == configName {
+	if ToStartPipelineConfigWithoutInput != nil && ToStartPipelineConfigWithoutInput.ConfigNameWithSuffix == configName {
+		unloadWarmReusedConfig(ToStartPipelineConfigWithoutInput)
 		ToStartPipelineConfigWithoutInput = nil
 		return nil
 	}
 	logger.Error(context.Background(), "unload config", "config not found", configName)
-	return fmt.Errorf("config not found")
+	return ErrConfigNotFound
 }
 
 func loadBuiltinConfig(name string, project string, logstore string,
 	configName string, cfgStr string) (*LogstoreConfig, error) {
 	logger.Infof(context.Background(), "load built-in config %v, config name: %v, logstore: %v", name, configName, logstore)
-	return createLogstoreConfig(project, logstore, configName, -1, cfgStr)
+	logstoreC, err := buildLogstoreConfigWithContext(project, logstore, configName, -1, cfgStr, newBuiltinRuntimeContext)
+	if err != nil {
+		return nil, err
+	}
+	adoptUnsendBuffer(logstoreC)
+	return logstoreC, nil
 }
 
 // loadMetric creates a metric plugin object and append to logstoreConfig.MetricPlugins.
@@ -0,0 +1,64 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ManagerStatistics are lifetime statistics of configs managed by the plugin manager.
+type ManagerStatistics struct {
+	Uptime         time.Duration
+	StartedConfigs int64 // configs started, including reloaded ones
+	StoppedConfigs int64 // configs stopped, including those disabled because of stop timeout
+	RunningConfigs int
+	PeakConfigs    int64 // max number of configs running at the same time
+}
+
+var managerStartTime = time.Now()
+var startedConfigs atomic.Int64
+var stoppedConfigs atomic.Int64
+var peakConfigs atomic.Int64
+
+// ManagerStats returns lifetime statistics of configs since the process started.
+func ManagerStats() ManagerStatistics {
+	LogtailConfigLock.RLock()
+	running := len(LogtailConfig)
+	LogtailConfigLock.RUnlock()
+	return ManagerStatistics{
+		Uptime:         time.Since(managerStartTime),
+		StartedConfigs: startedConfigs.Load(),
+		StoppedConfigs: stoppedConfigs.Load(),
+		RunningConfigs: running,
+		PeakConfigs:    peakConfigs.Load(),
+	}
+}
+
+// recordConfigStarted is called with LogtailConfigLock held after a config is added to LogtailConfig.
+func recordConfigStarted() {
+	startedConfigs.Add(1)
+	if running := int64(len(LogtailConfig)); running > peakConfigs.Load() {
+		peakConfigs.Store(running)
+	}
+}
+
+func init() {
+	RegisterConfigStateListener(func(configName string, state ConfigState) {
+		if state == ConfigStateStopped || state == ConfigStateDisabled {
+			stoppedConfigs.Add(1)
+		}
+	})
+}
@@ -17,6 +17,7 @@ import (
 	goruntimemetrics "runtime/metrics"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alibaba/ilogtail/pkg/helper/k8smeta"
 	"github.com/alibaba/ilogtail/pkg/selfmonitor"
@@ -56,6 +57,8 @@ func GetGoDirectMetrics() []map[string]string {
 	metrics := make([]map[string]string, 0)
 	// go plugin metrics
 	metrics = append(metrics, GetGoPluginMetrics()...)
+	// disabled pipeline metrics
+	metrics = append(metrics, GetDisabledConfigMetrics()...)
 	// k8s meta metrics
 	metrics = append(metrics, k8smeta.GetMetaManagerMetrics()...)
 	return metrics
@@ -79,11 +82,26 @@ func GetGoCppProvidedMetrics() []map[string]string {
 // go 插件指标，直接输出
 func GetGoPluginMetrics() []map[string]string {
 	metrics := make([]map[string]string, 0)
-	LogtailConfigLock.RLock()
-	for _, config := range LogtailConfig {
+	RangeConfigs(func(_ string, config *LogstoreConfig) bool {
 		metrics = append(metrics, config.Context.ExportMetricRecords()...)
+		return true
+	})
+	return metrics
+}
+
+// 因停止超时被禁用的配置，直接输出其被禁用的时长
+func GetDisabledConfigMetrics() []map[string]string {
+	metrics := make([]map[string]string, 0)
+	for _, config := range GetDisabledConfigs() {
+		metricsRecord := &selfmonitor.MetricsRecord{Labels: []selfmonitor.LabelPair{
+			{Key: selfmonitor.MetricLabelKeyMetricCategory, Value: selfmonitor.MetricLabelValueMetricCategoryPipeline},
+			{Key: selfmonitor.MetricLabelKeyProject, Value: config.ProjectName},
+			{Key: selfmonitor.MetricLabelKeyLogstore, Value: config.LogstoreName},
+			{Key: selfmonitor.MetricLabelKeyPipelineName, Value: config.ConfigNameWithSuffix},
+		}}
+		selfmonitor.NewGaugeMetricAndRegister(metricsRecord, selfmonitor.MetricPipelineDisabledSeconds).Set(time.Since(config.DisabledTime).Seconds())
+		metrics = append(metrics, metricsRecord.ExportMetricRecords())
 	}
-	LogtailConfigLock.RUnlock()
 	return metrics
 }
 
@@ -123,6 +141,10 @@ func GetAgentStat() []map[string]string {
 		}
 		metric[key] = valueStr
 	}
+	metric[selfmonitor.MetricAgentGoRoutinesLeaked] = strconv.FormatInt(LeakedGoroutines(), 10)
+	metric[selfmonitor.MetricAgentUnsendBufferRecoveredRecordsTotal] = strconv.FormatInt(unsendBufferRecoveredRecords.Load(), 10)
+	metric[selfmonitor.MetricAgentUnsendBufferRecoveredBytesTotal] = strconv.FormatInt(unsendBufferRecoveredBytes.Load(), 10)
+	metric[selfmonitor.MetricAgentUnsendBufferDroppedTotal] = strconv.FormatInt(unsendBufferDropped.Load(), 10)
 
 	metrics = append(metrics, metric)
 	return metrics
@@ -0,0 +1,121 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const prometheusMetricPrefix = "loongcollector_"
+
+// prometheusFamily is a metric family in the Prometheus text format.
+type prometheusFamily struct {
+	name       string
+	help       string
+	metricType string
+	samples    []prometheusSample
+}
+
+type prometheusSample struct {
+	labels [][2]string
+	value  int64
+}
+
+func (f *prometheusFamily) add(value int64, labels ...[2]string) {
+	f.samples = append(f.samples, prometheusSample{labels: labels, value: value})
+}
+
+// PrometheusHandler returns a handler serving the self monitor counters of the agent in the Prometheus
+// text format, so that they can be scraped without the alarm or container built-in configs.
+// Values are read from the same counters as those configs: RecordCounters of running configs,
+// FlusherStats, the aggregator queues sampled by ConfigMemoryStats, GetDisabledConfigCount and PanicCounts.
+func PrometheusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write(encodePrometheusFamilies(collectPrometheusFamilies()))
+	})
+}
+
+func collectPrometheusFamilies() []*prometheusFamily {
+	inputRecords := &prometheusFamily{name: "pipeline_input_records_total", help: "Records received from inputs of the config.", metricType: "counter"}
+	flushedRecords := &prometheusFamily{name: "pipeline_flushed_records_total", help: "Records flushed by all flushers of the config without error.", metricType: "counter"}
+	droppedRecords := &prometheusFamily{name: "pipeline_dropped_records_total", help: "Records dropped by the config, by stage.", metricType: "counter"}
+	queueDepth := &prometheusFamily{name: "pipeline_aggregator_queue_depth", help: "Groups from aggregators waiting for flushers of the config.", metricType: "gauge"}
+	RangeConfigs(func(configName string, config *LogstoreConfig) bool {
+		name := [2]string{"config", configName}
+		counters := config.RecordCounters()
+		inputRecords.add(counters.InputRecords, name)
+		flushedRecords.add(counters.FlushedRecords, name)
+		droppedRecords.add(counters.ProcessorDroppedRecords, name, [2]string{"stage", "processor"})
+		droppedRecords.add(counters.AggregatorDroppedRecords, name, [2]string{"stage", "aggregator"})
+		droppedRecords.add(counters.FlusherDroppedRecords, name, [2]string{"stage", "flusher"})
+		droppedRecords.add(counters.RateLimitDroppedRecords, name, [2]string{"stage", "rate_limit"})
+		queueDepth.add(int64(sampleConfigMemUsage(config).AggregatorQueueDepth), name)
+		return true
+	})
+
+	flusherSent := &prometheusFamily{name: "flusher_sent_total", help: "Groups sent by flushers of the type in the config.", metricType: "counter"}
+	flusherFailed := &prometheusFamily{name: "flusher_failed_total", help: "Groups failed to be sent by flushers of the type in the config.", metricType: "counter"}
+	for _, stat := range FlusherStats() {
+		labels := [][2]string{{"config", stat.ConfigName}, {"flusher_type", stat.FlusherType}}
+		flusherSent.add(stat.Sent, labels...)
+		flusherFailed.add(stat.Failed, labels...)
+	}
+
+	disabledConfigs := &prometheusFamily{name: "disabled_configs", help: "Configs which do not stop in time.", metricType: "gauge"}
+	disabledConfigs.add(int64(GetDisabledConfigCount()))
+
+	panics := &prometheusFamily{name: "panics_total", help: "Panics recovered from plugins of the type since the process started.", metricType: "counter"}
+	for pluginType, count := range PanicCounts() {
+		panics.add(count, [2]string{"plugin_type", pluginType})
+	}
+
+	return []*prometheusFamily{inputRecords, flushedRecords, droppedRecords, queueDepth, flusherSent, flusherFailed, disabledConfigs, panics}
+}
+
+// encodePrometheusFamilies writes families in the given order, samples of a family are sorted by labels.
+func encodePrometheusFamilies(families []*prometheusFamily) []byte {
+	var buf bytes.Buffer
+	for _, family := range families {
+		name := prometheusMetricPrefix + family.name
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", name, family.help, name, family.metricType)
+		lines := make([]string, 0, len(family.samples))
+		for _, sample := range family.samples {
+			lines = append(lines, fmt.Sprintf("%s%s %d\n", name, encodePrometheusLabels(sample.labels), sample.value))
+		}
+		sort.Strings(lines)
+		for _, line := range lines {
+			buf.WriteString(line)
+		}
+	}
+	return buf.Bytes()
+}
+
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func encodePrometheusLabels(labels [][2]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		pairs = append(pairs, label[0]+`="`+prometheusLabelEscaper.Replace(label[1])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
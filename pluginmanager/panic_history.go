@@ -0,0 +1,166 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxRecentPanics       = 64
+	maxPanicMessageLength = 1024
+	minPanicStackSize     = 2048
+	maxPanicStackSize     = 1 << 20
+)
+
+// PanicRecord describes a panic recovered by panicRecover.
+type PanicRecord struct {
+	PluginType     string
+	Time           time.Time
+	Message        string // truncated to maxPanicMessageLength
+	GoroutineCount int    // number of goroutines when the panic is recovered
+}
+
+// recentPanics is a ring of the latest maxRecentPanics panics.
+var recentPanicsLock sync.Mutex
+var recentPanics = make([]PanicRecord, 0, maxRecentPanics)
+var recentPanicsNext int
+
+// panicCounts counts panics recovered by panicRecover, keyed by plugin type.
+var panicCountsLock sync.Mutex
+var panicCounts = make(map[string]*atomic.Int64)
+
+var PanicAlarmsPerMinute = flag.Int("PanicAlarmsPerMinute", 10, "max PLUGIN_RUNTIME_ALARM logged for panics of each plugin type per minute, 0 for no limit")
+
+// panicAlarmWindow counts PLUGIN_RUNTIME_ALARM of a plugin type in the current minute.
+type panicAlarmWindow struct {
+	start      time.Time
+	logged     int
+	suppressed int64
+}
+
+var panicAlarmWindowsLock sync.Mutex
+var panicAlarmWindows = make(map[string]*panicAlarmWindow)
+
+var PanicStackAllGoroutines = flag.Bool("PanicStackAllGoroutines", false, "log stacks of all goroutines rather than the panicking one when a plugin panics, for debug")
+
+var panicRethrow atomic.Bool
+
+// SetPanicRethrow makes panicRecover panic again after the panic is logged and recorded,
+// so that panics in plugins fail unit and integration tests.
+func SetPanicRethrow(rethrow bool) {
+	panicRethrow.Store(rethrow)
+}
+
+// PanicCounts returns the number of panics recovered from each plugin type since the process started.
+func PanicCounts() map[string]int64 {
+	panicCountsLock.Lock()
+	defer panicCountsLock.Unlock()
+	counts := make(map[string]int64, len(panicCounts))
+	for pluginType, count := range panicCounts {
+		counts[pluginType] = count.Load()
+	}
+	return counts
+}
+
+func countPanic(pluginType string) {
+	panicCountsLock.Lock()
+	count, ok := panicCounts[pluginType]
+	if !ok {
+		count = new(atomic.Int64)
+		panicCounts[pluginType] = count
+	}
+	panicCountsLock.Unlock()
+	count.Add(1)
+}
+
+// admitPanicAlarm decides whether PLUGIN_RUNTIME_ALARM is logged for a panic of the plugin type, so that a plugin
+// panicking repeatedly logs at most PanicAlarmsPerMinute alarms each minute. Panics are recorded anyway.
+// @return whether to log the alarm, whether it is the first alarm of the minute, which carries the full stack,
+// and the number of alarms suppressed in the previous minute, if the alarm starts a new minute.
+func admitPanicAlarm(pluginType string, now time.Time) (log bool, first bool, suppressed int64) {
+	limit := *PanicAlarmsPerMinute
+	if limit <= 0 {
+		return true, true, 0
+	}
+	panicAlarmWindowsLock.Lock()
+	defer panicAlarmWindowsLock.Unlock()
+	window, ok := panicAlarmWindows[pluginType]
+	if !ok {
+		window = &panicAlarmWindow{start: now}
+		panicAlarmWindows[pluginType] = window
+	} else if now.Sub(window.start) >= time.Minute {
+		suppressed = window.suppressed
+		*window = panicAlarmWindow{start: now}
+	}
+	if window.logged >= limit {
+		window.suppressed++
+		return false, false, 0
+	}
+	window.logged++
+	return true, window.logged == 1, suppressed
+}
+
+// panicStack returns the stack of the current goroutine, or of all goroutines if all is set.
+// The buffer grows until the stack is not truncated or maxPanicStackSize is reached.
+func panicStack(all bool) []byte {
+	buf := make([]byte, minPanicStackSize)
+	for {
+		n := runtime.Stack(buf, all)
+		if n < len(buf) || len(buf) >= maxPanicStackSize {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+func recordPanic(pluginType string, err interface{}) {
+	countPanic(pluginType)
+	message := fmt.Sprint(err)
+	if len(message) > maxPanicMessageLength {
+		message = message[:maxPanicMessageLength]
+	}
+	record := PanicRecord{
+		PluginType:     pluginType,
+		Time:           time.Now(),
+		Message:        message,
+		GoroutineCount: runtime.NumGoroutine(),
+	}
+	recentPanicsLock.Lock()
+	defer recentPanicsLock.Unlock()
+	if len(recentPanics) < maxRecentPanics {
+		recentPanics = append(recentPanics, record)
+	} else {
+		recentPanics[recentPanicsNext] = record
+	}
+	recentPanicsNext = (recentPanicsNext + 1) % maxRecentPanics
+}
+
+// RecentPanics returns the latest panics recovered from plugins, oldest first.
+func RecentPanics() []PanicRecord {
+	recentPanicsLock.Lock()
+	defer recentPanicsLock.Unlock()
+	records := make([]PanicRecord, 0, len(recentPanics))
+	if len(recentPanics) == maxRecentPanics {
+		records = append(records, recentPanics[recentPanicsNext:]...)
+		return append(records, recentPanics[:recentPanicsNext]...)
+	}
+	return append(records, recentPanics...)
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentPanics(t *testing.T) {
+	func() {
+		defer panicRecover("test_plugin")
+		panic(strings.Repeat("x", maxPanicMessageLength+1))
+	}()
+	records := RecentPanics()
+	assert.NotEmpty(t, records)
+	last := records[len(records)-1]
+	assert.Equal(t, "test_plugin", last.PluginType)
+	assert.Equal(t, maxPanicMessageLength, len(last.Message))
+	assert.True(t, last.GoroutineCount > 0)
+	assert.False(t, last.Time.IsZero())
+
+	for i := 0; i < maxRecentPanics+10; i++ {
+		recordPanic("test_plugin", i)
+	}
+	records = RecentPanics()
+	assert.Equal(t, maxRecentPanics, len(records))
+	assert.Equal(t, "10", records[0].Message)
+	assert.Equal(t, fmt.Sprint(maxRecentPanics+9), records[maxRecentPanics-1].Message)
+}
+
+func TestPanicCounts(t *testing.T) {
+	before := PanicCounts()["count_test_plugin"]
+	func() {
+		defer panicRecover("count_test_plugin")
+		panic("count test")
+	}()
+	assert.Equal(t, before+1, PanicCounts()["count_test_plugin"])
+}
+
+func TestPanicRethrow(t *testing.T) {
+	SetPanicRethrow(true)
+	defer SetPanicRethrow(false)
+	assert.PanicsWithValue(t, "rethrow test", func() {
+		defer panicRecover("rethrow_test_plugin")
+		panic("rethrow test")
+	})
+	assert.Equal(t, int64(1), PanicCounts()["rethrow_test_plugin"])
+}
+
+func TestAdmitPanicAlarm(t *testing.T) {
+	defer func(limit int) { *PanicAlarmsPerMinute = limit }(*PanicAlarmsPerMinute)
+	*PanicAlarmsPerMinute = 2
+	now := time.Now()
+	log, first, suppressed := admitPanicAlarm("alarm_test_plugin", now)
+	assert.Equal(t, []interface{}{true, true, int64(0)}, []interface{}{log, first, suppressed})
+	log, first, _ = admitPanicAlarm("alarm_test_plugin", now.Add(time.Second))
+	assert.True(t, log)
+	assert.False(t, first)
+	for i := 0; i < 3; i++ {
+		log, _, _ = admitPanicAlarm("alarm_test_plugin", now.Add(2*time.Second))
+		assert.False(t, log)
+	}
+	// other plugin types are limited separately
+	log, _, _ = admitPanicAlarm("other_alarm_test_plugin", now.Add(2*time.Second))
+	assert.True(t, log)
+
+	// the first alarm of the next minute carries the stack and the suppressed count
+	log, first, suppressed = admitPanicAlarm("alarm_test_plugin", now.Add(time.Minute))
+	assert.Equal(t, []interface{}{true, true, int64(3)}, []interface{}{log, first, suppressed})
+	log, first, suppressed = admitPanicAlarm("alarm_test_plugin", now.Add(2*time.Minute))
+	assert.Equal(t, []interface{}{true, true, int64(0)}, []interface{}{log, first, suppressed})
+
+	*PanicAlarmsPerMinute = 0
+	for i := 0; i < 3; i++ {
+		log, first, _ = admitPanicAlarm("alarm_test_plugin", now.Add(2*time.Minute))
+		assert.True(t, log)
+		assert.True(t, first)
+	}
+	// panics are counted even if alarms are suppressed
+	*PanicAlarmsPerMinute = 1
+	before := PanicCounts()["suppressed_test_plugin"]
+	for i := 0; i < 3; i++ {
+		func() {
+			defer panicRecover("suppressed_test_plugin")
+			panic("suppressed test")
+		}()
+	}
+	assert.Equal(t, before+3, PanicCounts()["suppressed_test_plugin"])
+}
+
+func TestPanicStack(t *testing.T) {
+	stack := panicStack(true)
+	assert.NotEmpty(t, stack)
+	assert.NotContains(t, string(stack), "\x00")
+	assert.True(t, len(stack) <= maxPanicStackSize)
+}
+
+func deepStack(depth int, blocked chan struct{}, release chan struct{}) {
+	if depth == 0 {
+		close(blocked)
+		<-release
+		return
+	}
+	deepStack(depth-1, blocked, release)
+}
+
+func TestPanicStackNotTruncated(t *testing.T) {
+	blocked, release := make(chan struct{}), make(chan struct{})
+	go deepStack(200, blocked, release)
+	<-blocked
+	defer close(release)
+	stack := string(panicStack(true))
+	assert.True(t, len(stack) > minPanicStackSize)
+	// the creator of the deep goroutine is printed after all its frames
+	assert.Contains(t, stack, "created by github.com/alibaba/ilogtail/pluginmanager.TestPanicStackNotTruncated")
+
+	stack = string(panicStack(false))
+	assert.Contains(t, stack, "pluginmanager.TestPanicStackNotTruncated")
+	assert.NotContains(t, stack, "pluginmanager.deepStack")
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+// PluginCounts are the numbers of plugins of each category.
+type PluginCounts struct {
+	MetricInputs  int
+	ServiceInputs int
+	Processors    int
+	Aggregators   int
+	Flushers      int
+}
+
+func (c *PluginCounts) add(other PluginCounts) {
+	c.MetricInputs += other.MetricInputs
+	c.ServiceInputs += other.ServiceInputs
+	c.Processors += other.Processors
+	c.Aggregators += other.Aggregators
+	c.Flushers += other.Flushers
+}
+
+// PluginInventorySummary counts plugins of all running configs, in total and by the version of the pipeline.
+type PluginInventorySummary struct {
+	Total     PluginCounts
+	V1        PluginCounts
+	V2        PluginCounts
+	V1Configs int
+	V2Configs int
+}
+
+// PluginInventory returns the numbers of plugins of each category across all configs in LogtailConfig.
+// Runners are collected under LogtailConfigLock and counted after it is released, so that starting and
+// stopping configs are not blocked by counting.
+func PluginInventory() PluginInventorySummary {
+	runners := make([]PluginRunner, 0)
+	RangeConfigs(func(_ string, config *LogstoreConfig) bool {
+		if config.PluginRunner != nil {
+			runners = append(runners, config.PluginRunner)
+		}
+		return true
+	})
+	var summary PluginInventorySummary
+	for _, runner := range runners {
+		switch r := runner.(type) {
+		case *pluginv1Runner:
+			r.processorLock.RLock()
+			processors := len(r.ProcessorPlugins)
+			r.processorLock.RUnlock()
+			summary.V1.add(PluginCounts{
+				MetricInputs:  len(r.MetricPlugins),
+				ServiceInputs: len(r.ServicePlugins),
+				Processors:    processors,
+				Aggregators:   len(r.AggregatorPlugins),
+				Flushers:      len(r.FlusherPlugins),
+			})
+			summary.V1Configs++
+		case *pluginv2Runner:
+			r.processorLock.RLock()
+			processors := len(r.ProcessorPlugins)
+			r.processorLock.RUnlock()
+			summary.V2.add(PluginCounts{
+				MetricInputs:  len(r.MetricPlugins),
+				ServiceInputs: len(r.ServicePlugins),
+				Processors:    processors,
+				Aggregators:   len(r.AggregatorPlugins),
+				Flushers:      len(r.FlusherPlugins),
+			})
+			summary.V2Configs++
+		}
+	}
+	summary.Total.add(summary.V1)
+	summary.Total.add(summary.V2)
+	return summary
+}
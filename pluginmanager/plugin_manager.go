@@ -16,17 +16,21 @@ package pluginmanager
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"runtime"
-	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/alibaba/ilogtail/pkg/config"
-	"github.com/alibaba/ilogtail/pkg/flags"
 	"github.com/alibaba/ilogtail/pkg/helper"
 	"github.com/alibaba/ilogtail/pkg/logger"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
 )
 
 // Following variables are exported so that tests of main package can reference them.
@@ -39,10 +43,59 @@ var ToStartPipelineConfigWithInput *LogstoreConfig
 var ToStartPipelineConfigWithoutInput *LogstoreConfig
 var ContainerConfig *LogstoreConfig
 
-// Configs that were disabled because of slow or hang config.
+// Configs that were disabled because of slow or hang config, keyed by LogstoreConfig.Generation.
+// The key is versioned so that a config reloaded multiple times while hanging keeps all its instances tracked.
 var DisabledLogtailConfigLock sync.RWMutex
-var DisabledLogtailConfig = make(map[*LogstoreConfig]struct{})
+var DisabledLogtailConfig = make(map[int64]*LogstoreConfig)
 
+// ErrConfigNotFound is returned when the given config is neither running nor loaded.
+var ErrConfigNotFound = errors.New("config not found")
+
+// ErrConfigMismatch is returned by Start when the given config is not the loaded one.
+var ErrConfigMismatch = errors.New("config unmatch with the loaded pipeline")
+
+// ErrDuplicateConfig is returned by Start when another config with the same name is running in the same pipeline.
+var ErrDuplicateConfig = errors.New("duplicate config")
+
+// ErrAlreadyStarted is returned by Start when the given config is running and no new instance of it is loaded.
+var ErrAlreadyStarted = errors.New("config already started")
+
+// ErrAmbiguousConfigName is returned by Start and Stop when the given config name without suffix matches
+// more than one pipeline.
+var ErrAmbiguousConfigName = errors.New("ambiguous config name")
+
+// ErrStartTimeout is returned by Start when the config does not start within ConfigStartTimeoutMs.
+var ErrStartTimeout = errors.New("config start timeout")
+
+// ErrForceStopIncomplete is returned by ForceStop when plugins of the config still do not exit after it is
+// released forcibly.
+var ErrForceStopIncomplete = errors.New("force stop incomplete")
+
+// ErrPluginNotFound is returned when the config has no plugin of the given type and id.
+var ErrPluginNotFound = errors.New("plugin not found")
+
+// ErrReconfigUnsupported is returned by UpdatePluginDetail when the plugin does not implement pipeline.Reconfigurable.
+var ErrReconfigUnsupported = errors.New("plugin reconfiguration unsupported")
+
+// ErrDependencyCycle is returned by Start when the config depends on itself through DependsOn of the configs
+// waiting for their dependencies.
+var ErrDependencyCycle = errors.New("config dependency cycle")
+
+// configStopTimeout is the time timeoutStop waits for a config to stop.
+var configStopTimeout = 30 * time.Second
+
+var ConfigStartTimeoutMs = flag.Int("ConfigStartTimeoutMs", 30000, "time to wait for a config to start, the config is aborted if it does not start in time, ms")
+
+var FlushBufferOnRemove = flag.Bool("FlushBufferOnRemove", false, "try to flush data left in the buffer of a removed config once more before deleting it, instead of dropping it")
+
+var StopAllPipelinesConcurrency = flag.Int("StopAllPipelinesConcurrency", 8, "max number of configs stopped in parallel when stop all pipelines")
+
+var DisabledConfigAlarmMinutes = flag.Int("DisabledConfigAlarmMinutes", 10, "alarm when a config has been disabled for longer than this, minute")
+
+// Runners of stopped configs that still hold unsent data, keyed by unsendBufferKey.
+// The key is versioned so that a config stopped multiple times does not overwrite the previous runner.
+// Runners not adopted in time are dropped, see UnsendBufferTTLSec and MaxUnsendBuffers.
+var LastUnsendBufferLock sync.Mutex
 var LastUnsendBuffer = make(map[string]PluginRunner)
 
 // Two built-in logtail configs to report statistics and alarm (from system and other logtail configs).
@@ -90,151 +143,446 @@ var containerConfigJSON = `{
 
 func panicRecover(pluginType string) {
 	if err := recover(); err != nil {
-		trace := make([]byte, 2048)
-		runtime.Stack(trace, true)
-		logger.Error(context.Background(), "PLUGIN_RUNTIME_ALARM", "plugin", pluginType, "panicked", err, "stack", string(trace))
+		handlePanic(nil, pluginType, err)
+	}
+}
+
+// configPanicRecover works like panicRecover, and counts the panic in the config, see PanicDisableThreshold.
+func configPanicRecover(config *LogstoreConfig, pluginType string) {
+	if err := recover(); err != nil {
+		handlePanic(config, pluginType, err)
+	}
+}
+
+func handlePanic(config *LogstoreConfig, pluginType string, err interface{}) {
+	if log, first, suppressed := admitPanicAlarm(pluginType, time.Now()); log {
+		if suppressed > 0 {
+			logger.Warning(context.Background(), "PLUGIN_RUNTIME_ALARM", "plugin", pluginType, "panic alarms suppressed in the last minute", suppressed)
+		}
+		if first {
+			logger.Error(context.Background(), "PLUGIN_RUNTIME_ALARM", "plugin", pluginType, "panicked", err, "stack", string(panicStack(*PanicStackAllGoroutines)))
+		} else {
+			logger.Error(context.Background(), "PLUGIN_RUNTIME_ALARM", "plugin", pluginType, "panicked", err, "stack", "omitted after the first panic of the minute")
+		}
+	}
+	recordPanic(pluginType, err)
+	if config != nil {
+		recordConfigPanic(config)
+	}
+	if panicRethrow.Load() {
+		panic(err)
 	}
 }
 
 // Init initializes plugin manager.
-func Init() (err error) {
+// If a built-in config fails to load, a *BuiltinConfigLoadError is returned, unless allowDegraded is set
+// and the built-in config is not critical.
+// Init returns when ctx is done, without waiting for the checkpoint manager to be initialized.
+func Init(ctx context.Context, allowDegraded bool) (err error) {
 	logger.Info(context.Background(), "init plugin, local env tags", helper.EnvTags)
 
-	if err = CheckPointManager.Init(); err != nil {
+	if err = initCheckPointManager(ctx); err != nil {
 		return
 	}
-	if AlarmConfig, err = loadBuiltinConfig("alarm", "sls-admin", "logtail_alarm",
-		"logtail_alarm", alarmConfigJSON); err != nil {
-		logger.Error(context.Background(), "LOAD_PLUGIN_ALARM", "load alarm config fail", err)
-		return
+	loadQuarantinedConfigs()
+	if err = ctx.Err(); err != nil {
+		return fmt.Errorf("init plugin manager: %w", err)
 	}
-	if ContainerConfig, err = loadBuiltinConfig("container", "sls-admin", "logtail_containers", "logtail_containers", containerConfigJSON); err != nil {
-		logger.Error(context.Background(), "LOAD_PLUGIN_ALARM", "load container config fail", err)
+	startForcedGC()
+	if err = loadBuiltinConfigs(allowDegraded); err != nil {
 		return
 	}
-	logger.Info(context.Background(), "loadBuiltinConfig container")
+	logger.Info(context.Background(), "loadBuiltinConfig done")
 	return
 }
 
-// timeoutStop wrappers LogstoreConfig.Stop with timeout (5s by default).
-// @return true if Stop returns before timeout, otherwise false.
+// initCheckPointManager initializes the checkpoint manager, which may block on a slow disk, until ctx is done.
+// If it is aborted, the pending init closes the db once it is opened, so that Init can be retried.
+func initCheckPointManager(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("init checkpoint manager: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- CheckPointManager.initContext(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		logger.Error(context.Background(), "CHECKPOINT_INIT_ALARM", "init checkpoint manager aborted", ctx.Err())
+		return fmt.Errorf("init checkpoint manager: %w", ctx.Err())
+	}
+}
+
+// timeoutStop wrappers LogstoreConfig.Stop with timeout (30s by default).
+// Draining by DrainTimeoutMs of the config counts in the timeout.
+// @return true if Stop returns before timeout, otherwise false, and the config is moved into
+// DisabledLogtailConfig until it stops, see disableSlowConfig.
 func timeoutStop(config *LogstoreConfig, removedFlag bool) bool {
+	return timeoutStopContext(context.Background(), config, removedFlag)
+}
+
+// timeoutStopContext works like timeoutStop, but returns false at once when ctx is done.
+func timeoutStopContext(ctx context.Context, config *LogstoreConfig, removedFlag bool) bool {
+	return timeoutStopWithOptions(ctx, config, removedFlag, defaultStopOptions())
+}
+
+// timeoutStopWithOptions works like timeoutStopContext, and stops the config with opts.
+// Waiting for collections by opts counts in the timeout.
+func timeoutStopWithOptions(ctx context.Context, config *LogstoreConfig, removedFlag bool, opts StopOptions) bool {
+	ctx, cancel := context.WithTimeout(ctx, configStopTimeout)
+	defer cancel()
+	return stopUntilDone(ctx, config, removedFlag, opts)
+}
+
+// stopUntilDone works like timeoutStopWithOptions, but waits until ctx is done instead of configStopTimeout.
+func stopUntilDone(ctx context.Context, config *LogstoreConfig, removedFlag bool, opts StopOptions) bool {
 	done := make(chan int)
 	go func() {
 		addressStr := fmt.Sprintf("%p", config)
 		logger.Info(config.Context.GetRuntimeContext(), "Stop config in goroutine", "begin", "LogstoreConfig", addressStr)
-		_ = config.Stop(removedFlag)
+		_ = config.StopWithOptions(removedFlag, opts)
 		close(done)
 		logger.Info(context.Background(), "Stop config in goroutine", "end", "LogstoreConfig", addressStr)
 		// The config is valid but stop slowly, allow it to load again.
+		// If a replacement is loaded but not started yet, it adopts the unsent data when it starts.
 		DisabledLogtailConfigLock.Lock()
-		if _, exists := DisabledLogtailConfig[config]; !exists {
+		config.stopFinished = true
+		if _, exists := DisabledLogtailConfig[config.generation]; !exists {
 			DisabledLogtailConfigLock.Unlock()
 			return
 		}
 		logger.Info(context.Background(), "Valid but slow stop config", config.ConfigName, "LogstoreConfig", addressStr)
 		DeleteLogstoreConfig(config, removedFlag)
-		delete(DisabledLogtailConfig, config)
+		delete(DisabledLogtailConfig, config.generation)
 
 		DisabledLogtailConfigLock.Unlock()
+		forgetDisabledConfig(config.ConfigNameWithSuffix)
+		notifyConfigState(config.ConfigNameWithSuffix, ConfigStateRecovered)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		// the config may stop right now
+		select {
+		case <-done:
+			return true
+		default:
+			return !disableSlowConfig(config, !opts.shutdown)
+		}
+	}
+}
+
+// disableSlowConfig moves the config which does not stop in time into DisabledLogtailConfig, so that the
+// goroutine of timeoutStop deletes it once it stops. Checking and disabling under DisabledLogtailConfigLock
+// ensures the config is either deleted by the goroutine or returned to the caller as stopped.
+// If quarantine is set, the config is persisted to be quarantined after restart, see persistDisabledConfig.
+// @return false if the config has stopped meanwhile, and it should be deleted by the caller.
+func disableSlowConfig(config *LogstoreConfig, quarantine bool) bool {
+	DisabledLogtailConfigLock.Lock()
+	if config.stopFinished {
+		DisabledLogtailConfigLock.Unlock()
+		return false
+	}
+	alarmStopTimeout(config)
+	// a config disabled for panics is already in DisabledLogtailConfig
+	_, exists := DisabledLogtailConfig[config.generation]
+	if !exists {
+		config.disabledTime = time.Now()
+		DisabledLogtailConfig[config.generation] = config
+	}
+	DisabledLogtailConfigLock.Unlock()
+	if !exists && quarantine {
+		// The checkpoint db is written without holding the lock. The record is removed if the config stops
+		// meanwhile, as the goroutine of timeoutStop may have missed it.
+		persistDisabledConfig(config, ConfigHealthReasonStopTimeout)
+		DisabledLogtailConfigLock.RLock()
+		stopFinished := config.stopFinished
+		DisabledLogtailConfigLock.RUnlock()
+		if stopFinished {
+			forgetDisabledConfig(config.ConfigNameWithSuffix)
+		}
+	}
+	return true
+}
+
+// alarmStopTimeout reports a config which does not stop in time,
+// with the number of its goroutines which have not exited yet.
+func alarmStopTimeout(config *LogstoreConfig) {
+	logger.Error(config.Context.GetRuntimeContext(), "CONFIG_STOP_TIMEOUT_ALARM",
+		"timeout when stop config, goroutine might leak",
+		"leaked goroutines", runningGoroutines(config.PluginRunner), "spawned goroutines", config.spawnedGoroutines.Load())
+}
+
+// LeakedGoroutines returns the number of plugin goroutines of disabled configs which have not exited,
+// an estimate of goroutines leaked by configs that timed out when stopping.
+func LeakedGoroutines() int64 {
+	var count int64
+	DisabledLogtailConfigLock.RLock()
+	for _, config := range DisabledLogtailConfig {
+		count += runningGoroutines(config.PluginRunner)
+	}
+	DisabledLogtailConfigLock.RUnlock()
+	return count
+}
+
+// timeoutStart wrappers LogstoreConfig.Start with timeout (30s by default).
+// If Start does not return in time, the config is aborted: it is stopped and deleted once Start returns,
+// and it should not be added to LogtailConfig.
+// @return true if Start returns before timeout, otherwise false.
+func timeoutStart(config *LogstoreConfig) bool {
+	const (
+		starting int32 = iota
+		started
+		aborted
+	)
+	var state atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		defer panicRecover("Run plugin")
+		config.Start()
+		close(done)
+		if !state.CompareAndSwap(starting, started) {
+			logger.Info(context.Background(), "Abort config in goroutine", config.ConfigNameWithSuffix)
+			_ = config.Stop(true)
+			DeleteLogstoreConfig(config, true)
+		}
 	}()
+	timer := time.NewTimer(time.Duration(*ConfigStartTimeoutMs) * time.Millisecond)
+	defer timer.Stop()
 	select {
 	case <-done:
 		return true
-	case <-time.After(30 * time.Second):
+	case <-timer.C:
+		// the config may start right now
+		if !state.CompareAndSwap(starting, aborted) {
+			return true
+		}
+		logger.Error(context.Background(), "CONFIG_START_TIMEOUT_ALARM", "timeout when start config, abort it",
+			config.ConfigNameWithSuffix, "timeout", time.Duration(*ConfigStartTimeoutMs)*time.Millisecond)
 		return false
 	}
 }
 
+// disableLogstoreConfig records the config which does not stop in time.
+// The config is removed from DisabledLogtailConfig once it finally stops, see timeoutStop.
+func disableLogstoreConfig(config *LogstoreConfig) {
+	DisabledLogtailConfigLock.Lock()
+	config.disabledTime = time.Now()
+	DisabledLogtailConfig[config.generation] = config
+	DisabledLogtailConfigLock.Unlock()
+}
+
+// DisabledConfig describes a config in DisabledLogtailConfig.
+type DisabledConfig struct {
+	ProjectName          string
+	LogstoreName         string
+	ConfigNameWithSuffix string
+	DisabledTime         time.Time
+}
+
+// GetDisabledConfigCount returns the number of configs which do not stop in time.
+// A growing number implies goroutine leaks.
+func GetDisabledConfigCount() int {
+	DisabledLogtailConfigLock.RLock()
+	defer DisabledLogtailConfigLock.RUnlock()
+	return len(DisabledLogtailConfig)
+}
+
+// GetDisabledConfigs returns configs which do not stop in time, ordered by the time they were disabled.
+func GetDisabledConfigs() []DisabledConfig {
+	DisabledLogtailConfigLock.RLock()
+	configs := make([]DisabledConfig, 0, len(DisabledLogtailConfig))
+	for _, config := range DisabledLogtailConfig {
+		configs = append(configs, DisabledConfig{
+			ProjectName:          config.ProjectName,
+			LogstoreName:         config.LogstoreName,
+			ConfigNameWithSuffix: config.ConfigNameWithSuffix,
+			DisabledTime:         config.disabledTime,
+		})
+	}
+	DisabledLogtailConfigLock.RUnlock()
+	sort.Slice(configs, func(i, j int) bool {
+		if configs[i].DisabledTime.Equal(configs[j].DisabledTime) {
+			return configs[i].ConfigNameWithSuffix < configs[j].ConfigNameWithSuffix
+		}
+		return configs[i].DisabledTime.Before(configs[j].DisabledTime)
+	})
+	return configs
+}
+
 // StopAllPipelines stops all pipelines so that it is ready
 // to quit.
 // For user-defined config, timeoutStop is used to avoid hanging.
+// The runtime contexts of the configs are canceled before any of them is stopped, so that
+// context-aware plugins abort in-flight work at once. Configs without input are stopped last,
+// so the root context is canceled then.
+// Plugins implementing pipeline.Closer are called once their configs stop, see ShutdownHookTimeoutMs.
 func StopAllPipelines(withInput bool) error {
+	return StopAllPipelinesContext(context.Background(), withInput)
+}
+
+// StopAllPipelinesContext works like StopAllPipelines, but stops waiting when ctx is done.
+// Configs not stopped by then are moved into DisabledLogtailConfig at once, and ctx.Err() is returned.
+func StopAllPipelinesContext(ctx context.Context, withInput bool) (err error) {
 	defer panicRecover("Run plugin")
+	if !withInput {
+		cancelRootContext()
+	}
+	stopWarmConfigs(withInput, true)
+	dropDeferredConfigs(withInput)
+	// if request is withinput=true, only stop configs with input, otherwise only stop configs without input.
+	toStop := make(map[string]*LogstoreConfig)
+	RangeConfigs(func(configName string, logstoreConfig *LogstoreConfig) bool {
+		if logstoreConfig.PluginRunner.IsWithInputPlugin() == withInput {
+			cancelRuntimeContext(logstoreConfig)
+			toStop[configName] = logstoreConfig
+		}
+		return true
+	})
+
+	// Stop configs in parallel without holding LogtailConfigLock, so that the whole procedure
+	// takes about the time of the slowest config rather than the sum of all.
+	var stoppedLock sync.Mutex
+	stopped := make(map[string]bool, len(toStop))
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, stopAllPipelinesConcurrency())
+	for configName, logstoreConfig := range toStop {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(configName string, logstoreConfig *LogstoreConfig) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			logger.Info(logstoreConfig.Context.GetRuntimeContext(), "Stop config", configName)
+			// Configs not stopped in time for shutdown are not quarantined after restart.
+			opts := defaultStopOptions()
+			opts.shutdown = true
+			hasStopped := timeoutStopWithOptions(ctx, logstoreConfig, true, opts)
+			stoppedLock.Lock()
+			stopped[configName] = hasStopped
+			stoppedLock.Unlock()
+		}(configName, logstoreConfig)
+	}
+	wg.Wait()
+
+	closing := make([]*LogstoreConfig, 0, len(stopped))
+	stateChanges := make(map[string]ConfigState)
 	LogtailConfigLock.Lock()
-	toDeleteConfigNames := make(map[string]struct{})
-	for configName, logstoreConfig := range LogtailConfig {
-		needStop := false
-		if withInput {
-			// if request is withinput=true, only stop logstoreConfig.PluginRunner.IsWithInputPlugin=true
-			if logstoreConfig.PluginRunner.IsWithInputPlugin() {
-				needStop = true
-			}
+	for configName, hasStopped := range stopped {
+		logstoreConfig := toStop[configName]
+		if hasStopped {
+			closing = append(closing, logstoreConfig)
+			stateChanges[configName] = ConfigStateStopped
 		} else {
-			// if request is withinput=false, only stop logstoreConfig.PluginRunner.IsWithInputPlugin=false
-			if !logstoreConfig.PluginRunner.IsWithInputPlugin() {
-				needStop = true
-			}
+			stateChanges[configName] = ConfigStateDisabled
 		}
-		if needStop {
-			logger.Info(logstoreConfig.Context.GetRuntimeContext(), "Stop config", configName)
-			if hasStopped := timeoutStop(logstoreConfig, true); !hasStopped {
-				// TODO: This alarm can not be sent to server in current alarm design.
-				logger.Error(logstoreConfig.Context.GetRuntimeContext(), "CONFIG_STOP_TIMEOUT_ALARM",
-					"timeout when stop config, goroutine might leak")
-				// TODO: The key should be versioned. Current implementation will overwrite the previous version when reload a block config multiple times.
-				DisabledLogtailConfigLock.Lock()
-				DisabledLogtailConfig[logstoreConfig] = struct{}{}
-				DisabledLogtailConfigLock.Unlock()
-			} else {
-				DeleteLogstoreConfig(logstoreConfig, true)
-			}
-			toDeleteConfigNames[configName] = struct{}{}
+		// The config may be loaded again while stopping, keep the new one.
+		if LogtailConfig[configName] == logstoreConfig {
+			delete(LogtailConfig, configName)
 		}
 	}
-	for key := range toDeleteConfigNames {
-		delete(LogtailConfig, key)
-	}
 	LogtailConfigLock.Unlock()
-	return nil
+	// Shutdown hooks are called before DeleteLogstoreConfig detaches plugins from the config.
+	closePlugins(ctx, closing, func(config *LogstoreConfig) {
+		DeleteLogstoreConfig(config, true)
+	})
+	for configName, state := range stateChanges {
+		notifyConfigState(configName, state)
+	}
+	if err = ctx.Err(); err != nil {
+		logger.Warning(context.Background(), "CONFIG_STOP_TIMEOUT_ALARM", "stop all pipelines aborted", err)
+	}
+	return err
 }
 
+func stopAllPipelinesConcurrency() int {
+	if *StopAllPipelinesConcurrency < 1 {
+		return 1
+	}
+	return *StopAllPipelinesConcurrency
+}
+
+// DeleteLogstoreConfig releases the stopped config. It does nothing if the config is already deleted,
+// which happens when the goroutine of timeoutStop and Stop race, even if they delete it at the same time.
 func DeleteLogstoreConfig(config *LogstoreConfig, removedFlag bool) {
+	if config == nil || !config.deleted.CompareAndSwap(false, true) {
+		return
+	}
+	if config.Context == nil || config.PluginRunner == nil {
+		return
+	}
+	if removedFlag && *FlushBufferOnRemove {
+		flushRemovedBuffer(config)
+	}
+	cancelRuntimeContext(config)
 	if actualObject, ok := config.Context.(*ContextImp); ok {
 		actualObject.logstoreC = nil
 	}
 	config.Context = nil
-	if runner, ok := config.PluginRunner.(*pluginv1Runner); ok {
-		for _, obj := range runner.MetricPlugins {
-			obj.Config = nil
-		}
-		for _, obj := range runner.ServicePlugins {
-			obj.Config = nil
-		}
-		for _, obj := range runner.ProcessorPlugins {
-			obj.Config = nil
-		}
-		for _, obj := range runner.AggregatorPlugins {
-			obj.Config = nil
-		}
-		for _, obj := range runner.FlusherPlugins {
-			obj.Config = nil
-		}
+	config.PluginRunner.ForEachPlugin(func(p Plugin) {
+		p.detachConfig()
+	})
+	switch runner := config.PluginRunner.(type) {
+	case *pluginv1Runner:
 		runner.LogstoreConfig = nil
-	} else if runner, ok := config.PluginRunner.(*pluginv2Runner); ok {
-		for _, obj := range runner.MetricPlugins {
-			obj.Config = nil
-		}
-		for _, obj := range runner.ServicePlugins {
-			obj.Config = nil
-		}
-		for _, obj := range runner.ProcessorPlugins {
-			obj.Config = nil
-		}
-		for _, obj := range runner.AggregatorPlugins {
-			obj.Config = nil
-		}
-		for _, obj := range runner.FlusherPlugins {
-			obj.Config = nil
-		}
+	case *pluginv2Runner:
 		runner.LogstoreConfig = nil
 	}
 	if !removedFlag {
-		LastUnsendBuffer[config.ConfigName] = config.PluginRunner
+		parkUnsendBuffer(unsendBufferKey(config.ConfigNameWithSuffix, config.generation), config.PluginRunner)
 	}
 	config.PluginRunner = nil
 }
 
+// closeUnstartedConfig releases the config built but never started. Its flushers and extensions are stopped,
+// as their Init may open connections or start goroutines, while inputs and processors only work after Start.
+// Unsent data the config has adopted from LastUnsendBuffer is parked again for the next version.
+func closeUnstartedConfig(config *LogstoreConfig) {
+	adopted := GetFlushStoreLen(config.PluginRunner) > 0
+	switch runner := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		runner.stopPlugins(pluginFlusher, false)
+		runner.stopPlugins(pluginExtension, false)
+	case *pluginv2Runner:
+		runner.stopPlugins(pluginFlusher, false)
+		runner.stopPlugins(pluginExtension, false)
+	}
+	DeleteLogstoreConfig(config, !adopted)
+}
+
+func unsendBufferKey(configName string, generation int64) string {
+	return configName + "#" + strconv.FormatInt(generation, 10)
+}
+
+// unsendBufferConfigName returns the config name of the key made by unsendBufferKey.
+func unsendBufferConfigName(key string) string {
+	if index := strings.LastIndexByte(key, '#'); index >= 0 {
+		return key[:index]
+	}
+	return key
+}
+
+// adoptUnsendBuffer moves unsent data of all previous versions of the config into its runner, to be sent
+// once it starts.
+func adoptUnsendBuffer(config *LogstoreConfig) {
+	for _, runner := range takeUnsendBuffer(config) {
+		config.PluginRunner.Merge(runner)
+	}
+}
+
+// adoptRecoveredUnsendBuffer adopts unsent data of previous versions which were disabled
+// when the config was loaded, and finally stopped before it starts.
+// A config reused from the warm pool is running already, and adopts the data on next load.
+func adoptRecoveredUnsendBuffer(config *LogstoreConfig) {
+	if !config.warmReused {
+		adoptUnsendBuffer(config)
+	}
+}
+
 func DeleteLogstoreConfigFromLogtailConfig(configName string, removedFlag bool) {
 	LogtailConfigLock.Lock()
 	if config, ok := LogtailConfig[configName]; ok {
@@ -244,77 +592,487 @@ func DeleteLogstoreConfigFromLogtailConfig(configName string, removedFlag bool)
 	LogtailConfigLock.Unlock()
 }
 
-// StopBuiltInModulesConfig stops built-in services (self monitor, alarm, container and checkpoint manager).
+// StopBuiltInModulesConfig stops built-in services (built-in configs, see RegisterBuiltinConfig, checkpoint manager and forced gc).
 func StopBuiltInModulesConfig() {
-	if AlarmConfig != nil {
-		if *flags.ForceSelfCollect {
-			logger.Info(context.Background(), "force collect the alarm metrics")
-			control := pipeline.NewAsyncControl()
-			AlarmConfig.PluginRunner.RunPlugins(pluginMetricInput, control)
-			control.WaitCancel()
+	stopBuiltinConfigs()
+	CheckPointManager.Stop()
+	stopForcedGC()
+}
+
+// Shutdown stops the plugin manager in order, so that alarms raised by user configs while stopping are
+// still reported through AlarmConfig:
+//  1. user configs, those with input first, see StopInOrder;
+//  2. their alarms are collected into AlarmConfig;
+//  3. built-in configs, so AlarmConfig flushes the alarms as it stops;
+//  4. the checkpoint manager and forced gc.
+//
+// User configs not stopped before ctx is done are moved into DisabledLogtailConfig, and ctx.Err() is returned
+// after built-in modules are stopped anyway.
+func Shutdown(ctx context.Context) error {
+	var alarms []*util.Alarm
+	RangeConfigs(func(_ string, config *LogstoreConfig) bool {
+		if alarm := configAlarm(config); alarm != nil {
+			alarms = append(alarms, alarm)
 		}
-		_ = AlarmConfig.Stop(true)
-		AlarmConfig = nil
+		return true
+	})
+	err := StopInOrder(ctx)
+	if AlarmConfig != nil {
+		addStoppedConfigAlarms(alarms)
+		_ = ForceCollectOnce(AlarmConfig)
 	}
-	if ContainerConfig != nil {
-		if *flags.ForceSelfCollect {
-			logger.Info(context.Background(), "force collect the container metrics")
-			control := pipeline.NewAsyncControl()
-			ContainerConfig.PluginRunner.RunPlugins(pluginMetricInput, control)
-			control.WaitCancel()
-		}
-		_ = ContainerConfig.Stop(true)
-		ContainerConfig = nil
+	StopBuiltInModulesConfig()
+	return err
+}
+
+// ForceCollectOnce runs the metric inputs of the config once more and waits for them, while the config
+// keeps running. Paused inputs are skipped.
+func ForceCollectOnce(config *LogstoreConfig) error {
+	if config == nil || config.PluginRunner == nil {
+		return fmt.Errorf("%w: config is not running", ErrConfigNotFound)
 	}
-	CheckPointManager.Stop()
+	control := pipeline.NewAsyncControl()
+	config.PluginRunner.RunPlugins(pluginMetricInput, control)
+	control.WaitCancel()
+	return nil
 }
 
-// Stop stop the given config. ConfigName is with suffix.
+// Stop stop the given config. ConfigName is with suffix, or without it if only one pipeline of the config
+// is running, see resolveConfigName.
+// If the config is not removed and StopGracePeriodMs is set, it keeps running for the period
+// and is reused if loaded again unchanged.
+// If the config is removed, unsent data of its previous versions in LastUnsendBuffer is dropped.
 func Stop(configName string, removedFlag bool) error {
+	return StopWithContext(context.Background(), configName, removedFlag)
+}
+
+// StopWithContext works like Stop, but the deadline of ctx, if any, replaces the timeout of 30s.
+// If the config does not stop before ctx is done, it is moved into DisabledLogtailConfig as on timeout,
+// and ctx.Err() is returned.
+func StopWithContext(ctx context.Context, configName string, removedFlag bool) (err error) {
 	defer panicRecover("Run plugin")
+	if configName, err = resolveConfigName(configName); err != nil {
+		return err
+	}
 	LogtailConfigLock.RLock()
 	if config, exists := LogtailConfig[configName]; exists {
 		LogtailConfigLock.RUnlock()
-		if hasStopped := timeoutStop(config, removedFlag); !hasStopped {
-			logger.Error(config.Context.GetRuntimeContext(), "CONFIG_STOP_TIMEOUT_ALARM",
-				"timeout when stop config, goroutine might leak")
-			DisabledLogtailConfigLock.Lock()
-			DisabledLogtailConfig[config] = struct{}{}
-			DisabledLogtailConfigLock.Unlock()
+		if !removedFlag && *StopGracePeriodMs > 0 {
 			LogtailConfigLock.Lock()
 			delete(LogtailConfig, configName)
 			LogtailConfigLock.Unlock()
+			parkWarmConfig(config)
+			return nil
+		}
+		var hasStopped bool
+		if _, ok := ctx.Deadline(); ok {
+			hasStopped = stopUntilDone(ctx, config, removedFlag, defaultStopOptions())
 		} else {
-			logger.Info(config.Context.GetRuntimeContext(), "Stop config now", configName)
-			LogtailConfigLock.Lock()
-			DeleteLogstoreConfig(config, removedFlag)
-			delete(LogtailConfig, configName)
-			LogtailConfigLock.Unlock()
+			hasStopped = timeoutStopContext(ctx, config, removedFlag)
+		}
+		if !hasStopped {
+			err = ctx.Err()
+		}
+		removeStoppedConfig(configName, config, removedFlag, hasStopped)
+		if removedFlag {
+			// Unsent data of previous versions of a removed config will never be adopted.
+			DiscardUnsentBuffer(configName)
+		}
+		return err
+	}
+	LogtailConfigLock.RUnlock()
+	if dropDeferredConfig(configName) {
+		if removedFlag {
+			DiscardUnsentBuffer(configName)
 		}
 		return nil
 	}
+	return fmt.Errorf("%w: %s", ErrConfigNotFound, configName)
+}
+
+// removeStoppedConfig removes the config from LogtailConfig after stopping it. If it has not stopped in time,
+// it is left in DisabledLogtailConfig, otherwise it is deleted.
+func removeStoppedConfig(configName string, config *LogstoreConfig, removedFlag bool, hasStopped bool) {
+	if !hasStopped {
+		LogtailConfigLock.Lock()
+		delete(LogtailConfig, configName)
+		LogtailConfigLock.Unlock()
+		notifyConfigState(configName, ConfigStateDisabled)
+		return
+	}
+	logger.Info(config.Context.GetRuntimeContext(), "Stop config now", configName)
+	LogtailConfigLock.Lock()
+	DeleteLogstoreConfig(config, removedFlag)
+	delete(LogtailConfig, configName)
+	LogtailConfigLock.Unlock()
+	notifyConfigState(configName, ConfigStateStopped)
+}
+
+// Reload replaces the running config with a new one built from newConfigJSON. ConfigName is with suffix.
+// The new config is built and checked as by Start before the old one is stopped, so the old one keeps running
+// if the build fails or the new config is refused, e.g. by ErrMemoryBudgetExceeded.
+// Data the old config has not sent is moved to the new config once it starts, even if their flushers differ.
+// The new config is started with the timeout of Start, the old one is removed and ErrStartTimeout is returned
+// if it does not start in time, and the data is kept in LastUnsendBuffer under the old versions.
+// The old config is replaced by the new one under LogtailConfigLock, and ErrConfigNotFound is returned if it is
+// stopped or replaced by another caller while reloading.
+// If only global settings which can be applied live are changed, they are applied to the running config
+// without stopping it, and the new config is released, see closeUnstartedConfig.
+func Reload(configName string, newConfigJSON string) error {
+	defer panicRecover("Run plugin")
+	begin := time.Now()
+	LogtailConfigLock.RLock()
+	oldConfig, exists := LogtailConfig[configName]
 	LogtailConfigLock.RUnlock()
-	return fmt.Errorf("config not found: %s", configName)
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrConfigNotFound, configName)
+	}
+	newConfig, err := buildLogstoreConfig(oldConfig.ProjectName, oldConfig.LogstoreName, configName, oldConfig.LogstoreKey, newConfigJSON)
+	if err != nil {
+		return err
+	}
+	if reloadGlobalConfig(oldConfig, newConfig) {
+		closeUnstartedConfig(newConfig)
+		return nil
+	}
+	if err = checkStartConfig(newConfig, oldConfig); err != nil {
+		closeUnstartedConfig(newConfig)
+		return err
+	}
+
+	// If the old config does not stop in time, its runner is parked in LastUnsendBuffer once it finally stops,
+	// and adopted by the next load.
+	hasStopped := timeoutStop(oldConfig, false)
+	started := timeoutStart(newConfig)
+
+	// The old config is swapped out only if it is still the running one, as it may be stopped or replaced
+	// by another caller meanwhile.
+	LogtailConfigLock.Lock()
+	swapped := LogtailConfig[configName] == oldConfig
+	if hasStopped {
+		// park the unsent data under the old version, to be handed over once the new config runs
+		DeleteLogstoreConfig(oldConfig, false)
+	}
+	if swapped {
+		if started {
+			LogtailConfig[configName] = newConfig
+			recordConfigStarted()
+		} else {
+			delete(LogtailConfig, configName)
+		}
+	}
+	LogtailConfigLock.Unlock()
+	if hasStopped {
+		notifyConfigState(configName, ConfigStateStopped)
+	} else {
+		notifyConfigState(configName, ConfigStateDisabled)
+	}
+	if !started {
+		return fmt.Errorf("%w: %s", ErrStartTimeout, configName)
+	}
+	if !swapped {
+		// Data of the old config is left in LastUnsendBuffer.
+		if timeoutStop(newConfig, false) {
+			DeleteLogstoreConfig(newConfig, false)
+		}
+		return fmt.Errorf("%w: %s is stopped or replaced while reloading", ErrConfigNotFound, configName)
+	}
+	handOverUnsendBuffer(newConfig)
+	recordStartLatency(newConfig, time.Since(begin))
+	notifyConfigStarted(configName, newConfig.PluginRunner.IsWithInputPlugin())
+	logger.Info(newConfig.Context.GetRuntimeContext(), "Reload config", "success")
+	return nil
+}
+
+// PauseInput stops the input at pluginIndex of the running config from producing data, while the rest of
+// the pipeline keeps draining. Inputs are indexed as in ConfigInfo, metric inputs before service inputs.
+// A paused service input is blocked when it adds data. ConfigName is with suffix.
+func PauseInput(configName string, pluginIndex int) error {
+	return setInputPaused(configName, pluginIndex, true)
+}
+
+// ResumeInput resumes the input paused by PauseInput. ConfigName is with suffix.
+func ResumeInput(configName string, pluginIndex int) error {
+	return setInputPaused(configName, pluginIndex, false)
+}
+
+func setInputPaused(configName string, pluginIndex int, paused bool) error {
+	LogtailConfigLock.RLock()
+	config, exists := LogtailConfig[configName]
+	LogtailConfigLock.RUnlock()
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrConfigNotFound, configName)
+	}
+	var pluginType string
+	var err error
+	switch r := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		pluginType, err = r.setInputPaused(pluginIndex, paused)
+	case *pluginv2Runner:
+		pluginType, err = r.setInputPaused(pluginIndex, paused)
+	default:
+		err = fmt.Errorf("unsupported plugin runner %T", r)
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info(config.Context.GetRuntimeContext(), "set input paused", paused, "index", pluginIndex, "plugin", pluginType)
+	return nil
+}
+
+// Pause stops all inputs of the running config from producing data, while processors, aggregators and
+// flushers keep draining. The config stays in LogtailConfig with its checkpoints and buffers.
+// ConfigName is with suffix.
+func Pause(configName string) error {
+	return setConfigPaused(configName, true)
+}
+
+// Resume resumes all inputs of the config paused by Pause, including those paused by PauseInput.
+// ConfigName is with suffix.
+func Resume(configName string) error {
+	return setConfigPaused(configName, false)
 }
 
-// Start starts the given config. ConfigName is with suffix.
+func setConfigPaused(configName string, paused bool) error {
+	LogtailConfigLock.RLock()
+	config, exists := LogtailConfig[configName]
+	LogtailConfigLock.RUnlock()
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrConfigNotFound, configName)
+	}
+	switch r := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		r.setInputsPaused(paused)
+	case *pluginv2Runner:
+		r.setInputsPaused(paused)
+	default:
+		return fmt.Errorf("unsupported plugin runner %T", r)
+	}
+	logger.Info(config.Context.GetRuntimeContext(), "set config paused", paused)
+	return nil
+}
+
+// ReorderProcessors rearranges processors of the running config, so that the processor at
+// newOrder[i] becomes the i-th one. ConfigName is with suffix.
+// Events being processed during the swap may still pass processors in the old order.
+func ReorderProcessors(configName string, newOrder []int) error {
+	LogtailConfigLock.RLock()
+	config, exists := LogtailConfig[configName]
+	LogtailConfigLock.RUnlock()
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrConfigNotFound, configName)
+	}
+	var err error
+	switch r := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		err = r.reorderProcessors(newOrder)
+	case *pluginv2Runner:
+		err = r.reorderProcessors(newOrder)
+	default:
+		err = fmt.Errorf("unsupported plugin runner %T", r)
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info(config.Context.GetRuntimeContext(), "reorder processors", newOrder)
+	return nil
+}
+
+// UpdatePluginDetail applies the detail to the plugin of the running config in place, without stopping the
+// config, so its buffers are kept. ConfigName is resolved as in Stop, and instanceID is the id of the plugin,
+// such as "1" of "flusher_sls/1". Plugins not implementing pipeline.Reconfigurable return ErrReconfigUnsupported.
+// The detail is not saved in the config, a reload applies the detail of the new config.
+func UpdatePluginDetail(configName, pluginType, instanceID string, detail map[string]interface{}) (err error) {
+	if configName, err = resolveConfigName(configName); err != nil {
+		return err
+	}
+	LogtailConfigLock.RLock()
+	config, exists := LogtailConfig[configName]
+	LogtailConfigLock.RUnlock()
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrConfigNotFound, configName)
+	}
+	var target Plugin
+	config.PluginRunner.ForEachPlugin(func(plugin Plugin) {
+		if target == nil && plugin.PluginType() == pluginType && plugin.PluginID() == instanceID {
+			target = plugin
+		}
+	})
+	if target == nil {
+		return fmt.Errorf("%w: %s/%s of config %s", ErrPluginNotFound, pluginType, instanceID, configName)
+	}
+	reconfigurable, ok := target.pluginInstance().(pipeline.Reconfigurable)
+	if !ok {
+		return fmt.Errorf("%w: %s/%s", ErrReconfigUnsupported, pluginType, instanceID)
+	}
+	if err = reconfigurable.Reconfigure(detail); err != nil {
+		return fmt.Errorf("reconfigure plugin %s/%s: %w", pluginType, instanceID, err)
+	}
+	logger.Info(config.Context.GetRuntimeContext(), "update plugin detail", pluginType+"/"+instanceID, "detail", detail)
+	return nil
+}
+
+// CloseIdleFlusherConnections closes idle connections to the endpoint of flushers in all configs.
+// Only flushers implementing pipeline.IdleConnectionCloser are considered.
+// It returns the number of flushers whose idle connections are closed, and the last error encountered.
+func CloseIdleFlusherConnections(endpoint string) (int, error) {
+	var lastErr error
+	count := 0
+	RangeConfigs(func(configName string, config *LogstoreConfig) bool {
+		for _, flusher := range GetConfigFlushers(config.PluginRunner) {
+			closer, ok := flusher.(pipeline.IdleConnectionCloser)
+			if !ok {
+				continue
+			}
+			closed, err := closer.CloseIdle(endpoint)
+			if err != nil {
+				logger.Warning(config.Context.GetRuntimeContext(), "FLUSHER_CLOSE_IDLE_ALARM", "close idle connections error", err,
+					"config", configName, "endpoint", endpoint)
+				lastErr = err
+				continue
+			}
+			if closed {
+				count++
+			}
+		}
+		return true
+	})
+	return count, lastErr
+}
+
+// FlusherMetric is the sum of counters of flushers of the same type in a config.
+type FlusherMetric struct {
+	ConfigName  string
+	FlusherType string
+	pipeline.FlusherCounters
+}
+
+// FlusherStats returns the delivery counters of flushers in all running configs, keyed by
+// config name + ":" + flusher type. Only flushers implementing pipeline.FlusherCounterReporter are reported.
+func FlusherStats() map[string]FlusherMetric {
+	stats := make(map[string]FlusherMetric)
+	RangeConfigs(func(configName string, config *LogstoreConfig) bool {
+		if config.PluginRunner == nil {
+			return true
+		}
+		config.PluginRunner.ForEachPlugin(func(p Plugin) {
+			if p.Category() != pluginFlusher {
+				return
+			}
+			reporter, ok := p.pluginInstance().(pipeline.FlusherCounterReporter)
+			if !ok {
+				return
+			}
+			counters := reporter.FlusherCounters()
+			key := configName + ":" + p.PluginType()
+			metric := stats[key]
+			metric.ConfigName = configName
+			metric.FlusherType = p.PluginType()
+			metric.Sent += counters.Sent
+			metric.Failed += counters.Failed
+			metric.Retried += counters.Retried
+			metric.Dropped += counters.Dropped
+			stats[key] = metric
+		})
+		return true
+	})
+	return stats
+}
+
+// checkDuplicateConfig returns ErrDuplicateConfig if a running config would be replaced by config, or has the
+// same name without suffix and runs in the same pipeline, i.e. both or neither have inputs.
+// The running config replaced on purpose, as by Reload, is skipped if it is not nil.
+func checkDuplicateConfig(config *LogstoreConfig, replaced *LogstoreConfig) error {
+	withInput := config.PluginRunner.IsWithInputPlugin()
+	var err error
+	RangeConfigs(func(configName string, running *LogstoreConfig) bool {
+		if running == replaced {
+			return true
+		}
+		if configName != config.ConfigNameWithSuffix &&
+			(running.ConfigName != config.ConfigName || running.PluginRunner == nil || running.PluginRunner.IsWithInputPlugin() != withInput) {
+			return true
+		}
+		logger.Error(config.Context.GetRuntimeContext(), "DUPLICATE_CONFIG_ALARM", "config not started, another config with the same name is running",
+			config.ConfigNameWithSuffix, "running config", configName, "project", running.ProjectName, "logstore", running.LogstoreName)
+		err = fmt.Errorf("%w: %s, running config %s of project %s logstore %s",
+			ErrDuplicateConfig, config.ConfigNameWithSuffix, configName, running.ProjectName, running.LogstoreName)
+		return false
+	})
+	return err
+}
+
+// Start starts the given config. ConfigName is with suffix, or without it if only one pipeline of the config
+// is loaded or running, see resolveConfigName.
+// It returns ErrAlreadyStarted if the config is running and no new instance of it is loaded,
+// and ErrDuplicateConfig if another config with the same name is running in the same pipeline.
+// The time from entry to registering the config is recorded, see StartLatencies.
+// A config whose DependsOn configs are not all running is deferred, and started once they are, see
+// DeferredConfigs. It returns ErrDependencyCycle if the config depends on itself through deferred configs.
+// A config quarantined after restart is refused with ErrConfigQuarantined, and deferred to be started once
+// the quarantine is over, see deferQuarantinedStart.
+// If the config fails to start and is dropped, the other pipeline of it keeps running by itself, or is released
+// with ReleaseSiblingOnStartFailure set, see releaseSiblingPipeline.
 func Start(configName string) error {
 	defer panicRecover("Run plugin")
+	begin := time.Now()
+	if err := takeSiblingStartFailure(configName); err != nil {
+		return err
+	}
+	configName, err := resolveConfigName(configName)
+	if err != nil {
+		return err
+	}
+	if err := checkQuarantine(configName); err != nil {
+		logger.Warning(context.Background(), "CONFIG_QUARANTINE_ALARM", "refuse to start config", err)
+		deferQuarantinedStart(configName)
+		return err
+	}
 	if ToStartPipelineConfigWithInput != nil && ToStartPipelineConfigWithInput.ConfigNameWithSuffix == configName {
-		ToStartPipelineConfigWithInput.Start()
-		LogtailConfigLock.Lock()
-		LogtailConfig[ToStartPipelineConfigWithInput.ConfigNameWithSuffix] = ToStartPipelineConfigWithInput
-		LogtailConfigLock.Unlock()
+		config := ToStartPipelineConfigWithInput
+		if deferred, err := deferStart(config); deferred || err != nil {
+			ToStartPipelineConfigWithInput = nil
+			if err != nil {
+				releaseSiblingPipeline(config, err)
+			}
+			return err
+		}
+		if err := startConfig(config, true, begin); err != nil {
+			if errors.Is(err, ErrStartTimeout) {
+				ToStartPipelineConfigWithInput = nil
+				releaseSiblingPipeline(config, err)
+			}
+			return err
+		}
 		ToStartPipelineConfigWithInput = nil
+		startDeferredConfigs()
 		return nil
 	} else if ToStartPipelineConfigWithoutInput != nil && ToStartPipelineConfigWithoutInput.ConfigNameWithSuffix == configName {
-		ToStartPipelineConfigWithoutInput.Start()
-		LogtailConfigLock.Lock()
-		LogtailConfig[ToStartPipelineConfigWithoutInput.ConfigNameWithSuffix] = ToStartPipelineConfigWithoutInput
-		LogtailConfigLock.Unlock()
+		config := ToStartPipelineConfigWithoutInput
+		if deferred, err := deferStart(config); deferred || err != nil {
+			ToStartPipelineConfigWithoutInput = nil
+			if err != nil {
+				releaseSiblingPipeline(config, err)
+			}
+			return err
+		}
+		if err := startConfig(config, false, begin); err != nil {
+			if errors.Is(err, ErrStartTimeout) {
+				ToStartPipelineConfigWithoutInput = nil
+				releaseSiblingPipeline(config, err)
+			}
+			return err
+		}
 		ToStartPipelineConfigWithoutInput = nil
+		startDeferredConfigs()
 		return nil
 	}
+	LogtailConfigLock.RLock()
+	_, running := LogtailConfig[configName]
+	LogtailConfigLock.RUnlock()
+	if running {
+		return fmt.Errorf("%w: %s", ErrAlreadyStarted, configName)
+	}
 	// should never happen
 	var loadedConfigName string
 	if ToStartPipelineConfigWithInput != nil {
@@ -323,27 +1081,32 @@ func Start(configName string) error {
 	if ToStartPipelineConfigWithoutInput != nil {
 		loadedConfigName += " " + ToStartPipelineConfigWithoutInput.ConfigNameWithSuffix
 	}
-	return fmt.Errorf("config unmatch with the loaded pipeline: given %s, expect %s", configName, loadedConfigName)
+	return fmt.Errorf("%w: given %s, expect %s", ErrConfigMismatch, configName, loadedConfigName)
 }
 
-func init() {
-	go func() {
-		for {
-			// force gc every 3 minutes
-			time.Sleep(time.Minute * 3)
-			logger.Debug(context.Background(), "force gc done", time.Now())
-			runtime.GC()
-			logger.Debug(context.Background(), "force gc done", time.Now())
-			debug.FreeOSMemory()
-			logger.Debug(context.Background(), "free os memory done", time.Now())
-			if logger.DebugFlag() {
-				gcStat := debug.GCStats{}
-				debug.ReadGCStats(&gcStat)
-				logger.Debug(context.Background(), "gc stats", gcStat)
-				memStat := runtime.MemStats{}
-				runtime.ReadMemStats(&memStat)
-				logger.Debug(context.Background(), "mem stats", memStat)
-			}
-		}
-	}()
+// checkStartConfig returns the error refusing to start the config, see checkDuplicateConfig and checkMemoryBudget.
+func checkStartConfig(config *LogstoreConfig, replaced *LogstoreConfig) error {
+	if err := checkDuplicateConfig(config, replaced); err != nil {
+		return err
+	}
+	return checkMemoryBudget(config)
+}
+
+// startConfig starts the loaded config and puts it into LogtailConfig.
+func startConfig(config *LogstoreConfig, withInput bool, begin time.Time) error {
+	if err := checkStartConfig(config, nil); err != nil {
+		return err
+	}
+	adoptRecoveredUnsendBuffer(config)
+	if !timeoutStart(config) {
+		return fmt.Errorf("%w: %s", ErrStartTimeout, config.ConfigNameWithSuffix)
+	}
+	LogtailConfigLock.Lock()
+	LogtailConfig[config.ConfigNameWithSuffix] = config
+	latency := time.Since(begin)
+	recordConfigStarted()
+	LogtailConfigLock.Unlock()
+	recordStartLatency(config, latency)
+	notifyConfigStarted(config.ConfigNameWithSuffix, withInput)
+	return nil
 }
@@ -19,12 +19,27 @@ package pluginmanager
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/alibaba/ilogtail/pkg"
+	"github.com/alibaba/ilogtail/pkg/flags"
 	"github.com/alibaba/ilogtail/pkg/logger"
 	_ "github.com/alibaba/ilogtail/pkg/logger/test"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/pkg/selfmonitor"
 
 	// dependency packages
 	_ "github.com/alibaba/ilogtail/plugins/aggregator"
@@ -39,6 +54,7 @@ import (
 	_ "github.com/alibaba/ilogtail/plugins/processor/regex"
 
 	"github.com/stretchr/testify/suite"
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 func TestPluginManager(t *testing.T) {
@@ -51,7 +67,7 @@ type managerTestSuite struct {
 
 func (s *managerTestSuite) BeforeTest(suiteName, testName string) {
 	logger.Infof(context.Background(), "========== %s %s test start ========================", suiteName, testName)
-	s.NoError(Init(), "got error when init")
+	s.NoError(Init(context.Background(), false), "got error when init")
 }
 
 func (s *managerTestSuite) AfterTest(suiteName, testName string) {
@@ -87,6 +103,1537 @@ func (s *managerTestSuite) TestPluginManager() {
 	}
 }
 
+func (s *managerTestSuite) TestReload() {
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	LogtailConfigLock.RLock()
+	oldConfig := LogtailConfig["test_config"]
+	LogtailConfigLock.RUnlock()
+
+	parkedKey := unsendBufferKey("test_config", 0)
+	LastUnsendBufferLock.Lock()
+	LastUnsendBuffer[parkedKey] = &pluginv1Runner{FlushOutStore: NewFlushOutStore[protocol.LogGroup]()}
+	LastUnsendBufferLock.Unlock()
+
+	// invalid config keeps the old one running and the parked buffer untouched
+	s.Error(Reload("test_config", `{"flushers": [{"type": "not_exist_flusher"}]}`))
+	LogtailConfigLock.RLock()
+	s.Equal(oldConfig, LogtailConfig["test_config"])
+	LogtailConfigLock.RUnlock()
+	LastUnsendBufferLock.Lock()
+	s.Contains(LastUnsendBuffer, parkedKey)
+	LastUnsendBufferLock.Unlock()
+	s.Error(Reload("not_exist_config", `{}`))
+
+	s.NoError(Reload("test_config", GetTestConfig(noblockUpdateConfigName)))
+	LogtailConfigLock.RLock()
+	newConfig := LogtailConfig["test_config"]
+	LogtailConfigLock.RUnlock()
+	s.NotEqual(oldConfig, newConfig)
+	s.Nil(oldConfig.PluginRunner)
+	s.Equal(1, len(GetConfigFlushers(newConfig.PluginRunner)))
+	LastUnsendBufferLock.Lock()
+	s.NotContains(LastUnsendBuffer, parkedKey)
+	LastUnsendBufferLock.Unlock()
+	time.Sleep(time.Millisecond * time.Duration(100))
+	s.NoError(Stop("test_config", true))
+}
+
+func (s *managerTestSuite) TestStopGracePeriod() {
+	*StopGracePeriodMs = 500
+	defer func() { *StopGracePeriodMs = 0 }()
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	LogtailConfigLock.RLock()
+	oldConfig := LogtailConfig["test_config"]
+	LogtailConfigLock.RUnlock()
+
+	// config loaded again unchanged within the grace period reuses the running one
+	s.NoError(Stop("test_config", false))
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "test_config")
+	LogtailConfigLock.RUnlock()
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	LogtailConfigLock.RLock()
+	s.Equal(oldConfig, LogtailConfig["test_config"])
+	LogtailConfigLock.RUnlock()
+	s.NotNil(oldConfig.PluginRunner)
+
+	// config is parked in LastUnsendBuffer after the grace period
+	s.NoError(Stop("test_config", false))
+	time.Sleep(time.Millisecond * time.Duration(1000))
+	s.Nil(oldConfig.PluginRunner)
+	LastUnsendBufferLock.Lock()
+	s.Contains(LastUnsendBuffer, unsendBufferKey("test_config", oldConfig.generation))
+	LastUnsendBufferLock.Unlock()
+
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	LogtailConfigLock.RLock()
+	s.NotEqual(oldConfig, LogtailConfig["test_config"])
+	LogtailConfigLock.RUnlock()
+	time.Sleep(time.Millisecond * time.Duration(100))
+	s.NoError(Stop("test_config", true))
+}
+
+func (s *managerTestSuite) TestRecordCounters() {
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(4000))
+	LogtailConfigLock.RLock()
+	config := LogtailConfig["test_config"]
+	LogtailConfigLock.RUnlock()
+	counters := config.RecordCounters()
+	s.True(counters.InputRecords > 0)
+	s.Equal(counters.InputRecords, counters.ProcessorOutRecords+counters.ProcessorDroppedRecords)
+	s.True(counters.FlushedRecords > 0)
+	s.Equal(int64(0), counters.FlusherDroppedRecords)
+
+	pipelineLabel := `"` + selfmonitor.MetricLabelKeyMetricCategory + `":"` + selfmonitor.MetricLabelValueMetricCategoryPipeline + `"`
+	found := false
+	for _, record := range config.Context.ExportMetricRecords() {
+		if strings.Contains(record[selfmonitor.MetricLabelPrefix], pipelineLabel) {
+			found = strings.Contains(record[selfmonitor.MetricCounterPrefix], selfmonitor.MetricPipelineFlushedRecordsTotal)
+		}
+	}
+	s.True(found)
+	s.NoError(Stop("test_config", true))
+	s.Equal(RecordCounters{}, config.RecordCounters())
+}
+
+func (s *managerTestSuite) TestStartLatency() {
+	before := StartLatencies()
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "start_latency_config"), "got err when logad config")
+	after := StartLatencies()
+	s.Equal(before.Count+1, after.Count)
+	latency, ok := after.Latest["start_latency_config"]
+	s.True(ok)
+	s.Greater(latency, time.Duration(0))
+	s.GreaterOrEqual(after.Max, latency)
+	var bucketCount int64
+	for _, bucket := range after.Buckets {
+		bucketCount += bucket.Count
+	}
+	s.Equal(after.Count, bucketCount)
+	s.Equal(time.Duration(0), after.Buckets[len(after.Buckets)-1].UpperBound)
+
+	LogtailConfigLock.RLock()
+	config := LogtailConfig["start_latency_config"]
+	LogtailConfigLock.RUnlock()
+	found := false
+	for _, record := range config.Context.ExportMetricRecords() {
+		if strings.Contains(record[selfmonitor.MetricGaugePrefix], selfmonitor.MetricPipelineStartLatencyMs) {
+			found = true
+		}
+	}
+	s.True(found)
+	s.Eventually(func() bool {
+		return config.RecordCounters().InputRecords > 0
+	}, time.Second*5, time.Millisecond*10)
+	s.NoError(Stop("start_latency_config", true))
+}
+
+func (s *managerTestSuite) TestDisabledConfigs() {
+	first := &LogstoreConfig{ProjectName: "test_prj", LogstoreName: "test_logstore", ConfigNameWithSuffix: "disabled_1/1", generation: -1}
+	second := &LogstoreConfig{ProjectName: "test_prj", LogstoreName: "test_logstore", ConfigNameWithSuffix: "disabled_2/1", generation: -2}
+	disableLogstoreConfig(first)
+	disableLogstoreConfig(second)
+	defer func() {
+		DisabledLogtailConfigLock.Lock()
+		delete(DisabledLogtailConfig, first.generation)
+		delete(DisabledLogtailConfig, second.generation)
+		DisabledLogtailConfigLock.Unlock()
+	}()
+
+	// Configs hanging in other tests may be disabled too, only check the ones disabled here.
+	s.GreaterOrEqual(GetDisabledConfigCount(), 2)
+	configs := make([]DisabledConfig, 0)
+	for _, config := range GetDisabledConfigs() {
+		if strings.HasPrefix(config.ConfigNameWithSuffix, "disabled_") {
+			configs = append(configs, config)
+		}
+	}
+	s.Equal(2, len(configs))
+	s.Equal("disabled_1/1", configs[0].ConfigNameWithSuffix)
+	s.Equal("disabled_2/1", configs[1].ConfigNameWithSuffix)
+	s.False(configs[0].DisabledTime.After(configs[1].DisabledTime))
+
+	metrics := make([]map[string]string, 0)
+	for _, metric := range GetDisabledConfigMetrics() {
+		if strings.Contains(metric[selfmonitor.MetricLabelPrefix], "disabled_") {
+			metrics = append(metrics, metric)
+		}
+	}
+	s.Equal(2, len(metrics))
+	s.Contains(metrics[0][selfmonitor.MetricLabelPrefix], "disabled_1/1")
+	s.Contains(metrics[0][selfmonitor.MetricGaugePrefix], selfmonitor.MetricPipelineDisabledSeconds)
+}
+
+func (s *managerTestSuite) TestListRunningConfigs() {
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	hung := &LogstoreConfig{ConfigNameWithSuffix: "hung_config/1", generation: -1}
+	disableLogstoreConfig(hung)
+	defer func() {
+		DisabledLogtailConfigLock.Lock()
+		delete(DisabledLogtailConfig, hung.generation)
+		DisabledLogtailConfigLock.Unlock()
+	}()
+
+	infos := make([]ConfigInfo, 0)
+	for _, info := range ListRunningConfigs() {
+		if info.ConfigNameWithSuffix == "hung_config/1" || info.ConfigNameWithSuffix == "test_config" {
+			infos = append(infos, info)
+		}
+	}
+	s.Equal(2, len(infos))
+	s.Equal("hung_config/1", infos[0].ConfigNameWithSuffix)
+	s.True(infos[0].Disabled)
+	info := infos[1]
+	s.Equal("test_config", info.ConfigNameWithSuffix)
+	s.False(info.Disabled)
+	s.True(info.IsWithInputPlugin)
+	s.Equal([]string{"service_mock"}, info.ServicePlugins)
+	s.Empty(info.MetricPlugins)
+	s.Equal([]string{"processor_regex"}, info.ProcessorPlugins)
+	s.Equal([]string{"aggregator_default"}, info.AggregatorPlugins)
+	s.Equal([]string{"flusher_statistics", "flusher_checker"}, info.FlusherPlugins)
+	s.NoError(Stop("test_config", true))
+}
+
+func (s *managerTestSuite) TestGetConfigState() {
+	_, found := GetConfigState("test_config")
+	s.False(found)
+
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	status, found := GetConfigState("test_config")
+	s.True(found)
+	s.Equal(ConfigStateRunning, status.State)
+	s.True(status.Info.IsWithInputPlugin)
+	s.Equal(1, len(status.Info.ServicePlugins))
+	s.Equal(2, len(status.Info.FlusherPlugins))
+	s.NoError(Stop("test_config", true))
+
+	ToStartPipelineConfigWithoutInput = &LogstoreConfig{ConfigNameWithSuffix: "pending_config/2"}
+	status, found = GetConfigState("pending_config/2")
+	ToStartPipelineConfigWithoutInput = nil
+	s.True(found)
+	s.Equal(ConfigStatePendingStart, status.State)
+	s.Equal("pending_start", status.State.String())
+
+	hung := &LogstoreConfig{ConfigNameWithSuffix: "hung_config/1", generation: -1}
+	disableLogstoreConfig(hung)
+	defer func() {
+		DisabledLogtailConfigLock.Lock()
+		delete(DisabledLogtailConfig, hung.generation)
+		DisabledLogtailConfigLock.Unlock()
+	}()
+	status, found = GetConfigState("hung_config/1")
+	s.True(found)
+	s.Equal(ConfigStateDisabled, status.State)
+	s.True(status.Info.Disabled)
+	s.False(status.HasUnsentBuffer)
+
+	LastUnsendBufferLock.Lock()
+	LastUnsendBuffer[unsendBufferKey("unsent_config/1", -1)] = &pluginv1Runner{}
+	LastUnsendBufferLock.Unlock()
+	defer func() {
+		LastUnsendBufferLock.Lock()
+		delete(LastUnsendBuffer, unsendBufferKey("unsent_config/1", -1))
+		LastUnsendBufferLock.Unlock()
+	}()
+	status, found = GetConfigState("unsent_config/1")
+	s.True(found)
+	s.Equal(ConfigStateUnknown, status.State)
+	s.True(status.HasUnsentBuffer)
+}
+
+func (s *managerTestSuite) TestConfigHealth() {
+	_, err := ConfigHealth("test_config")
+	s.Error(err)
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	report, err := ConfigHealth("test_config")
+	s.NoError(err)
+	s.True(report.Healthy)
+	s.Empty(report.Reasons)
+
+	originalStalledInputSeconds := *StalledInputSeconds
+	*StalledInputSeconds = 0
+	defer func() {
+		*StalledInputSeconds = originalStalledInputSeconds
+	}()
+	recordPanic("test_config", "health test panic")
+	hung := &LogstoreConfig{ConfigNameWithSuffix: "test_config", generation: -1}
+	disableLogstoreConfig(hung)
+	defer func() {
+		DisabledLogtailConfigLock.Lock()
+		delete(DisabledLogtailConfig, hung.generation)
+		DisabledLogtailConfigLock.Unlock()
+	}()
+	report, err = ConfigHealth("test_config")
+	s.NoError(err)
+	s.False(report.Healthy)
+	reasons := make(map[string]map[string]string)
+	for _, reason := range report.Reasons {
+		reasons[reason.Reason] = reason.Values
+	}
+	s.Equal("-1", reasons[ConfigHealthReasonStopTimeout]["generation"])
+	s.Contains(reasons, ConfigHealthReasonInputStalled)
+	s.Equal("health test panic", reasons[ConfigHealthReasonRecentPanic]["last_message"])
+	s.NoError(Stop("test_config", true))
+}
+
+func (s *managerTestSuite) TestConfigErrors() {
+	err := Stop("not_exist_config", true)
+	s.ErrorIs(err, ErrConfigNotFound)
+	s.Equal("config not found: not_exist_config", err.Error())
+	s.ErrorIs(Reload("not_exist_config", `{}`), ErrConfigNotFound)
+	s.ErrorIs(UnloadPartiallyLoadedConfig("not_exist_config"), ErrConfigNotFound)
+
+	err = Start("not_exist_config")
+	s.ErrorIs(err, ErrConfigMismatch)
+	s.True(strings.HasPrefix(err.Error(), "config unmatch with the loaded pipeline: given not_exist_config"))
+}
+
+func (s *managerTestSuite) TestHealthSnapshot() {
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	report := HealthSnapshot()
+	s.Equal(1, report.RunningConfigs)
+
+	hung := &LogstoreConfig{ConfigNameWithSuffix: "test_config", generation: -1}
+	disableLogstoreConfig(hung)
+	defer func() {
+		DisabledLogtailConfigLock.Lock()
+		delete(DisabledLogtailConfig, hung.generation)
+		DisabledLogtailConfigLock.Unlock()
+	}()
+	time.Sleep(time.Millisecond * time.Duration(10))
+	report = HealthSnapshot()
+	// ignore configs disabled by other tests
+	var stuck *StuckConfig
+	for i := range report.StuckConfigs {
+		if report.StuckConfigs[i].Generation == hung.generation {
+			stuck = &report.StuckConfigs[i]
+		}
+	}
+	s.Equal(len(report.StuckConfigs), report.DisabledConfigs)
+	s.NotNil(stuck)
+	s.Equal("test_config", stuck.ConfigNameWithSuffix)
+	s.True(stuck.StuckFor >= 10*time.Millisecond)
+	s.Equal(CheckPointManager.IsRunning(), report.CheckPointRunning)
+	s.NoError(Stop("test_config", true))
+}
+
+func (s *managerTestSuite) TestConfigStateListener() {
+	states := make(chan ConfigState, 10)
+	RegisterConfigStateListener(func(configName string, state ConfigState) {
+		if configName == "state_config" {
+			states <- state
+		}
+	})
+	RegisterConfigStateListener(func(configName string, state ConfigState) {
+		panic("listener panicked")
+	})
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "state_config"), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	s.NoError(Stop("state_config", true))
+	s.Equal(1, len(states))
+	s.Equal(ConfigStateStopped, <-states)
+
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "state_config"), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	s.NoError(StopAllPipelines(true))
+	s.Equal(1, len(states))
+	s.Equal("stopped", (<-states).String())
+}
+
+func (s *managerTestSuite) TestStopAllPipelinesCancelContext() {
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	LogtailConfigLock.RLock()
+	ctx := LogtailConfig["test_config"].Context.GetRuntimeContext()
+	LogtailConfigLock.RUnlock()
+	s.NotNil(ctx.Value(pkg.LogTailMeta))
+	s.NoError(ctx.Err())
+
+	s.NoError(StopAllPipelines(true))
+	s.ErrorIs(ctx.Err(), context.Canceled)
+
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	LogtailConfigLock.RLock()
+	ctx = LogtailConfig["test_config"].Context.GetRuntimeContext()
+	LogtailConfigLock.RUnlock()
+	cancelRootContext()
+	s.ErrorIs(ctx.Err(), context.Canceled)
+	s.NoError(StopAllPipelines(true))
+}
+
+func (s *managerTestSuite) TestStopAllPipelinesKeepBuiltinContext() {
+	s.Require().NotNil(AlarmConfig)
+	ctx := AlarmConfig.Context.GetRuntimeContext()
+	s.NoError(StopAllPipelines(true))
+	s.NoError(StopAllPipelines(false))
+	// built-in configs keep running to report alarms until StopBuiltInModulesConfig
+	s.NoError(ctx.Err())
+	s.NoError(ForceCollectOnce(AlarmConfig))
+}
+
+// hangFlusher hangs in Stop until hangFlusherRelease is closed.
+type hangFlusher struct{}
+
+var hangFlusherRelease chan struct{}
+
+func (f *hangFlusher) Init(ctx pipeline.Context) error {
+	return nil
+}
+
+func (f *hangFlusher) Description() string {
+	return "flusher which hangs when stop"
+}
+
+func (f *hangFlusher) SetUrgent(flag bool) {
+}
+
+func (f *hangFlusher) IsReady(projectName string, logstoreName string, logstoreKey int64) bool {
+	return true
+}
+
+func (f *hangFlusher) Flush(projectName string, logstoreName string, configName string, logGroupList []*protocol.LogGroup) error {
+	return nil
+}
+
+func (f *hangFlusher) Stop() error {
+	<-hangFlusherRelease
+	return nil
+}
+
+func init() {
+	pipeline.Flushers["flusher_hang_test"] = func() pipeline.Flusher {
+		return &hangFlusher{}
+	}
+}
+
+func (s *managerTestSuite) TestReloadGlobalConfig() {
+	reloadConfig := `{"global": {"DrainTimeoutMs": %d, "DefaultLogQueueSize": %d}, "inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 100, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "reload_global_config", fmt.Sprintf(reloadConfig, 0, 100)), "got err when logad config")
+	LogtailConfigLock.RLock()
+	oldConfig := LogtailConfig["reload_global_config"]
+	LogtailConfigLock.RUnlock()
+	s.Eventually(func() bool {
+		return oldConfig.RecordCounters().InputRecords > 0
+	}, time.Second*5, time.Millisecond*10)
+
+	// a live setting is applied to the running config
+	s.NoError(Reload("reload_global_config", fmt.Sprintf(reloadConfig, 1000, 100)))
+	LogtailConfigLock.RLock()
+	s.Same(oldConfig, LogtailConfig["reload_global_config"])
+	LogtailConfigLock.RUnlock()
+	s.NotNil(oldConfig.PluginRunner)
+	s.Equal(1000, oldConfig.GlobalConfig.DrainTimeoutMs)
+
+	// the queue size needs to rebuild the config
+	s.NoError(Reload("reload_global_config", fmt.Sprintf(reloadConfig, 1000, 200)))
+	LogtailConfigLock.RLock()
+	newConfig := LogtailConfig["reload_global_config"]
+	LogtailConfigLock.RUnlock()
+	s.NotSame(oldConfig, newConfig)
+	s.Nil(oldConfig.PluginRunner)
+	s.Equal(200, cap(newConfig.PluginRunner.(*pluginv1Runner).LogsChan))
+	s.Eventually(func() bool {
+		return newConfig.RecordCounters().InputRecords > 0
+	}, time.Second*5, time.Millisecond*10)
+	s.NoError(Stop("reload_global_config", true))
+}
+
+func (s *managerTestSuite) TestReloadReleasesUnusedConfig() {
+	reloadConfig := `{"global": {"DrainTimeoutMs": %d}, "flushers": [{"type": "flusher_stopped_record_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "reload_unused_config", fmt.Sprintf(reloadConfig, 0)))
+	// the config built for a live reload is not used, and its flushers are stopped
+	stopped := stoppedRecordFlushers.Load()
+	s.NoError(Reload("reload_unused_config", fmt.Sprintf(reloadConfig, 1000)))
+	s.Equal(stopped+1, stoppedRecordFlushers.Load())
+	s.NoError(Stop("reload_unused_config", true))
+}
+
+func (s *managerTestSuite) TestLoadFailureStopsLoadedFlushers() {
+	// the flusher loaded before the unknown one is stopped
+	stopped := stoppedRecordFlushers.Load()
+	s.Error(LoadLogstoreConfig("test_prj", "test_logstore", "load_failure_config", 0,
+		`{"flushers": [{"type": "flusher_stopped_record_test"}, {"type": "flusher_unknown_test"}]}`))
+	s.Equal(stopped+1, stoppedRecordFlushers.Load())
+	s.Nil(ToStartPipelineConfigWithoutInput)
+}
+
+func (s *managerTestSuite) TestReloadStoppedMeanwhile() {
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "reload_stopped_config", `{"flushers": [{"type": "flusher_slow_stop_test"}]}`))
+	LogtailConfigLock.RLock()
+	oldConfig := LogtailConfig["reload_stopped_config"]
+	LogtailConfigLock.RUnlock()
+	reloaded := make(chan error, 1)
+	go func() {
+		reloaded <- Reload("reload_stopped_config", `{"flushers": [{"type": "flusher_checker"}]}`)
+	}()
+	// the old config is removed by another caller while it is stopped by Reload
+	s.Eventually(func() bool {
+		return oldConfig.FlushOutFlag.Load()
+	}, time.Second*5, time.Millisecond*10)
+	LogtailConfigLock.Lock()
+	delete(LogtailConfig, "reload_stopped_config")
+	LogtailConfigLock.Unlock()
+
+	s.ErrorIs(<-reloaded, ErrConfigNotFound)
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "reload_stopped_config")
+	LogtailConfigLock.RUnlock()
+	s.Nil(oldConfig.PluginRunner)
+	// unsent data of the old config is kept under its generation
+	LastUnsendBufferLock.Lock()
+	s.Contains(LastUnsendBuffer, unsendBufferKey("reload_stopped_config", oldConfig.generation))
+	LastUnsendBufferLock.Unlock()
+	DiscardUnsentBuffer("reload_stopped_config")
+}
+
+func (s *managerTestSuite) TestReloadHandsOverUnsentData() {
+	checkerConfig := `{"flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "reload_unsent_config", checkerConfig))
+	parked := &pluginv1Runner{FlushOutStore: NewFlushOutStore[protocol.LogGroup]()}
+	parked.FlushOutStore.Add(&protocol.LogGroup{Logs: []*protocol.Log{{Time: 1, Contents: []*protocol.Log_Content{{Key: "content", Value: "unsent"}}}}})
+	parkUnsendBuffer(unsendBufferKey("reload_unsent_config", -1), parked)
+
+	// the unsent data is sent by the new config once it starts
+	s.NoError(Reload("reload_unsent_config", checkerConfig))
+	LogtailConfigLock.RLock()
+	newConfig := LogtailConfig["reload_unsent_config"]
+	LogtailConfigLock.RUnlock()
+	checkFlusher, ok := GetConfigFlushers(newConfig.PluginRunner)[0].(*checker.FlusherChecker)
+	s.Require().True(ok)
+	s.Eventually(func() bool {
+		return checkFlusher.GetLogCount() == 1
+	}, time.Second*5, time.Millisecond*10)
+	s.NoError(checkFlusher.CheckKeyValue("content", "unsent"))
+	s.NoError(Stop("reload_unsent_config", true))
+}
+
+func (s *managerTestSuite) TestInputRateLimit() {
+	limitConfig := `{"global": {"MaxEventsPerSecond": 10, "LimitStrategy": "drop"}, "inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 1000, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "rate_limit_config", limitConfig), "got err when logad config")
+	LogtailConfigLock.RLock()
+	config := LogtailConfig["rate_limit_config"]
+	LogtailConfigLock.RUnlock()
+	s.Eventually(func() bool {
+		return config.RecordCounters().RateLimitDroppedRecords > 0
+	}, time.Second*5, time.Millisecond*10)
+	// records within the limit still pass
+	counters := config.RecordCounters()
+	s.Greater(counters.ProcessorOutRecords, int64(0))
+	s.Less(counters.ProcessorOutRecords, counters.InputRecords)
+	s.NoError(Stop("rate_limit_config", true))
+}
+
+func (s *managerTestSuite) TestReloadHangingConfig() {
+	hangFlusherRelease = make(chan struct{})
+	originalTimeout := configStopTimeout
+	configStopTimeout = time.Millisecond * time.Duration(500)
+	defer func() {
+		configStopTimeout = originalTimeout
+	}()
+	hangConfig := `{"flushers": [{"type": "flusher_hang_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "hang_config", hangConfig), "got err when logad config")
+
+	generations := make([]int64, 0)
+	for i := 0; i < 3; i++ {
+		LogtailConfigLock.RLock()
+		generations = append(generations, LogtailConfig["hang_config"].Generation())
+		LogtailConfigLock.RUnlock()
+		s.NoError(Reload("hang_config", hangConfig))
+	}
+	DisabledLogtailConfigLock.RLock()
+	for _, generation := range generations {
+		s.Contains(DisabledLogtailConfig, generation)
+	}
+	DisabledLogtailConfigLock.RUnlock()
+
+	close(hangFlusherRelease)
+	s.NoError(Stop("hang_config", true))
+	s.Eventually(func() bool {
+		DisabledLogtailConfigLock.RLock()
+		defer DisabledLogtailConfigLock.RUnlock()
+		for _, generation := range generations {
+			if _, exists := DisabledLogtailConfig[generation]; exists {
+				return false
+			}
+		}
+		return true
+	}, time.Second*5, time.Millisecond*100)
+	LastUnsendBufferLock.Lock()
+	for _, generation := range generations {
+		delete(LastUnsendBuffer, unsendBufferKey("hang_config", generation))
+	}
+	LastUnsendBufferLock.Unlock()
+}
+
+func (s *managerTestSuite) TestDisableSlowConfig() {
+	defer ClearQuarantine("slow_config")
+	stopped, err := createLogstoreConfig("test_prj", "test_logstore", "slow_config", 0, `{"flushers": [{"type": "flusher_checker"}]}`)
+	s.Require().NoError(err)
+	defer cancelRuntimeContext(stopped)
+	// a config which stops right after the timeout is left to the caller
+	stopped.stopFinished = true
+	s.False(disableSlowConfig(stopped, true))
+	DisabledLogtailConfigLock.RLock()
+	s.NotContains(DisabledLogtailConfig, stopped.Generation())
+	DisabledLogtailConfigLock.RUnlock()
+
+	slow, err := createLogstoreConfig("test_prj", "test_logstore", "slow_config", 0, `{"flushers": [{"type": "flusher_checker"}]}`)
+	s.Require().NoError(err)
+	defer cancelRuntimeContext(slow)
+	s.True(disableSlowConfig(slow, true))
+	DisabledLogtailConfigLock.Lock()
+	s.Contains(DisabledLogtailConfig, slow.Generation())
+	delete(DisabledLogtailConfig, slow.Generation())
+	DisabledLogtailConfigLock.Unlock()
+}
+
+// unresponsiveService ignores Stop and blocks until it is closed, like an input blocked on a connection.
+type unresponsiveService struct {
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (i *unresponsiveService) Init(pipeline.Context) (int, error) {
+	i.closed = make(chan struct{})
+	return 0, nil
+}
+
+func (i *unresponsiveService) Description() string {
+	return "service input ignoring stop for test"
+}
+
+func (i *unresponsiveService) Start(pipeline.Collector) error {
+	<-i.closed
+	return nil
+}
+
+func (i *unresponsiveService) Stop() error {
+	<-i.closed
+	return nil
+}
+
+func (i *unresponsiveService) Close() error {
+	i.closeOnce.Do(func() {
+		close(i.closed)
+	})
+	return nil
+}
+
+func init() {
+	pipeline.ServiceInputs["service_unresponsive_test"] = func() pipeline.ServiceInput {
+		return &unresponsiveService{}
+	}
+}
+
+func (s *managerTestSuite) TestForceStop() {
+	defer ClearQuarantine("force_stop_config")
+	originalTimeout, originalGracePeriod := configStopTimeout, forceStopGracePeriod
+	configStopTimeout = time.Millisecond * time.Duration(500)
+	forceStopGracePeriod = time.Millisecond * time.Duration(500)
+	defer func() {
+		configStopTimeout, forceStopGracePeriod = originalTimeout, originalGracePeriod
+	}()
+	isDisabled := func(generation int64) bool {
+		DisabledLogtailConfigLock.RLock()
+		defer DisabledLogtailConfigLock.RUnlock()
+		_, exists := DisabledLogtailConfig[generation]
+		return exists
+	}
+	generations := make([]int64, 0)
+	defer func() {
+		LastUnsendBufferLock.Lock()
+		for _, generation := range generations {
+			delete(LastUnsendBuffer, unsendBufferKey("force_stop_config", generation))
+		}
+		LastUnsendBufferLock.Unlock()
+	}()
+
+	// the input ignoring Stop exits once it is closed
+	config := `{"inputs": [{"type": "service_unresponsive_test"}], "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "force_stop_config", config))
+	LogtailConfigLock.RLock()
+	generations = append(generations, LogtailConfig["force_stop_config"].Generation())
+	LogtailConfigLock.RUnlock()
+	s.NoError(ForceStop("force_stop_config"))
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "force_stop_config")
+	LogtailConfigLock.RUnlock()
+	s.False(isDisabled(generations[0]))
+	s.ErrorIs(ForceStop("force_stop_config"), ErrConfigNotFound)
+
+	// plugins which still do not exit are reported
+	hangFlusherRelease = make(chan struct{})
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "force_stop_config", `{"flushers": [{"type": "flusher_hang_test"}]}`))
+	LogtailConfigLock.RLock()
+	generations = append(generations, LogtailConfig["force_stop_config"].Generation())
+	LogtailConfigLock.RUnlock()
+	err := ForceStop("force_stop_config")
+	s.ErrorIs(err, ErrForceStopIncomplete)
+	s.ErrorContains(err, "flusher_hang_test")
+	s.True(isDisabled(generations[1]))
+
+	// a config already disabled is released by name
+	s.ErrorIs(ForceStop("force_stop_config"), ErrForceStopIncomplete)
+	close(hangFlusherRelease)
+	s.Eventually(func() bool {
+		return !isDisabled(generations[1])
+	}, time.Second*5, time.Millisecond*100)
+}
+
+func (s *managerTestSuite) TestStopWithContext() {
+	defer ClearQuarantine("slow_stop_config")
+	slowConfig := `{"flushers": [{"type": "flusher_slow_stop_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "slow_stop_config", slowConfig), "got err when logad config")
+	LogtailConfigLock.RLock()
+	generation := LogtailConfig["slow_stop_config"].Generation()
+	LogtailConfigLock.RUnlock()
+
+	// a tighter deadline than the timeout
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(100))
+	defer cancel()
+	s.ErrorIs(StopWithContext(ctx, "slow_stop_config", true), context.DeadlineExceeded)
+	DisabledLogtailConfigLock.RLock()
+	s.Contains(DisabledLogtailConfig, generation)
+	DisabledLogtailConfigLock.RUnlock()
+	s.Eventually(func() bool {
+		DisabledLogtailConfigLock.RLock()
+		defer DisabledLogtailConfigLock.RUnlock()
+		_, exists := DisabledLogtailConfig[generation]
+		return !exists
+	}, time.Second*5, time.Millisecond*100)
+
+	// a looser deadline than the timeout
+	originalTimeout := configStopTimeout
+	configStopTimeout = time.Millisecond * time.Duration(100)
+	defer func() {
+		configStopTimeout = originalTimeout
+	}()
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "slow_stop_config", slowConfig), "got err when logad config")
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second*time.Duration(5))
+	defer cancel()
+	s.NoError(StopWithContext(ctx, "slow_stop_config", true))
+	events := ConfigEventLog("slow_stop_config", 1)
+	s.Require().Len(events, 1)
+	s.Equal(ConfigEventStopped, events[0].Kind)
+}
+
+// slowStopFlusher takes one second to stop.
+type slowStopFlusher struct {
+	hangFlusher
+}
+
+func (f *slowStopFlusher) Stop() error {
+	time.Sleep(time.Second)
+	return nil
+}
+
+func init() {
+	pipeline.Flushers["flusher_slow_stop_test"] = func() pipeline.Flusher {
+		return &slowStopFlusher{}
+	}
+}
+
+// slowFlushFlusher takes 100ms to flush, and records the logs flushed before it is stopped.
+type slowFlushFlusher struct {
+	hangFlusher
+	flushedLogs       atomic.Int64
+	flushedLogsAtStop atomic.Int64
+	stopped           atomic.Bool
+	flushedAfterStop  atomic.Bool
+}
+
+var lastSlowFlushFlusher *slowFlushFlusher
+
+func (f *slowFlushFlusher) Flush(projectName string, logstoreName string, configName string, logGroupList []*protocol.LogGroup) error {
+	time.Sleep(time.Millisecond * time.Duration(100))
+	if f.stopped.Load() {
+		f.flushedAfterStop.Store(true)
+	}
+	for _, logGroup := range logGroupList {
+		f.flushedLogs.Add(int64(len(logGroup.Logs)))
+	}
+	return nil
+}
+
+func (f *slowFlushFlusher) Stop() error {
+	f.flushedLogsAtStop.Store(f.flushedLogs.Load())
+	f.stopped.Store(true)
+	return nil
+}
+
+func init() {
+	pipeline.Flushers["flusher_slow_flush_test"] = func() pipeline.Flusher {
+		lastSlowFlushFlusher = &slowFlushFlusher{}
+		return lastSlowFlushFlusher
+	}
+}
+
+func (s *managerTestSuite) TestStopOrder() {
+	s.Equal([]pluginCategory{pluginServiceInput, pluginMetricInput, pluginProcessor, pluginAggregator, pluginFlusher, pluginExtension}, stopOrder)
+
+	slowConfig := `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 100, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_slow_flush_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "slow_flush_config", slowConfig), "got err when logad config")
+	flusher := lastSlowFlushFlusher
+	LogtailConfigLock.RLock()
+	runner := LogtailConfig["slow_flush_config"].PluginRunner.(*pluginv1Runner)
+	LogtailConfigLock.RUnlock()
+	time.Sleep(time.Millisecond * time.Duration(1500))
+	s.NoError(Stop("slow_flush_config", true))
+
+	// all logs from the input are flushed before the flusher is stopped
+	counters := runner.recordCounters.snapshot()
+	s.True(counters.InputRecords > 0)
+	s.Equal(counters.InputRecords, flusher.flushedLogsAtStop.Load())
+	s.False(flusher.flushedAfterStop.Load())
+}
+
+// stopPriorityFlusher records its Name in stopPriorityFlushers when stopped.
+type stopPriorityFlusher struct {
+	hangFlusher
+	Name     string
+	Priority int
+}
+
+var stopPriorityFlushersLock sync.Mutex
+var stopPriorityFlushers []string
+
+func (f *stopPriorityFlusher) StopPriority() int {
+	return f.Priority
+}
+
+func (f *stopPriorityFlusher) Stop() error {
+	stopPriorityFlushersLock.Lock()
+	stopPriorityFlushers = append(stopPriorityFlushers, f.Name)
+	stopPriorityFlushersLock.Unlock()
+	return nil
+}
+
+func init() {
+	pipeline.Flushers["flusher_stop_priority_test"] = func() pipeline.Flusher {
+		return &stopPriorityFlusher{}
+	}
+}
+
+func (s *managerTestSuite) TestStopPriority() {
+	priorityConfig := `{"flushers": [
+		{"type": "flusher_stop_priority_test", "detail": {"Name": "late", "Priority": 1}},
+		{"type": "flusher_stop_priority_test", "detail": {"Name": "early", "Priority": -1}},
+		{"type": "flusher_stop_priority_test", "detail": {"Name": "default"}}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "stop_priority_config", priorityConfig))
+	stopPriorityFlushersLock.Lock()
+	stopPriorityFlushers = nil
+	stopPriorityFlushersLock.Unlock()
+	s.NoError(Stop("stop_priority_config", true))
+
+	// flushers are stopped by ascending priority
+	stopPriorityFlushersLock.Lock()
+	defer stopPriorityFlushersLock.Unlock()
+	s.Equal([]string{"early", "default", "late"}, stopPriorityFlushers)
+}
+
+// flushOnStopService emits its buffered data in Stop, after it is asked to stop.
+type flushOnStopService struct {
+	collector pipeline.Collector
+	stopped   chan struct{}
+}
+
+func (i *flushOnStopService) Init(pipeline.Context) (int, error) {
+	i.stopped = make(chan struct{})
+	return 0, nil
+}
+
+func (i *flushOnStopService) Description() string {
+	return "service input flushing on stop for test"
+}
+
+func (i *flushOnStopService) Start(collector pipeline.Collector) error {
+	i.collector = collector
+	<-i.stopped
+	return nil
+}
+
+func (i *flushOnStopService) Stop() error {
+	i.collector.AddData(nil, map[string]string{"content": "buffered"})
+	close(i.stopped)
+	return nil
+}
+
+func init() {
+	pipeline.ServiceInputs["service_flush_on_stop_test"] = func() pipeline.ServiceInput {
+		return &flushOnStopService{}
+	}
+}
+
+func (s *managerTestSuite) TestStopOrderDataAfterInputStop() {
+	config := `{"inputs": [{"type": "service_flush_on_stop_test"}], "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "flush_on_stop_config", config), "got err when logad config")
+	LogtailConfigLock.RLock()
+	runner := LogtailConfig["flush_on_stop_config"].PluginRunner
+	LogtailConfigLock.RUnlock()
+	checkFlusher, ok := GetConfigFlushers(runner)[0].(*checker.FlusherChecker)
+	s.Require().True(ok)
+	s.Zero(checkFlusher.GetLogCount())
+	time.Sleep(time.Millisecond * time.Duration(100))
+
+	// data produced by the input while stopping passes processors and aggregators before flushers stop
+	s.NoError(StopAllPipelines(true))
+	s.Equal(1, checkFlusher.GetLogCount())
+	s.NoError(checkFlusher.CheckKeyValue("content", "buffered"))
+}
+
+func (s *managerTestSuite) TestStopAllPipelinesContext() {
+	hangFlusherRelease = make(chan struct{})
+	hangConfig := `{"flushers": [{"type": "flusher_hang_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "hang_config", hangConfig), "got err when logad config")
+	LogtailConfigLock.RLock()
+	generation := LogtailConfig["hang_config"].Generation()
+	LogtailConfigLock.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(200))
+	defer cancel()
+	begin := time.Now()
+	s.ErrorIs(StopAllPipelinesContext(ctx, false), context.DeadlineExceeded)
+	s.True(time.Since(begin) < time.Second*5, "stop should not wait for the stop timeout")
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "hang_config")
+	LogtailConfigLock.RUnlock()
+	DisabledLogtailConfigLock.RLock()
+	s.Contains(DisabledLogtailConfig, generation)
+	DisabledLogtailConfigLock.RUnlock()
+
+	close(hangFlusherRelease)
+	s.Eventually(func() bool {
+		DisabledLogtailConfigLock.RLock()
+		defer DisabledLogtailConfigLock.RUnlock()
+		_, exists := DisabledLogtailConfig[generation]
+		return !exists
+	}, time.Second*5, time.Millisecond*100)
+}
+
+func (s *managerTestSuite) TestStopAllPipelinesInParallel() {
+	slowConfig := `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 10, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_slow_stop_test"}]}`
+	configCount := 8
+	for i := 0; i < configCount; i++ {
+		s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", fmt.Sprintf("slow_config_%d", i), slowConfig), "got err when logad config")
+	}
+	time.Sleep(time.Millisecond * time.Duration(100))
+
+	begin := time.Now()
+	s.NoError(StopAllPipelines(true))
+	s.Less(time.Since(begin), time.Second*time.Duration(configCount/2))
+	for _, info := range ListRunningConfigs() {
+		s.False(strings.HasPrefix(info.ConfigNameWithSuffix, "slow_config_"))
+	}
+}
+
+func (s *managerTestSuite) TestStopAllPipelinesWithoutLock() {
+	slowConfig := `{"flushers": [{"type": "flusher_slow_stop_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "slow_config", slowConfig), "got err when logad config")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.NoError(StopAllPipelines(false))
+	}()
+	// readers are not blocked while the config takes one second to stop
+	var longest time.Duration
+	for stopping := true; stopping; {
+		select {
+		case <-done:
+			stopping = false
+		case <-time.After(time.Millisecond * time.Duration(10)):
+			begin := time.Now()
+			GetConfigState("slow_config")
+			if elapsed := time.Since(begin); elapsed > longest {
+				longest = elapsed
+			}
+		}
+	}
+	s.Less(longest, time.Millisecond*time.Duration(100))
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "slow_config")
+	LogtailConfigLock.RUnlock()
+}
+
+func (s *managerTestSuite) TestStopAllPipelinesConcurrencyLimit() {
+	originalConcurrency := *StopAllPipelinesConcurrency
+	*StopAllPipelinesConcurrency = 2
+	defer func() {
+		*StopAllPipelinesConcurrency = originalConcurrency
+	}()
+	slowConfig := `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 10, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_slow_stop_test"}]}`
+	configCount := 4
+	for i := 0; i < configCount; i++ {
+		s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", fmt.Sprintf("slow_config_%d", i), slowConfig), "got err when logad config")
+	}
+	time.Sleep(time.Millisecond * time.Duration(100))
+
+	// each config takes 1s to stop, and at most 2 of them are stopped at the same time
+	begin := time.Now()
+	s.NoError(StopAllPipelines(true))
+	elapsed := time.Since(begin)
+	s.GreaterOrEqual(elapsed, time.Second*time.Duration(configCount/2))
+	s.Less(elapsed, time.Second*time.Duration(configCount))
+}
+
+// urgentReadyFlusher is only ready after SetUrgent is called.
+type urgentReadyFlusher struct {
+	hangFlusher
+	urgent atomic.Bool
+}
+
+func (f *urgentReadyFlusher) SetUrgent(flag bool) {
+	f.urgent.Store(flag)
+}
+
+func (f *urgentReadyFlusher) IsReady(projectName string, logstoreName string, logstoreKey int64) bool {
+	return f.urgent.Load()
+}
+
+func (f *urgentReadyFlusher) Stop() error {
+	return nil
+}
+
+func init() {
+	pipeline.Flushers["flusher_urgent_ready_test"] = func() pipeline.Flusher {
+		return &urgentReadyFlusher{}
+	}
+}
+
+// panicInput panics on each collection.
+type panicInput struct{}
+
+func (r *panicInput) Init(context pipeline.Context) (int, error) {
+	return 10, nil
+}
+
+func (r *panicInput) Description() string {
+	return "input which panics for test"
+}
+
+func (r *panicInput) Collect(collector pipeline.Collector) error {
+	panic("panic input test")
+}
+
+func init() {
+	pipeline.MetricInputs["metric_panic_test"] = func() pipeline.MetricInput {
+		return &panicInput{}
+	}
+}
+
+func (s *managerTestSuite) TestPanicDisableConfig() {
+	panicConfig := `{"inputs": [{"type": "metric_panic_test"}], "flushers": [{"type": "flusher_checker"}]}`
+	originalThreshold := *PanicDisableThreshold
+	*PanicDisableThreshold = 0
+	defer func() {
+		*PanicDisableThreshold = originalThreshold
+	}()
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "panic_config", panicConfig), "got err when logad config")
+	LogtailConfigLock.RLock()
+	config := LogtailConfig["panic_config"]
+	LogtailConfigLock.RUnlock()
+	s.Eventually(func() bool {
+		return config.RecordCounters().Panics == 1
+	}, time.Second*5, time.Millisecond*10)
+	LogtailConfigLock.RLock()
+	s.Contains(LogtailConfig, "panic_config")
+	LogtailConfigLock.RUnlock()
+	s.NoError(Stop("panic_config", true))
+
+	states := make(chan ConfigState, 10)
+	RegisterConfigStateListener(func(configName string, state ConfigState) {
+		if configName == "panic_config" {
+			states <- state
+		}
+	})
+	*PanicDisableThreshold = 1
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "panic_config", panicConfig), "got err when logad config")
+	s.Eventually(func() bool {
+		LogtailConfigLock.RLock()
+		defer LogtailConfigLock.RUnlock()
+		_, exists := LogtailConfig["panic_config"]
+		return !exists
+	}, time.Second*5, time.Millisecond*10)
+	s.Equal(ConfigStateDisabled, <-states)
+	s.Equal(ConfigStateRecovered, <-states)
+}
+
+func (s *managerTestSuite) TestStopAllPipelinesWithDrain() {
+	drainConfig := `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 10, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_urgent_ready_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "drain_config", drainConfig), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	LogtailConfigLock.RLock()
+	config := LogtailConfig["drain_config"]
+	LogtailConfigLock.RUnlock()
+	s.False(isConfigDrained(config))
+
+	begin := time.Now()
+	s.NoError(StopAllPipelinesWithDrain(true, time.Second*time.Duration(3)))
+	s.Less(time.Since(begin), time.Second*time.Duration(3))
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "drain_config")
+	LogtailConfigLock.RUnlock()
+}
+
+func (s *managerTestSuite) TestStopInOrder() {
+	originalPause := *StopPhasePauseMs
+	*StopPhasePauseMs = 300
+	defer func() {
+		*StopPhasePauseMs = originalPause
+	}()
+	events := SubscribeLifecycle()
+	defer UnsubscribeLifecycle(events)
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "stop_order_input", `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 10, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_checker"}]}`))
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "stop_order_output", `{"flushers": [{"type": "flusher_checker"}]}`))
+	time.Sleep(time.Millisecond * time.Duration(100))
+
+	s.NoError(StopInOrder(context.Background()))
+	stopped := make(map[string]time.Time)
+	for len(stopped) < 2 {
+		select {
+		case event := <-events:
+			if event.Kind == LifecycleStopped && strings.HasPrefix(event.ConfigName, "stop_order_") {
+				stopped[event.ConfigName] = event.Timestamp
+			}
+		case <-time.After(time.Second * 5):
+			s.FailNow("no lifecycle event received")
+		}
+	}
+	// configs with input are stopped first, and downstream configs are stopped after the pause
+	s.GreaterOrEqual(stopped["stop_order_output"].Sub(stopped["stop_order_input"]), time.Millisecond*time.Duration(300))
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "stop_order_input")
+	s.NotContains(LogtailConfig, "stop_order_output")
+	LogtailConfigLock.RUnlock()
+
+	// the pause is cut short once ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(100))
+	defer cancel()
+	*StopPhasePauseMs = 5000
+	begin := time.Now()
+	s.ErrorIs(StopInOrder(ctx), context.DeadlineExceeded)
+	s.Less(time.Since(begin), time.Second*time.Duration(5))
+}
+
+func (s *managerTestSuite) TestStopWithDrainTimeout() {
+	drainConfig := `{"global": {"DrainTimeoutMs": 500}, "flushers": [{"type": "flusher_urgent_ready_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "drain_config", drainConfig), "got err when logad config")
+	LogtailConfigLock.RLock()
+	generation := LogtailConfig["drain_config"].Generation()
+	LogtailConfigLock.RUnlock()
+	// The flusher is not ready if the config is not removed, so stop waits for the drain timeout.
+	begin := time.Now()
+	s.NoError(Stop("drain_config", false))
+	s.GreaterOrEqual(time.Since(begin), time.Millisecond*time.Duration(500))
+	LastUnsendBufferLock.Lock()
+	delete(LastUnsendBuffer, unsendBufferKey("drain_config", generation))
+	LastUnsendBufferLock.Unlock()
+
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "drain_config", drainConfig), "got err when logad config")
+	begin = time.Now()
+	s.NoError(Stop("drain_config", true))
+	s.Less(time.Since(begin), time.Millisecond*time.Duration(500))
+}
+
+func (s *managerTestSuite) TestFlushBufferOnRemove() {
+	*FlushBufferOnRemove = true
+	defer func() {
+		*FlushBufferOnRemove = false
+	}()
+	config, err := createLogstoreConfig("test_prj", "test_logstore", "flush_removed_config", 0, `{"flushers": [{"type": "flusher_checker"}]}`)
+	s.NoError(err)
+	checkFlusher, ok := GetConfigFlushers(config.PluginRunner)[0].(*checker.FlusherChecker)
+	s.True(ok)
+	runner := config.PluginRunner.(*pluginv1Runner)
+	runner.FlushOutStore.Add(&protocol.LogGroup{Logs: []*protocol.Log{{Contents: []*protocol.Log_Content{{Key: "content", Value: "test"}}}}})
+
+	DeleteLogstoreConfig(config, true)
+	s.Equal(1, checkFlusher.GetLogCount())
+	s.Equal(0, runner.FlushOutStore.Len())
+}
+
+func (s *managerTestSuite) TestConfigMemoryStats() {
+	config, err := createLogstoreConfig("test_prj", "test_logstore", "memory_config", 0, `{"flushers": [{"type": "flusher_checker"}]}`)
+	s.NoError(err)
+	runner := config.PluginRunner.(*pluginv1Runner)
+	runner.LogsChan <- &pipeline.LogWithContext{Log: &protocol.Log{}}
+	runner.LogsChan <- &pipeline.LogWithContext{Log: &protocol.Log{}}
+	runner.LogGroupsChan <- &protocol.LogGroup{}
+	runner.recordCounters.sampleGroupSize(100, 10)
+	LogtailConfigLock.Lock()
+	LogtailConfig["memory_config"] = config
+	LogtailConfigLock.Unlock()
+	defer func() {
+		LogtailConfigLock.Lock()
+		delete(LogtailConfig, "memory_config")
+		LogtailConfigLock.Unlock()
+	}()
+
+	usage := ConfigMemoryStats()["memory_config"]
+	s.Equal(2, usage.InputQueueDepth)
+	s.Equal(1, usage.AggregatorQueueDepth)
+	s.Equal(0, usage.BufferedGroups)
+	s.Equal(int64(2*10+100), usage.EstimatedBytes)
+}
+
+func (s *managerTestSuite) TestStartMemoryBudget() {
+	defer func() {
+		*StartMemoryBudgetMB = 0
+		*SkipStartMemoryBudget = false
+	}()
+	budgetConfig := `{"global": {"DefaultLogQueueSize": 100, "DefaultLogGroupQueueSize": 10}, "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "budget_config", 0, budgetConfig))
+	// the input queue of a config without inputs is limited to 10
+	s.Equal(int64(10*estimatedRecordBytes+10*estimatedGroupBytes), estimateConfigMemory(ToStartPipelineConfigWithoutInput))
+
+	*StartMemoryBudgetMB = 1
+	s.ErrorIs(Start("budget_config"), ErrMemoryBudgetExceeded)
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "budget_config")
+	LogtailConfigLock.RUnlock()
+
+	*SkipStartMemoryBudget = true
+	s.NoError(Start("budget_config"))
+	s.NoError(Stop("budget_config", true))
+}
+
+func (s *managerTestSuite) TestReloadMemoryBudget() {
+	defer func() {
+		*StartMemoryBudgetMB = 0
+	}()
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "budget_config", `{"flushers": [{"type": "flusher_checker"}]}`))
+	LogtailConfigLock.RLock()
+	oldConfig := LogtailConfig["budget_config"]
+	LogtailConfigLock.RUnlock()
+
+	// the new config is refused before the old one is stopped
+	*StartMemoryBudgetMB = 1
+	s.ErrorIs(Reload("budget_config", `{"flushers": [{"type": "flusher_checker"}, {"type": "flusher_checker"}]}`), ErrMemoryBudgetExceeded)
+	LogtailConfigLock.RLock()
+	s.Equal(oldConfig, LogtailConfig["budget_config"])
+	LogtailConfigLock.RUnlock()
+	s.NotNil(oldConfig.PluginRunner)
+
+	*StartMemoryBudgetMB = 0
+	s.NoError(Reload("budget_config", `{"flushers": [{"type": "flusher_checker"}, {"type": "flusher_checker"}]}`))
+	s.NoError(Stop("budget_config", true))
+}
+
+func (s *managerTestSuite) TestManagerStats() {
+	before := ManagerStats()
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	running := ManagerStats()
+	s.Equal(before.StartedConfigs+1, running.StartedConfigs)
+	s.Equal(before.RunningConfigs+1, running.RunningConfigs)
+	s.GreaterOrEqual(running.PeakConfigs, int64(running.RunningConfigs))
+	s.NoError(Stop("test_config", true))
+	after := ManagerStats()
+	s.Equal(before.StoppedConfigs+1, after.StoppedConfigs)
+	s.Equal(before.RunningConfigs, after.RunningConfigs)
+	s.Greater(after.Uptime, before.Uptime)
+}
+
+func (s *managerTestSuite) TestStartReplacementOfRecoveredConfig() {
+	hangFlusherRelease = make(chan struct{})
+	originalTimeout := configStopTimeout
+	configStopTimeout = time.Millisecond * time.Duration(500)
+	defer func() {
+		configStopTimeout = originalTimeout
+	}()
+	hangConfig := `{"flushers": [{"type": "flusher_hang_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "recover_config", hangConfig), "got err when logad config")
+	LogtailConfigLock.RLock()
+	hung := LogtailConfig["recover_config"]
+	LogtailConfigLock.RUnlock()
+	s.NoError(Stop("recover_config", false))
+	DisabledLogtailConfigLock.RLock()
+	s.Contains(DisabledLogtailConfig, hung.Generation())
+	DisabledLogtailConfigLock.RUnlock()
+
+	// The replacement is loaded while the old one hangs, which unblocks after the stop timeout.
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "recover_config", 0, GetTestConfig(noblockUpdateNoInputConfigName)))
+	close(hangFlusherRelease)
+	s.Eventually(func() bool {
+		DisabledLogtailConfigLock.RLock()
+		defer DisabledLogtailConfigLock.RUnlock()
+		_, exists := DisabledLogtailConfig[hung.Generation()]
+		return !exists
+	}, time.Second*5, time.Millisecond*100)
+	parkedKey := unsendBufferKey("recover_config", hung.Generation())
+	LastUnsendBufferLock.Lock()
+	s.Contains(LastUnsendBuffer, parkedKey)
+	LastUnsendBufferLock.Unlock()
+
+	s.NoError(Start("recover_config"))
+	LastUnsendBufferLock.Lock()
+	s.NotContains(LastUnsendBuffer, parkedKey)
+	LastUnsendBufferLock.Unlock()
+	LogtailConfigLock.RLock()
+	replacement := LogtailConfig["recover_config"]
+	LogtailConfigLock.RUnlock()
+	s.NotNil(replacement)
+	s.NotEqual(hung.Generation(), replacement.Generation())
+	s.NoError(Stop("recover_config", true))
+}
+
+func (s *managerTestSuite) TestUnsendBufferLimit() {
+	originalMax := *MaxUnsendBuffers
+	*MaxUnsendBuffers = 3
+	defer func() {
+		*MaxUnsendBuffers = originalMax
+		LastUnsendBufferLock.Lock()
+		for key := range LastUnsendBuffer {
+			if strings.HasPrefix(key, "unsend_config_") {
+				delete(LastUnsendBuffer, key)
+				delete(lastUnsendBufferTime, key)
+			}
+		}
+		LastUnsendBufferLock.Unlock()
+	}()
+	for i := 0; i < 6; i++ {
+		configName := fmt.Sprintf("unsend_config_%d", i)
+		s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", configName), "got err when logad config")
+		time.Sleep(time.Millisecond * time.Duration(100))
+		s.NoError(Stop(configName, false))
+		s.True(GetUnsendBufferSize() <= 3)
+	}
+	status, found := GetConfigState("unsend_config_5")
+	s.True(found)
+	s.True(status.HasUnsentBuffer)
+	_, found = GetConfigState("unsend_config_0")
+	s.False(found)
+
+	originalTTL := *UnsendBufferTTLSec
+	*UnsendBufferTTLSec = 60
+	defer func() {
+		*UnsendBufferTTLSec = originalTTL
+	}()
+	LastUnsendBufferLock.Lock()
+	for key := range lastUnsendBufferTime {
+		if strings.HasPrefix(key, "unsend_config_5#") {
+			lastUnsendBufferTime[key] = time.Now().Add(-time.Hour)
+		}
+	}
+	LastUnsendBufferLock.Unlock()
+	expireUnsendBuffer()
+	_, found = GetConfigState("unsend_config_5")
+	s.False(found)
+	_, found = GetConfigState("unsend_config_4")
+	s.True(found)
+}
+
+func (s *managerTestSuite) TestUnsendBufferMetrics() {
+	agentStat := func(key string) int64 {
+		value, err := strconv.ParseInt(GetAgentStat()[0][key], 10, 64)
+		s.NoError(err)
+		return value
+	}
+	recoveredRecords := agentStat(selfmonitor.MetricAgentUnsendBufferRecoveredRecordsTotal)
+	recoveredBytes := agentStat(selfmonitor.MetricAgentUnsendBufferRecoveredBytesTotal)
+	dropped := agentStat(selfmonitor.MetricAgentUnsendBufferDroppedTotal)
+
+	group := &protocol.LogGroup{Logs: []*protocol.Log{
+		{Contents: []*protocol.Log_Content{{Key: "content", Value: "first"}}},
+		{Contents: []*protocol.Log_Content{{Key: "content", Value: "second"}}},
+	}}
+	runner := &pluginv1Runner{FlushOutStore: NewFlushOutStore[protocol.LogGroup]()}
+	runner.FlushOutStore.Add(group)
+	parkUnsendBuffer(unsendBufferKey("unsend_metrics_config", -1), runner)
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "unsend_metrics_config", `{"flushers": [{"type": "flusher_checker"}]}`), "got err when logad config")
+	s.NotContains(PendingUnsentConfigs(), "unsend_metrics_config")
+	s.Equal(recoveredRecords+2, agentStat(selfmonitor.MetricAgentUnsendBufferRecoveredRecordsTotal))
+	s.Equal(recoveredBytes+int64(group.Size()), agentStat(selfmonitor.MetricAgentUnsendBufferRecoveredBytesTotal))
+	s.NoError(Stop("unsend_metrics_config", true))
+
+	// unsent data not adopted in time is dropped
+	originalTTL := *UnsendBufferTTLSec
+	*UnsendBufferTTLSec = 60
+	defer func() {
+		*UnsendBufferTTLSec = originalTTL
+	}()
+	key := unsendBufferKey("unsend_metrics_dropped_config", -1)
+	parkUnsendBuffer(key, &pluginv1Runner{FlushOutStore: NewFlushOutStore[protocol.LogGroup]()})
+	LastUnsendBufferLock.Lock()
+	lastUnsendBufferTime[key] = time.Now().Add(-time.Hour)
+	LastUnsendBufferLock.Unlock()
+	expireUnsendBuffer()
+	s.NotContains(PendingUnsentConfigs(), "unsend_metrics_dropped_config")
+	s.Equal(dropped+1, agentStat(selfmonitor.MetricAgentUnsendBufferDroppedTotal))
+}
+
+func (s *managerTestSuite) TestDiscardUnsentBuffer() {
+	parkUnsendBuffer(unsendBufferKey("unsent_config", -1), &pluginv1Runner{})
+	parkUnsendBuffer(unsendBufferKey("unsent_config", -2), &pluginv1Runner{})
+	parkUnsendBuffer(unsendBufferKey("unsent_config#2", -1), &pluginv1Runner{})
+	defer DiscardUnsentBuffer("unsent_config#2")
+	pending := make([]string, 0)
+	for _, configName := range PendingUnsentConfigs() {
+		if strings.HasPrefix(configName, "unsent_config") {
+			pending = append(pending, configName)
+		}
+	}
+	s.Equal([]string{"unsent_config", "unsent_config#2"}, pending)
+
+	DiscardUnsentBuffer("unsent_config")
+	s.NotContains(PendingUnsentConfigs(), "unsent_config")
+	s.Contains(PendingUnsentConfigs(), "unsent_config#2")
+	_, found := GetConfigState("unsent_config")
+	s.False(found)
+}
+
+func (s *managerTestSuite) TestRemoveConfigDiscardsUnsentBuffer() {
+	hangFlusherRelease = make(chan struct{})
+	originalTimeout := configStopTimeout
+	configStopTimeout = time.Millisecond * time.Duration(500)
+	defer func() {
+		configStopTimeout = originalTimeout
+	}()
+	hangConfig := `{"flushers": [{"type": "flusher_hang_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "hang_config", hangConfig), "got err when logad config")
+	// the old version is parked in LastUnsendBuffer once it stops after the new version is started
+	s.NoError(Reload("hang_config", hangConfig))
+	close(hangFlusherRelease)
+	s.Eventually(func() bool {
+		status, _ := GetConfigState("hang_config")
+		return status.HasUnsentBuffer
+	}, time.Second*5, time.Millisecond*10)
+
+	s.NoError(Stop("hang_config", true))
+	s.NotContains(PendingUnsentConfigs(), "hang_config")
+}
+
+func (s *managerTestSuite) TestRegisterBuiltinConfig() {
+	s.Error(RegisterBuiltinConfig(builtinAlarmName, "test_prj", "test_logstore", "custom_builtin", `{}`))
+	s.Error(RegisterBuiltinConfig("custom", "test_prj", "test_logstore", "custom_builtin", `{`))
+	s.NoError(RegisterBuiltinConfig("custom", "test_prj", "test_logstore", "custom_builtin", `{"flushers": [{"type": "flusher_checker"}]}`))
+	defer func() {
+		builtinConfigLock.Lock()
+		builtinConfigs = builtinConfigs[:len(builtinConfigs)-1]
+		builtinConfigLock.Unlock()
+	}()
+	s.Error(RegisterBuiltinConfig("custom", "test_prj", "test_logstore", "custom_builtin", `{}`))
+
+	s.NoError(Init(context.Background(), false))
+	custom := builtinConfigs[len(builtinConfigs)-1]
+	s.NotNil(custom.config)
+	s.Equal("custom_builtin", custom.config.ConfigNameWithSuffix)
+	s.NotNil(AlarmConfig)
+	s.NotNil(ContainerConfig)
+	StartBuiltInModulesConfig()
+	StopBuiltInModulesConfig()
+	s.Nil(custom.config)
+	s.Nil(AlarmConfig)
+	s.Nil(ContainerConfig)
+}
+
+func (s *managerTestSuite) TestInitDegraded() {
+	s.NoError(RegisterBuiltinConfig("broken", "test_prj", "test_logstore", "broken_builtin", `{"flushers": [{"type": "flusher_not_exist"}]}`))
+	defer func() {
+		builtinConfigLock.Lock()
+		builtinConfigs = builtinConfigs[:len(builtinConfigs)-1]
+		builtinConfigLock.Unlock()
+	}()
+
+	err := Init(context.Background(), false)
+	var loadErr *BuiltinConfigLoadError
+	s.True(errors.As(err, &loadErr))
+	s.Equal("broken", loadErr.Name)
+	s.False(loadErr.Critical)
+	stopBuiltinConfigs()
+
+	s.NoError(Init(context.Background(), true))
+	s.NotNil(AlarmConfig)
+	s.NotNil(ContainerConfig)
+	s.Nil(builtinConfigs[len(builtinConfigs)-1].config)
+	stopBuiltinConfigs()
+
+	// a critical built-in config fails Init even if degraded start is allowed
+	s.NoError(RegisterCriticalBuiltinConfig("broken_critical", "test_prj", "test_logstore", "broken_critical_builtin", `{"flushers": [{"type": "flusher_not_exist"}]}`))
+	defer func() {
+		builtinConfigLock.Lock()
+		builtinConfigs = builtinConfigs[:len(builtinConfigs)-1]
+		builtinConfigLock.Unlock()
+	}()
+	err = Init(context.Background(), true)
+	s.True(errors.As(err, &loadErr))
+	s.Equal("broken_critical", loadErr.Name)
+	s.True(loadErr.Critical)
+	stopBuiltinConfigs()
+}
+
+func (s *managerTestSuite) TestInitWithBuiltinConfigsDisabled() {
+	stopBuiltinConfigs()
+	*EnableAlarmMetrics = false
+	*EnableContainerMetrics = false
+	defer func() {
+		*EnableAlarmMetrics = true
+		*EnableContainerMetrics = true
+	}()
+	s.NoError(Init(context.Background(), false))
+	s.Nil(AlarmConfig)
+	s.Nil(ContainerConfig)
+	StartBuiltInModulesConfig()
+	stopBuiltinConfigs()
+
+	*EnableContainerMetrics = true
+	s.NoError(Init(context.Background(), false))
+	s.Nil(AlarmConfig)
+	s.NotNil(ContainerConfig)
+	stopBuiltinConfigs()
+}
+
+func (s *managerTestSuite) TestBuiltinConfigOverride() {
+	stopBuiltinConfigs()
+	overridePath := filepath.Join(s.T().TempDir(), "container.json")
+	*ContainerConfigOverridePath = overridePath
+	defer func() {
+		*ContainerConfigOverridePath = ""
+	}()
+
+	s.NoError(os.WriteFile(overridePath, []byte(`{"global": {"InputIntervalMs": 60000}, "inputs": [{"type": "metric_container"}]}`), 0600))
+	s.NoError(Init(context.Background(), false))
+	s.Equal(60000, ContainerConfig.GlobalConfig.InputIntervalMs)
+	stopBuiltinConfigs()
+
+	// the built-in config is used if the override is malformed
+	s.NoError(os.WriteFile(overridePath, []byte(`{"global": {`), 0600))
+	s.NoError(Init(context.Background(), false))
+	s.Equal(30000, ContainerConfig.GlobalConfig.InputIntervalMs)
+	stopBuiltinConfigs()
+}
+
+func (s *managerTestSuite) TestBuiltinConfigInterval() {
+	stopBuiltinConfigs()
+	*ContainerInputIntervalMs = 5000
+	defer func() {
+		*ContainerInputIntervalMs = 30000
+	}()
+	s.NoError(Init(context.Background(), false))
+	s.Equal(5000, ContainerConfig.GlobalConfig.InputIntervalMs)
+	s.Equal(30000, AlarmConfig.GlobalConfig.InputIntervalMs)
+	s.Equal(4, ContainerConfig.GlobalConfig.DefaultLogQueueSize)
+	stopBuiltinConfigs()
+}
+
+func (s *managerTestSuite) TestInitCancelled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ErrorIs(Init(ctx, false), context.Canceled)
+
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	s.ErrorIs(Init(ctx, false), context.DeadlineExceeded)
+}
+
 func GetTestConfig(configName string) string {
 	fileName := "./test_config/" + configName + ".json"
 	byteStr, err := os.ReadFile(fileName)
@@ -174,3 +1721,1202 @@ func LoadAndStartMockConfig(args ...string) error {
 	}
 	return Start(configName)
 }
+
+func (s *managerTestSuite) TestPauseInput() {
+	pauseConfig := `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 100, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "pause_config", pauseConfig), "got err when logad config")
+	LogtailConfigLock.RLock()
+	config := LogtailConfig["pause_config"]
+	LogtailConfigLock.RUnlock()
+	s.Eventually(func() bool {
+		return config.RecordCounters().InputRecords > 0
+	}, time.Second*5, time.Millisecond*10)
+
+	s.Error(PauseInput("pause_config", 1))
+	s.ErrorIs(PauseInput("not_exist_config", 0), ErrConfigNotFound)
+	s.NoError(PauseInput("pause_config", 0))
+	time.Sleep(time.Millisecond * time.Duration(500))
+	paused := config.RecordCounters().InputRecords
+	time.Sleep(time.Millisecond * time.Duration(500))
+	s.Equal(paused, config.RecordCounters().InputRecords)
+
+	s.NoError(ResumeInput("pause_config", 0))
+	s.Eventually(func() bool {
+		return config.RecordCounters().InputRecords > paused
+	}, time.Second*5, time.Millisecond*10)
+
+	// a paused input does not block stopping the config
+	s.NoError(PauseInput("pause_config", 0))
+	s.NoError(Stop("pause_config", true))
+}
+
+func (s *managerTestSuite) TestPauseConfig() {
+	pauseConfig := `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 100, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "pause_config", pauseConfig), "got err when logad config")
+	LogtailConfigLock.RLock()
+	config := LogtailConfig["pause_config"]
+	LogtailConfigLock.RUnlock()
+	s.Eventually(func() bool {
+		return config.RecordCounters().InputRecords > 0
+	}, time.Second*5, time.Millisecond*10)
+
+	s.ErrorIs(Pause("not_exist_config"), ErrConfigNotFound)
+	s.NoError(Pause("pause_config"))
+	time.Sleep(time.Millisecond * time.Duration(500))
+	paused := config.RecordCounters().InputRecords
+	time.Sleep(time.Millisecond * time.Duration(500))
+	s.Equal(paused, config.RecordCounters().InputRecords)
+	LogtailConfigLock.RLock()
+	s.Same(config, LogtailConfig["pause_config"])
+	LogtailConfigLock.RUnlock()
+
+	s.NoError(Resume("pause_config"))
+	s.Eventually(func() bool {
+		return config.RecordCounters().InputRecords > paused
+	}, time.Second*5, time.Millisecond*10)
+	s.NoError(Stop("pause_config", true))
+}
+
+func (s *managerTestSuite) TestCollectHealth() {
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	findStatus := func(configName string) (ConfigHealthStatus, bool) {
+		for _, status := range CollectHealth() {
+			if status.ConfigName == configName {
+				return status, true
+			}
+		}
+		return ConfigHealthStatus{}, false
+	}
+	s.Eventually(func() bool {
+		status, ok := findStatus("test_config")
+		return ok && !status.LastFlushTime.IsZero()
+	}, time.Second*5, time.Millisecond*100)
+	status, _ := findStatus("test_config")
+	s.True(status.WithInput)
+	s.False(status.Builtin)
+
+	alarm, ok := findStatus("logtail_alarm")
+	s.True(ok)
+	s.True(alarm.Builtin)
+	_, ok = findStatus("logtail_containers")
+	s.True(ok)
+
+	data, err := json.Marshal(CollectHealth())
+	s.NoError(err)
+	s.Contains(string(data), `"config_name":"test_config"`)
+	s.NoError(Stop("test_config", true))
+	_, ok = findStatus("test_config")
+	s.False(ok)
+}
+
+func (s *managerTestSuite) TestConfigPipelineLayout() {
+	_, _, err := ConfigPipelineLayout("layout_config")
+	s.ErrorIs(err, ErrConfigNotFound)
+
+	inputConfig := `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 10, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "layout_config/1", inputConfig), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	hasInput, hasNonInput, err := ConfigPipelineLayout("layout_config")
+	s.NoError(err)
+	s.True(hasInput)
+	s.False(hasNonInput)
+
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "layout_config/2", -1, `{"flushers": [{"type": "flusher_checker"}]}`))
+	hasInput, hasNonInput, err = ConfigPipelineLayout("layout_config/2")
+	s.NoError(err)
+	s.True(hasInput)
+	s.True(hasNonInput)
+	s.NoError(UnloadPartiallyLoadedConfig("layout_config/2"))
+
+	s.NoError(Stop("layout_config/1", true))
+	_, _, err = ConfigPipelineLayout("layout_config")
+	s.ErrorIs(err, ErrConfigNotFound)
+}
+
+func (s *managerTestSuite) TestConfigNameWithoutSuffix() {
+	inputConfig := `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 10, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "suffix_config/1", -1, inputConfig))
+	s.NoError(Start("suffix_config"))
+	LogtailConfigLock.RLock()
+	config := LogtailConfig["suffix_config/1"]
+	LogtailConfigLock.RUnlock()
+	s.Require().NotNil(config)
+	s.Eventually(func() bool {
+		return config.RecordCounters().InputRecords > 0
+	}, time.Second*5, time.Millisecond*10)
+
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "suffix_config/2", -1, `{"flushers": [{"type": "flusher_checker"}]}`))
+	s.ErrorIs(Start("suffix_config"), ErrAmbiguousConfigName)
+	s.NoError(Start("suffix_config/2"))
+	s.ErrorIs(Stop("suffix_config", true), ErrAmbiguousConfigName)
+	s.NoError(Stop("suffix_config/2", true))
+
+	s.NoError(Stop("suffix_config", true))
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "suffix_config/1")
+	LogtailConfigLock.RUnlock()
+	s.ErrorIs(Stop("suffix_config", true), ErrConfigNotFound)
+}
+
+func (s *managerTestSuite) TestConfigDependsOn() {
+	dependentConfig := `{"global": {"DependsOn": ["depended_config"]}, "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "dependent_config", 0, dependentConfig))
+	s.NoError(Start("dependent_config"))
+	s.Equal([]string{"dependent_config"}, DeferredConfigs())
+	status, ok := GetConfigState("dependent_config")
+	s.True(ok)
+	s.Equal(ConfigStatePendingStart, status.State)
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "dependent_config")
+	LogtailConfigLock.RUnlock()
+
+	// the deferred config starts once its dependency starts
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "depended_config", 0, `{"flushers": [{"type": "flusher_checker"}]}`))
+	s.NoError(Start("depended_config"))
+	s.Empty(DeferredConfigs())
+	LogtailConfigLock.RLock()
+	s.Contains(LogtailConfig, "dependent_config")
+	s.Contains(LogtailConfig, "depended_config")
+	LogtailConfigLock.RUnlock()
+	s.NoError(Stop("dependent_config", true))
+	s.NoError(Stop("depended_config", true))
+
+	// cycles are rejected, and the deferred config can be stopped
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "cycle_a", 0, `{"global": {"DependsOn": ["cycle_b"]}, "flushers": [{"type": "flusher_checker"}]}`))
+	s.NoError(Start("cycle_a"))
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "cycle_b", 0, `{"global": {"DependsOn": ["cycle_a"]}, "flushers": [{"type": "flusher_checker"}]}`))
+	s.ErrorIs(Start("cycle_b"), ErrDependencyCycle)
+	s.Equal([]string{"cycle_a"}, DeferredConfigs())
+	s.NoError(Stop("cycle_a", true))
+	s.Empty(DeferredConfigs())
+
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "cycle_self", 0, `{"global": {"DependsOn": ["cycle_self"]}, "flushers": [{"type": "flusher_checker"}]}`))
+	s.ErrorIs(Start("cycle_self"), ErrDependencyCycle)
+	s.Empty(DeferredConfigs())
+}
+
+func (s *managerTestSuite) TestDiscardDeferredConfig() {
+	parked := &pluginv1Runner{FlushOutStore: NewFlushOutStore[protocol.LogGroup]()}
+	parked.FlushOutStore.Add(&protocol.LogGroup{Logs: []*protocol.Log{{Time: 1}}})
+	parkUnsendBuffer(unsendBufferKey("deferred_unsent_config", -1), parked)
+	deferredConfig := `{"global": {"DependsOn": ["deferred_missing_config"]}, "flushers": [{"type": "flusher_stopped_record_test"}]}`
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "deferred_unsent_config", 0, deferredConfig))
+	s.NoError(Start("deferred_unsent_config"))
+	s.Equal([]string{"deferred_unsent_config"}, DeferredConfigs())
+	s.NotContains(PendingUnsentConfigs(), "deferred_unsent_config")
+
+	// the flushers of the deferred config are stopped, and the unsent data it adopted is kept
+	stopped := stoppedRecordFlushers.Load()
+	s.NoError(Stop("deferred_unsent_config", false))
+	s.Empty(DeferredConfigs())
+	s.Equal(stopped+1, stoppedRecordFlushers.Load())
+	s.Contains(PendingUnsentConfigs(), "deferred_unsent_config")
+	DiscardUnsentBuffer("deferred_unsent_config")
+}
+
+func (s *managerTestSuite) TestPartialStart() {
+	withoutInputConfig := `{"flushers": [{"type": "flusher_checker"}]}`
+	// the pipeline with input fails to start as it depends on itself
+	withInputConfig := `{"global": {"DependsOn": ["partial_config/1"]}, "inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 10, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_checker"}]}`
+
+	// the other pipeline runs by itself by default
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "partial_config/2", 0, withoutInputConfig))
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "partial_config/1", 0, withInputConfig))
+	s.ErrorIs(Start("partial_config/1"), ErrDependencyCycle)
+	s.NoError(Start("partial_config/2"))
+	s.NoError(Stop("partial_config/2", true))
+
+	*ReleaseSiblingOnStartFailure = true
+	defer func() {
+		*ReleaseSiblingOnStartFailure = false
+	}()
+	// the other pipeline waiting to start is unloaded
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "partial_config/2", 0, withoutInputConfig))
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "partial_config/1", 0, withInputConfig))
+	s.ErrorIs(Start("partial_config/1"), ErrDependencyCycle)
+	s.Nil(ToStartPipelineConfigWithoutInput)
+	s.ErrorIs(Start("partial_config/2"), ErrSiblingStartFailed)
+	s.ErrorIs(Start("partial_config/2"), ErrConfigMismatch)
+
+	// the other pipeline running is stopped
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "partial_config/2", 0, withoutInputConfig))
+	s.NoError(Start("partial_config/2"))
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "partial_config/1", 0, withInputConfig))
+	s.ErrorIs(Start("partial_config/1"), ErrDependencyCycle)
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "partial_config/2")
+	LogtailConfigLock.RUnlock()
+
+	// pipelines of other configs are not affected
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "other_config/2", 0, withoutInputConfig))
+	s.NoError(Start("other_config/2"))
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "partial_config/1", 0, withInputConfig))
+	s.ErrorIs(Start("partial_config/1"), ErrDependencyCycle)
+	LogtailConfigLock.RLock()
+	s.Contains(LogtailConfig, "other_config/2")
+	LogtailConfigLock.RUnlock()
+	s.NoError(Stop("other_config/2", true))
+
+	// a pipeline refused but kept loaded to retry does not release the other
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "partial_config/2", 0, withoutInputConfig))
+	s.NoError(Start("partial_config/2"))
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "partial_config/1", 0, `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 10, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_checker"}]}`))
+	*StartMemoryBudgetMB = 1
+	s.ErrorIs(Start("partial_config/1"), ErrMemoryBudgetExceeded)
+	*StartMemoryBudgetMB = 0
+	LogtailConfigLock.RLock()
+	s.Contains(LogtailConfig, "partial_config/2")
+	LogtailConfigLock.RUnlock()
+	s.NoError(Start("partial_config/1"))
+	time.Sleep(time.Millisecond * 100)
+	s.NoError(Stop("partial_config/1", true))
+	s.NoError(Stop("partial_config/2", true))
+}
+
+func (s *managerTestSuite) TestPluginInventory() {
+	before := PluginInventory()
+	v1Config := `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 10, "Fields": {"content": "test"}}}], "processors": [{"type": "processor_anchor", "detail": {"SourceKey": "content", "Anchors": []}}], "flushers": [{"type": "flusher_checker"}, {"type": "flusher_stdout"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "inventory_v1_config", v1Config))
+	v2Config := `{"global": {"StructureType": "v2"}, "flushers": [{"type": "flusher_stdout"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "inventory_v2_config", v2Config))
+
+	after := PluginInventory()
+	s.Equal(before.V1Configs+1, after.V1Configs)
+	s.Equal(before.V2Configs+1, after.V2Configs)
+	s.Equal(before.V1.ServiceInputs+1, after.V1.ServiceInputs)
+	s.Equal(before.V1.Processors+1, after.V1.Processors)
+	s.Equal(before.V1.Aggregators+1, after.V1.Aggregators)
+	s.Equal(before.V1.Flushers+2, after.V1.Flushers)
+	s.Equal(before.V2.Aggregators+1, after.V2.Aggregators)
+	s.Equal(before.V2.Flushers+1, after.V2.Flushers)
+	s.Equal(after.V1.Flushers+after.V2.Flushers, after.Total.Flushers)
+
+	LogtailConfigLock.RLock()
+	config := LogtailConfig["inventory_v1_config"]
+	LogtailConfigLock.RUnlock()
+	s.Eventually(func() bool {
+		return config.RecordCounters().InputRecords > 0
+	}, time.Second*5, time.Millisecond*10)
+	s.NoError(Stop("inventory_v1_config", true))
+	s.NoError(Stop("inventory_v2_config", true))
+	s.Equal(before, PluginInventory())
+}
+
+func (s *managerTestSuite) TestStartTwice() {
+	s.NoError(LoadAndStartMockConfig(), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	s.ErrorIs(Start("test_config"), ErrAlreadyStarted)
+	s.ErrorIs(Start("not_loaded_config"), ErrConfigMismatch)
+	s.NoError(Stop("test_config", true))
+}
+
+func (s *managerTestSuite) TestPostStartHook() {
+	type started struct {
+		withInput  bool
+		running    bool
+		lockIsFree bool
+	}
+	startedConfigs := make(chan started, 10)
+	RegisterPostStartHook(func(configName string, withInput bool) {
+		if configName != "hook_config" {
+			return
+		}
+		lockIsFree := LogtailConfigLock.TryLock()
+		if lockIsFree {
+			LogtailConfigLock.Unlock()
+		}
+		LogtailConfigLock.RLock()
+		_, running := LogtailConfig[configName]
+		LogtailConfigLock.RUnlock()
+		startedConfigs <- started{withInput: withInput, running: running, lockIsFree: lockIsFree}
+	})
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "hook_config", `{"flushers": [{"type": "flusher_checker"}]}`), "got err when logad config")
+	s.Equal(started{withInput: false, running: true, lockIsFree: true}, <-startedConfigs)
+	s.NoError(Stop("hook_config", true))
+}
+
+var countInputCollects atomic.Int64
+
+// countInput is collected once an hour, and counts collects in countInputCollects.
+type countInput struct{}
+
+func (r *countInput) Init(context pipeline.Context) (int, error) {
+	return int(time.Hour / time.Millisecond), nil
+}
+
+func (r *countInput) Description() string {
+	return "input which counts collects for test"
+}
+
+func (r *countInput) Collect(collector pipeline.Collector) error {
+	countInputCollects.Add(1)
+	return nil
+}
+
+func init() {
+	pipeline.MetricInputs["metric_count_test"] = func() pipeline.MetricInput {
+		return &countInput{}
+	}
+}
+
+func (s *managerTestSuite) TestForceCollectOnce() {
+	s.Error(ForceCollectOnce(nil))
+	countConfig := `{"global": {"InputMaxFirstCollectDelayMs": 1}, "inputs": [{"type": "metric_count_test"}], "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "count_config", countConfig), "got err when logad config")
+	LogtailConfigLock.RLock()
+	config := LogtailConfig["count_config"]
+	LogtailConfigLock.RUnlock()
+	s.Eventually(func() bool {
+		return countInputCollects.Load() == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	s.NoError(ForceCollectOnce(config))
+	s.Equal(int64(2), countInputCollects.Load())
+	LogtailConfigLock.RLock()
+	s.Contains(LogtailConfig, "count_config")
+	LogtailConfigLock.RUnlock()
+	s.NoError(Stop("count_config", true))
+}
+
+func (s *managerTestSuite) TestStartDuplicateConfig() {
+	inputConfig := `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 10, "Fields": {"content": "test"}}}], "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "dup_config/1", inputConfig), "got err when logad config")
+	time.Sleep(time.Millisecond * time.Duration(100))
+	LogtailConfigLock.RLock()
+	running := LogtailConfig["dup_config/1"]
+	LogtailConfigLock.RUnlock()
+
+	s.NoError(LoadLogstoreConfig("other_prj", "other_logstore", "dup_config", -1, inputConfig))
+	err := Start("dup_config")
+	s.ErrorIs(err, ErrDuplicateConfig)
+	s.Contains(err.Error(), "test_prj")
+	s.NoError(UnloadPartiallyLoadedConfig("dup_config"))
+	LogtailConfigLock.RLock()
+	s.Equal(running, LogtailConfig["dup_config/1"])
+	s.NotContains(LogtailConfig, "dup_config")
+	LogtailConfigLock.RUnlock()
+
+	// the part of the config without input runs in another pipeline
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "dup_config/2", `{"flushers": [{"type": "flusher_checker"}]}`), "got err when logad config")
+	s.NoError(Stop("dup_config/2", true))
+	s.NoError(Stop("dup_config/1", true))
+}
+
+// stoppedRecordFlusher counts the instances stopped in stoppedRecordFlushers.
+type stoppedRecordFlusher struct {
+	hangFlusher
+}
+
+var stoppedRecordFlushers atomic.Int64
+
+func (f *stoppedRecordFlusher) Stop() error {
+	stoppedRecordFlushers.Add(1)
+	return nil
+}
+
+func init() {
+	pipeline.Flushers["flusher_stopped_record_test"] = func() pipeline.Flusher {
+		return &stoppedRecordFlusher{}
+	}
+}
+
+func (s *managerTestSuite) TestForceSelfCollectFlusher() {
+	stopBuiltinConfigs()
+	s.NoError(RegisterBuiltinConfig("force_collect", "test_prj", "test_logstore", "force_collect_builtin",
+		`{"inputs": [{"type": "metric_count_test"}], "flushers": [{"type": "flusher_checker"}]}`))
+	*flags.ForceSelfCollect = true
+	*ForceSelfCollectFlusher = "flusher_stopped_record_test"
+	defer func() {
+		builtinConfigLock.Lock()
+		builtinConfigs = builtinConfigs[:len(builtinConfigs)-1]
+		builtinConfigLock.Unlock()
+		*flags.ForceSelfCollect = false
+		*ForceSelfCollectFlusher = ""
+	}()
+	s.NoError(Init(context.Background(), false))
+	collects := countInputCollects.Load()
+	stopped := stoppedRecordFlushers.Load()
+	stopBuiltinConfigs()
+	s.Equal(collects+1, countInputCollects.Load())
+	// one override flusher for each built-in config
+	s.Equal(stopped+3, stoppedRecordFlushers.Load())
+}
+
+// closerFlusher records OnAgentShutdown calls in closedFlushers, and blocks in it while closerFlusherBlock is set,
+// or until closerFlusherHold is closed if it is not nil, ignoring ctx.
+type closerFlusher struct {
+	hangFlusher
+}
+
+var closedFlushers atomic.Int64
+var closerFlusherBlock atomic.Bool
+var closerFlusherHold chan struct{}
+
+func (f *closerFlusher) Stop() error {
+	return nil
+}
+
+func (f *closerFlusher) OnAgentShutdown(ctx context.Context) error {
+	if closerFlusherHold != nil {
+		<-closerFlusherHold
+		return nil
+	}
+	if closerFlusherBlock.Load() {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	closedFlushers.Add(1)
+	return nil
+}
+
+func init() {
+	pipeline.Flushers["flusher_closer_test"] = func() pipeline.Flusher {
+		return &closerFlusher{}
+	}
+}
+
+func (s *managerTestSuite) TestShutdownHook() {
+	closerConfig := `{"flushers": [{"type": "flusher_closer_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "closer_config", closerConfig), "got err when logad config")
+	closed := closedFlushers.Load()
+	// configs stopped one by one are not shut down
+	s.NoError(Stop("closer_config", true))
+	s.Equal(closed, closedFlushers.Load())
+
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "closer_config", closerConfig), "got err when logad config")
+	s.NoError(StopAllPipelines(false))
+	s.Equal(closed+1, closedFlushers.Load())
+
+	// hooks are abandoned after ShutdownHookTimeoutMs
+	originalTimeout := *ShutdownHookTimeoutMs
+	*ShutdownHookTimeoutMs = 200
+	closerFlusherBlock.Store(true)
+	defer func() {
+		*ShutdownHookTimeoutMs = originalTimeout
+		closerFlusherBlock.Store(false)
+	}()
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "closer_config", closerConfig), "got err when logad config")
+	begin := time.Now()
+	s.NoError(StopAllPipelines(false))
+	s.Less(time.Since(begin), time.Second)
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "closer_config")
+	LogtailConfigLock.RUnlock()
+}
+
+func (s *managerTestSuite) TestShutdownHookOutlivesTimeout() {
+	originalTimeout := *ShutdownHookTimeoutMs
+	*ShutdownHookTimeoutMs = 200
+	closerFlusherHold = make(chan struct{})
+	defer func() {
+		*ShutdownHookTimeoutMs = originalTimeout
+		closerFlusherHold = nil
+	}()
+	closerConfig := `{"flushers": [{"type": "flusher_closer_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "closer_hold_config", closerConfig))
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "closer_skipped_config", closerConfig))
+	LogtailConfigLock.RLock()
+	configs := []*LogstoreConfig{LogtailConfig["closer_hold_config"], LogtailConfig["closer_skipped_config"]}
+	LogtailConfigLock.RUnlock()
+
+	// the configs are kept in place while a hook runs after the timeout, and the hooks not called are skipped
+	begin := time.Now()
+	s.NoError(StopAllPipelines(false))
+	s.Less(time.Since(begin), time.Second)
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "closer_hold_config")
+	s.NotContains(LogtailConfig, "closer_skipped_config")
+	LogtailConfigLock.RUnlock()
+	// the first config is held by its hook, so is the second one after it
+	s.False(configs[0].deleted.Load())
+	s.False(configs[1].deleted.Load())
+
+	close(closerFlusherHold)
+	s.Eventually(func() bool {
+		return configs[0].deleted.Load() && configs[1].deleted.Load()
+	}, time.Second*5, time.Millisecond*10)
+}
+
+// shutdownRecordFlusher raises an alarm of its config when stopped if AlarmOnStop is set, and records the
+// alarms it flushes and its stop in shutdownEvents otherwise.
+type shutdownRecordFlusher struct {
+	hangFlusher
+	context     pipeline.Context
+	AlarmOnStop bool
+}
+
+var shutdownEventsLock sync.Mutex
+var shutdownEvents []string
+
+func recordShutdownEvent(event string) {
+	shutdownEventsLock.Lock()
+	shutdownEvents = append(shutdownEvents, event)
+	shutdownEventsLock.Unlock()
+}
+
+func (f *shutdownRecordFlusher) Init(ctx pipeline.Context) error {
+	f.context = ctx
+	return nil
+}
+
+func (f *shutdownRecordFlusher) Flush(projectName string, logstoreName string, configName string, logGroupList []*protocol.LogGroup) error {
+	for _, logGroup := range logGroupList {
+		for _, log := range logGroup.Logs {
+			for _, content := range log.Contents {
+				if content.Key == "alarm_type" {
+					recordShutdownEvent("alarm " + content.Value)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (f *shutdownRecordFlusher) Stop() error {
+	if f.AlarmOnStop {
+		f.context.GetRuntimeContext().Value(pkg.LogTailMeta).(*pkg.LogtailContextMeta).RecordAlarm("SHUTDOWN_TEST_ALARM", "stopped")
+	} else {
+		recordShutdownEvent("stop")
+	}
+	return nil
+}
+
+func init() {
+	pipeline.Flushers["flusher_shutdown_record_test"] = func() pipeline.Flusher {
+		return &shutdownRecordFlusher{}
+	}
+}
+
+func (s *managerTestSuite) TestShutdown() {
+	stopBuiltinConfigs()
+	overridePath := filepath.Join(s.T().TempDir(), "alarm.json")
+	*AlarmConfigOverridePath = overridePath
+	defer func() {
+		*AlarmConfigOverridePath = ""
+	}()
+	s.NoError(os.WriteFile(overridePath, []byte(`{"global": {"InputIntervalMs": 3600000},
+		"inputs": [{"type": "metric_alarm"}], "flushers": [{"type": "flusher_shutdown_record_test"}]}`), 0600))
+	s.NoError(Init(context.Background(), false))
+	StartBuiltInModulesConfig()
+	shutdownEventsLock.Lock()
+	shutdownEvents = nil
+	shutdownEventsLock.Unlock()
+
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "shutdown_config",
+		`{"flushers": [{"type": "flusher_shutdown_record_test", "detail": {"AlarmOnStop": true}}]}`), "got err when logad config")
+	s.NoError(Shutdown(context.Background()))
+	s.Nil(AlarmConfig)
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "shutdown_config")
+	LogtailConfigLock.RUnlock()
+
+	// the alarm raised by the user config while stopping is flushed before AlarmConfig stops
+	shutdownEventsLock.Lock()
+	defer shutdownEventsLock.Unlock()
+	s.Contains(shutdownEvents, "alarm SHUTDOWN_TEST_ALARM")
+	s.Equal("stop", shutdownEvents[len(shutdownEvents)-1])
+}
+
+func (s *managerTestSuite) TestQuarantineAfterRestart() {
+	MkdirDataDir()
+	s.NoError(CheckPointManager.Init())
+	hung := &LogstoreConfig{ConfigNameWithSuffix: "quarantine_config", generation: -1}
+	quarantineLogstoreConfig(hung, ConfigHealthReasonStopTimeout)
+	// the agent exits before the config stops
+	DisabledLogtailConfigLock.Lock()
+	delete(DisabledLogtailConfig, hung.generation)
+	DisabledLogtailConfigLock.Unlock()
+
+	s.NoError(Init(context.Background(), false))
+	var quarantined *QuarantinedConfig
+	for _, config := range QuarantinedConfigs() {
+		if config.ConfigNameWithSuffix == "quarantine_config" {
+			quarantined = &config
+		}
+	}
+	s.Require().NotNil(quarantined)
+	s.Equal(ConfigHealthReasonStopTimeout, quarantined.Reason)
+	s.True(quarantined.Until.After(time.Now()))
+
+	config := `{"flushers": [{"type": "flusher_checker"}]}`
+	s.ErrorIs(LoadAndStartMockConfig("test_prj", "test_logstore", "quarantine_config", config), ErrConfigQuarantined)
+	s.Contains(DeferredConfigs(), "quarantine_config")
+	// the deferred config is started once the quarantine is cleared
+	s.True(ClearQuarantine("quarantine_config"))
+	s.False(ClearQuarantine("quarantine_config"))
+	s.NotContains(DeferredConfigs(), "quarantine_config")
+	LogtailConfigLock.RLock()
+	s.Contains(LogtailConfig, "quarantine_config")
+	LogtailConfigLock.RUnlock()
+	s.NoError(Stop("quarantine_config", true))
+
+	// the quarantine is not persisted any more
+	s.NoError(Init(context.Background(), false))
+	for _, config := range QuarantinedConfigs() {
+		s.NotEqual("quarantine_config", config.ConfigNameWithSuffix)
+	}
+}
+
+func (s *managerTestSuite) TestQuarantineBackoffOver() {
+	MkdirDataDir()
+	s.NoError(CheckPointManager.Init())
+	originalBackoff := *QuarantineBackoffSec
+	*QuarantineBackoffSec = 1
+	defer func() {
+		*QuarantineBackoffSec = originalBackoff
+	}()
+	hung := &LogstoreConfig{ConfigNameWithSuffix: "backoff_config", generation: -1}
+	quarantineLogstoreConfig(hung, ConfigHealthReasonStopTimeout)
+	DisabledLogtailConfigLock.Lock()
+	delete(DisabledLogtailConfig, hung.generation)
+	DisabledLogtailConfigLock.Unlock()
+	s.NoError(Init(context.Background(), false))
+
+	config := `{"flushers": [{"type": "flusher_checker"}]}`
+	s.ErrorIs(LoadAndStartMockConfig("test_prj", "test_logstore", "backoff_config", config), ErrConfigQuarantined)
+	// the config is started once the backoff is over, and the quarantine is cleared
+	s.Eventually(func() bool {
+		LogtailConfigLock.RLock()
+		defer LogtailConfigLock.RUnlock()
+		_, exists := LogtailConfig["backoff_config"]
+		return exists
+	}, time.Second*5, time.Millisecond*100)
+	s.False(ClearQuarantine("backoff_config"))
+	s.NoError(Stop("backoff_config", true))
+}
+
+func (s *managerTestSuite) TestShutdownStopTimeoutNotQuarantined() {
+	MkdirDataDir()
+	s.NoError(CheckPointManager.Init())
+	hangFlusherRelease = make(chan struct{})
+	originalTimeout := configStopTimeout
+	configStopTimeout = time.Millisecond * time.Duration(500)
+	defer func() {
+		configStopTimeout = originalTimeout
+	}()
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "shutdown_hang_config", `{"flushers": [{"type": "flusher_hang_test"}]}`))
+	LogtailConfigLock.RLock()
+	generation := LogtailConfig["shutdown_hang_config"].Generation()
+	LogtailConfigLock.RUnlock()
+	isDisabled := func(generation int64) bool {
+		DisabledLogtailConfigLock.RLock()
+		defer DisabledLogtailConfigLock.RUnlock()
+		_, exists := DisabledLogtailConfig[generation]
+		return exists
+	}
+	s.NoError(StopAllPipelines(false))
+	s.True(isDisabled(generation))
+	_, err := CheckPointManager.GetCheckpoint(quarantineCheckpointName, "shutdown_hang_config")
+	s.ErrorIs(err, leveldb.ErrNotFound)
+
+	close(hangFlusherRelease)
+	s.Eventually(func() bool {
+		return !isDisabled(generation)
+	}, time.Second*5, time.Millisecond*100)
+}
+
+func (s *managerTestSuite) TestSubscribeLifecycle() {
+	events := SubscribeLifecycle()
+	defer UnsubscribeLifecycle(events)
+	nextKind := func() LifecycleEventKind {
+		for {
+			select {
+			case event := <-events:
+				if event.ConfigName == "lifecycle_config" {
+					s.False(event.Timestamp.IsZero())
+					return event.Kind
+				}
+			case <-time.After(time.Second * 5):
+				s.Fail("no lifecycle event received")
+				return -1
+			}
+		}
+	}
+	config := `{"flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "lifecycle_config", config))
+	s.Equal(LifecycleStarted, nextKind())
+	s.NoError(Stop("lifecycle_config", true))
+	s.Equal(LifecycleStopped, nextKind())
+
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "lifecycle_config", config))
+	s.Equal(LifecycleStarted, nextKind())
+	s.NoError(StopAllPipelinesWithDrain(false, time.Second))
+	s.Equal(LifecycleDrained, nextKind())
+	s.Equal(LifecycleStopped, nextKind())
+}
+
+func (s *managerTestSuite) TestConfigEventLog() {
+	hangFlusherRelease = make(chan struct{})
+	originalTimeout := configStopTimeout
+	configStopTimeout = time.Millisecond * time.Duration(500)
+	defer func() {
+		configStopTimeout = originalTimeout
+		ClearQuarantine("event_log_config")
+	}()
+	kinds := func(limit int) []ConfigEventKind {
+		kinds := make([]ConfigEventKind, 0)
+		for _, event := range ConfigEventLog("event_log_config", limit) {
+			s.False(event.Timestamp.IsZero())
+			kinds = append(kinds, event.Kind)
+		}
+		return kinds
+	}
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "event_log_config", `{"flushers": [{"type": "flusher_checker"}]}`))
+	s.NoError(Stop("event_log_config", true))
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "event_log_config", `{"flushers": [{"type": "flusher_hang_test"}]}`))
+	s.NoError(Stop("event_log_config", true))
+	close(hangFlusherRelease)
+	s.Eventually(func() bool {
+		return len(kinds(0)) == 7
+	}, time.Second*5, time.Millisecond*100)
+	s.Equal([]ConfigEventKind{ConfigEventLoaded, ConfigEventStarted, ConfigEventStopped,
+		ConfigEventLoaded, ConfigEventStarted, ConfigEventDisabled, ConfigEventRecovered}, kinds(0))
+	s.Equal([]ConfigEventKind{ConfigEventDisabled, ConfigEventRecovered}, kinds(2))
+	s.Empty(ConfigEventLog("not_exist_config", 0))
+}
+
+func (s *managerTestSuite) TestDeleteLogstoreConfigTwice() {
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "delete_twice_config", 0, `{"flushers": [{"type": "flusher_checker"}]}`))
+	config := ToStartPipelineConfigWithoutInput
+	ToStartPipelineConfigWithoutInput = nil
+	s.Require().NotNil(config)
+	s.NotPanics(func() {
+		DeleteLogstoreConfig(config, true)
+		DeleteLogstoreConfig(config, true)
+		DeleteLogstoreConfig(config, false)
+		DeleteLogstoreConfig(nil, true)
+	})
+	s.Nil(config.PluginRunner)
+	s.Nil(config.Context)
+}
+
+func (s *managerTestSuite) TestDeleteLogstoreConfigConcurrently() {
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "delete_concurrently_config", 0, `{"flushers": [{"type": "flusher_checker"}]}`))
+	config := ToStartPipelineConfigWithoutInput
+	ToStartPipelineConfigWithoutInput = nil
+	s.Require().NotNil(config)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(removedFlag bool) {
+			defer wg.Done()
+			defer func() {
+				s.Nil(recover())
+			}()
+			<-start
+			DeleteLogstoreConfig(config, removedFlag)
+		}(i%2 == 0)
+	}
+	close(start)
+	wg.Wait()
+	s.Nil(config.PluginRunner)
+	s.Nil(config.Context)
+}
+
+func (s *managerTestSuite) TestRangeConfigs() {
+	config := `{"flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "range_a", config))
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "range_b", config))
+	names := make(map[string]bool)
+	RangeConfigs(func(name string, cfg *LogstoreConfig) bool {
+		s.Equal(name, cfg.ConfigNameWithSuffix)
+		names[name] = true
+		return true
+	})
+	s.True(names["range_a"])
+	s.True(names["range_b"])
+
+	visited := 0
+	RangeConfigs(func(name string, cfg *LogstoreConfig) bool {
+		visited++
+		return false
+	})
+	s.Equal(1, visited)
+	s.NoError(Stop("range_a", true))
+	s.NoError(Stop("range_b", true))
+}
+
+func (s *managerTestSuite) TestApplyConfigBatch() {
+	config := `{"flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "batch_a", config))
+	LogtailConfigLock.RLock()
+	oldConfig := LogtailConfig["batch_a"]
+	LogtailConfigLock.RUnlock()
+
+	// the configs built in a failed batch are released, and their flushers are stopped
+	stopped := stoppedRecordFlushers.Load()
+	err := ApplyConfigBatch(map[string]string{
+		"batch_a": config,
+		"batch_b": `{"inputs": [{"type": "unknown_input"}], "flushers": [{"type": "flusher_checker"}]}`,
+		"batch_c": `{`,
+		"batch_d": `{"flushers": [{"type": "flusher_stopped_record_test"}]}`,
+	})
+	s.ErrorIs(err, ErrInvalidConfigBatch)
+	s.Equal(stopped+1, stoppedRecordFlushers.Load())
+	s.Contains(err.Error(), "batch_b")
+	s.Contains(err.Error(), "batch_c")
+	s.NotContains(err.Error(), "batch_a")
+	LogtailConfigLock.RLock()
+	s.Equal(oldConfig, LogtailConfig["batch_a"])
+	s.NotContains(LogtailConfig, "batch_b")
+	s.NotContains(LogtailConfig, "batch_c")
+	s.NotContains(LogtailConfig, "batch_d")
+	LogtailConfigLock.RUnlock()
+
+	s.NoError(ApplyConfigBatch(map[string]string{"batch_a": config, "batch_b": config}))
+	LogtailConfigLock.RLock()
+	newConfig := LogtailConfig["batch_a"]
+	s.Contains(LogtailConfig, "batch_b")
+	LogtailConfigLock.RUnlock()
+	s.NotEqual(oldConfig, newConfig)
+	s.Equal("test_prj", newConfig.ProjectName)
+	s.Equal("test_logstore", newConfig.LogstoreName)
+	s.NoError(Stop("batch_a", true))
+	s.NoError(Stop("batch_b", true))
+}
+
+// hangStartRunner blocks Run until release is closed.
+type hangStartRunner struct {
+	PluginRunner
+	release chan struct{}
+}
+
+func (r *hangStartRunner) Run() {
+	<-r.release
+	r.PluginRunner.Run()
+}
+
+func (s *managerTestSuite) TestStartTimeout() {
+	originalTimeout := *ConfigStartTimeoutMs
+	*ConfigStartTimeoutMs = 200
+	defer func() {
+		*ConfigStartTimeoutMs = originalTimeout
+	}()
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "hang_start_config", 0, `{"flushers": [{"type": "flusher_checker"}]}`))
+	config := ToStartPipelineConfigWithoutInput
+	s.Require().NotNil(config)
+	runner := &hangStartRunner{PluginRunner: config.PluginRunner, release: make(chan struct{})}
+	config.PluginRunner = runner
+
+	s.ErrorIs(Start("hang_start_config"), ErrStartTimeout)
+	s.Nil(ToStartPipelineConfigWithoutInput)
+	LogtailConfigLock.RLock()
+	s.NotContains(LogtailConfig, "hang_start_config")
+	LogtailConfigLock.RUnlock()
+
+	// the aborted config is torn down once it starts
+	close(runner.release)
+	s.Eventually(func() bool {
+		return config.Context == nil
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func (s *managerTestSuite) TestBuiltinConfigProjectOverride() {
+	stopBuiltinConfigs()
+	*AlarmProject = "custom_project"
+	*AlarmLogstore = "custom_alarm"
+	*ContainerLogstore = "  "
+	defer func() {
+		*AlarmProject = ""
+		*AlarmLogstore = ""
+		*ContainerLogstore = ""
+	}()
+	s.NoError(Init(context.Background(), false))
+	s.Equal("custom_project", AlarmConfig.ProjectName)
+	s.Equal("custom_alarm", AlarmConfig.LogstoreName)
+	// empty overrides fall back to the built-in names
+	s.Equal("sls-admin", ContainerConfig.ProjectName)
+	s.Equal("logtail_containers", ContainerConfig.LogstoreName)
+	stopBuiltinConfigs()
+}
+
+var slowCollectStarted = make(chan *slowCollectInput, 1)
+
+// slowCollectInput takes 300ms to collect, and records whether the config was stopping when the first
+// collection finished.
+type slowCollectInput struct {
+	config            *LogstoreConfig
+	stoppingAtFinish  atomic.Bool
+	finishedCollected atomic.Bool
+}
+
+func (r *slowCollectInput) Init(context pipeline.Context) (int, error) {
+	return int(time.Hour / time.Millisecond), nil
+}
+
+func (r *slowCollectInput) Description() string {
+	return "input which collects slowly for test"
+}
+
+func (r *slowCollectInput) Collect(collector pipeline.Collector) error {
+	slowCollectStarted <- r
+	time.Sleep(time.Millisecond * 300)
+	stopping := r.config.FlushOutFlag.Load()
+	if r.finishedCollected.CompareAndSwap(false, true) {
+		r.stoppingAtFinish.Store(stopping)
+	}
+	return nil
+}
+
+func init() {
+	pipeline.MetricInputs["metric_slow_collect_test"] = func() pipeline.MetricInput {
+		return &slowCollectInput{}
+	}
+}
+
+func (s *managerTestSuite) TestStopWaitsForCollection() {
+	slowConfig := `{"global": {"InputMaxFirstCollectDelayMs": 1}, "inputs": [{"type": "metric_slow_collect_test"}], "flushers": [{"type": "flusher_checker"}]}`
+	for _, waitTimeoutMs := range []int{0, 2000} {
+		*StopCollectWaitTimeoutMs = waitTimeoutMs
+		s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "slow_collect_config", 0, slowConfig))
+		config := ToStartPipelineConfigWithInput
+		s.Require().NotNil(config)
+		input := config.PluginRunner.(*pluginv1Runner).MetricPlugins[0].Input.(*slowCollectInput)
+		input.config = config
+		s.NoError(Start("slow_collect_config"))
+		<-slowCollectStarted
+		s.NoError(Stop("slow_collect_config", true))
+		s.True(input.finishedCollected.Load())
+		// the config starts stopping only after the collection if Stop waits for it
+		s.Equal(waitTimeoutMs == 0, input.stoppingAtFinish.Load())
+		// the input collects once more when it is cancelled
+		select {
+		case <-slowCollectStarted:
+		default:
+		}
+	}
+	*StopCollectWaitTimeoutMs = 0
+}
+
+// countingFlusher reports fixed delivery counters.
+type countingFlusher struct {
+	hangFlusher
+}
+
+func (f *countingFlusher) Stop() error {
+	return nil
+}
+
+func (f *countingFlusher) FlusherCounters() pipeline.FlusherCounters {
+	return pipeline.FlusherCounters{Sent: 10, Failed: 1, Retried: 2, Dropped: 3}
+}
+
+func init() {
+	pipeline.Flushers["flusher_counting_test"] = func() pipeline.Flusher {
+		return &countingFlusher{}
+	}
+}
+
+func (s *managerTestSuite) TestFlusherStats() {
+	countingConfig := `{"flushers": [{"type": "flusher_counting_test"}, {"type": "flusher_counting_test"}, {"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "counting_config", countingConfig), "got err when logad config")
+	stats := FlusherStats()
+	s.Equal(FlusherMetric{
+		ConfigName:      "counting_config",
+		FlusherType:     "flusher_counting_test",
+		FlusherCounters: pipeline.FlusherCounters{Sent: 20, Failed: 2, Retried: 4, Dropped: 6},
+	}, stats["counting_config:flusher_counting_test"])
+	s.NotContains(stats, "counting_config:flusher_checker")
+	s.NoError(Stop("counting_config", true))
+	s.NotContains(FlusherStats(), "counting_config:flusher_counting_test")
+}
+
+func (s *managerTestSuite) TestPrometheusHandler() {
+	countingConfig := `{"inputs": [{"type": "service_mock", "detail": {"LogsPerSecond": 100, "Fields": {"content": "test"}}}],
+		"flushers": [{"type": "flusher_counting_test"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", `prometheus "config"`, countingConfig), "got err when logad config")
+	LogtailConfigLock.RLock()
+	config := LogtailConfig[`prometheus "config"`]
+	LogtailConfigLock.RUnlock()
+	s.Eventually(func() bool {
+		return config.RecordCounters().InputRecords > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	countPanic("prometheus_test_plugin")
+
+	recorder := httptest.NewRecorder()
+	PrometheusHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	s.Equal(http.StatusOK, recorder.Code)
+	s.Contains(recorder.Header().Get("Content-Type"), "text/plain")
+	body := recorder.Body.String()
+	s.Contains(body, "# TYPE loongcollector_pipeline_input_records_total counter\n")
+	s.Contains(body, `loongcollector_pipeline_input_records_total{config="prometheus \"config\""} `)
+	s.Contains(body, `loongcollector_pipeline_dropped_records_total{config="prometheus \"config\"",stage="rate_limit"} 0`+"\n")
+	s.Contains(body, "# TYPE loongcollector_pipeline_aggregator_queue_depth gauge\n")
+	s.Contains(body, `loongcollector_flusher_sent_total{config="prometheus \"config\"",flusher_type="flusher_counting_test"} 10`+"\n")
+	s.Contains(body, `loongcollector_flusher_failed_total{config="prometheus \"config\"",flusher_type="flusher_counting_test"} 1`+"\n")
+	s.Regexp(`\nloongcollector_disabled_configs [0-9]+\n`, body)
+	s.Regexp(`loongcollector_panics_total\{plugin_type="prometheus_test_plugin"\} [1-9]`, body)
+	s.NoError(Stop(`prometheus "config"`, true))
+}
+
+// reconfigFlusher applies BatchSize of the detail given to Reconfigure.
+type reconfigFlusher struct {
+	hangFlusher
+	BatchSize int
+	lock      sync.Mutex
+}
+
+func (f *reconfigFlusher) Stop() error {
+	return nil
+}
+
+func (f *reconfigFlusher) Reconfigure(detail map[string]interface{}) error {
+	batchSize, ok := detail["BatchSize"].(float64)
+	if !ok || batchSize <= 0 {
+		return fmt.Errorf("invalid BatchSize %v", detail["BatchSize"])
+	}
+	f.lock.Lock()
+	f.BatchSize = int(batchSize)
+	f.lock.Unlock()
+	return nil
+}
+
+func (f *reconfigFlusher) batchSize() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.BatchSize
+}
+
+func init() {
+	pipeline.Flushers["flusher_reconfig_test"] = func() pipeline.Flusher {
+		return &reconfigFlusher{}
+	}
+}
+
+func (s *managerTestSuite) TestUpdatePluginDetail() {
+	reconfigConfig := `{"flushers": [{"type": "flusher_reconfig_test/5", "detail": {"BatchSize": 10}}, {"type": "flusher_checker/6"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "reconfig_config", reconfigConfig), "got err when logad config")
+	LogtailConfigLock.RLock()
+	config := LogtailConfig["reconfig_config"]
+	LogtailConfigLock.RUnlock()
+	var flusher *reconfigFlusher
+	config.PluginRunner.ForEachPlugin(func(plugin Plugin) {
+		if f, ok := plugin.pluginInstance().(*reconfigFlusher); ok {
+			flusher = f
+		}
+	})
+	s.Require().NotNil(flusher)
+	s.Equal(10, flusher.batchSize())
+
+	s.NoError(UpdatePluginDetail("reconfig_config", "flusher_reconfig_test", "5", map[string]interface{}{"BatchSize": float64(100)}))
+	s.Equal(100, flusher.batchSize())
+	// the config keeps running with the same flusher
+	LogtailConfigLock.RLock()
+	s.Equal(config, LogtailConfig["reconfig_config"])
+	LogtailConfigLock.RUnlock()
+
+	// invalid detail is rejected and the previous settings are kept
+	s.Error(UpdatePluginDetail("reconfig_config", "flusher_reconfig_test", "5", map[string]interface{}{"BatchSize": "large"}))
+	s.Equal(100, flusher.batchSize())
+
+	s.ErrorIs(UpdatePluginDetail("reconfig_config", "flusher_checker", "6", map[string]interface{}{}), ErrReconfigUnsupported)
+	s.ErrorIs(UpdatePluginDetail("reconfig_config", "flusher_reconfig_test", "6", map[string]interface{}{}), ErrPluginNotFound)
+	s.ErrorIs(UpdatePluginDetail("not_exist_config", "flusher_reconfig_test", "5", map[string]interface{}{}), ErrConfigNotFound)
+	s.NoError(Stop("reconfig_config", true))
+}
+
+func (s *managerTestSuite) TestBackpressure() {
+	backpressureConfig := `{"global": {"BackpressureWatermarkPercent": 50}, "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadLogstoreConfig("test_prj", "test_logstore", "backpressure_config", 0, backpressureConfig))
+	config := ToStartPipelineConfigWithoutInput
+	ToStartPipelineConfigWithoutInput = nil
+	s.Require().NotNil(config)
+	defer cancelRuntimeContext(config)
+	runner := config.PluginRunner.(*pluginv1Runner)
+	var collects atomic.Int32
+	collect := func() error {
+		collects.Add(1)
+		return nil
+	}
+
+	s.False(runner.ShouldPause())
+	s.NoError(config.collect(collect))
+	s.Equal(int32(1), collects.Load())
+	for i := 0; i*100 < cap(runner.LogGroupsChan)*50; i++ {
+		runner.LogGroupsChan <- &protocol.LogGroup{}
+	}
+	s.True(runner.ShouldPause())
+	go func() {
+		_ = config.collect(collect)
+	}()
+	time.Sleep(inputPausedCheckInterval * 3)
+	s.Equal(int32(1), collects.Load(), "collection should be delayed under backpressure")
+	for len(runner.LogGroupsChan) > 0 {
+		<-runner.LogGroupsChan
+	}
+	s.Eventually(func() bool {
+		return collects.Load() == 2
+	}, time.Second*5, inputPausedCheckInterval)
+
+	// inputs are not paused while the config is stopping
+	for i := 0; i*100 < cap(runner.LogGroupsChan)*50; i++ {
+		runner.LogGroupsChan <- &protocol.LogGroup{}
+	}
+	config.FlushOutFlag.Store(true)
+	s.False(config.underBackpressure())
+	s.NoError(config.collect(collect))
+	s.Equal(int32(3), collects.Load())
+
+	config.GlobalConfig.BackpressureWatermarkPercent = 0
+	s.False(runner.ShouldPause())
+}
+
+// hangCollectInput hangs in Collect until hangCollectRelease is closed.
+type hangCollectInput struct{}
+
+var hangCollectRelease chan struct{}
+
+func (r *hangCollectInput) Init(context pipeline.Context) (int, error) {
+	return int(time.Hour / time.Millisecond), nil
+}
+
+func (r *hangCollectInput) Description() string {
+	return "input which hangs when collect for test"
+}
+
+func (r *hangCollectInput) Collect(collector pipeline.Collector) error {
+	<-hangCollectRelease
+	return nil
+}
+
+func init() {
+	pipeline.MetricInputs["metric_hang_collect_test"] = func() pipeline.MetricInput {
+		return &hangCollectInput{}
+	}
+}
+
+func (s *managerTestSuite) TestLeakedGoroutines() {
+	hangCollectRelease = make(chan struct{})
+	originalTimeout := configStopTimeout
+	configStopTimeout = time.Millisecond * time.Duration(500)
+	defer func() {
+		configStopTimeout = originalTimeout
+		ClearQuarantine("hang_collect_config")
+	}()
+	hangConfig := `{"global": {"InputMaxFirstCollectDelayMs": 1}, "inputs": [{"type": "metric_hang_collect_test"}], "flushers": [{"type": "flusher_checker"}]}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "hang_collect_config", hangConfig), "got err when logad config")
+	LogtailConfigLock.RLock()
+	config := LogtailConfig["hang_collect_config"]
+	LogtailConfigLock.RUnlock()
+	s.Require().NotNil(config)
+	s.Greater(config.spawnedGoroutines.Load(), int64(0))
+
+	s.NoError(Stop("hang_collect_config", true))
+	// stopping hangs on the input, so none of the goroutines of the config has exited
+	spawned := config.spawnedGoroutines.Load()
+	s.Equal(spawned, runningGoroutines(config.PluginRunner))
+	// disabled configs left by other tests may count in the total
+	s.GreaterOrEqual(LeakedGoroutines(), spawned)
+	leaked, err := strconv.ParseInt(GetAgentStat()[0][selfmonitor.MetricAgentGoRoutinesLeaked], 10, 64)
+	s.NoError(err)
+	s.GreaterOrEqual(leaked, spawned)
+
+	close(hangCollectRelease)
+	s.Eventually(func() bool {
+		DisabledLogtailConfigLock.RLock()
+		defer DisabledLogtailConfigLock.RUnlock()
+		_, exists := DisabledLogtailConfig[config.Generation()]
+		return !exists && runningGoroutines(config.PluginRunner) == 0
+	}, time.Second*5, time.Millisecond*100)
+}
@@ -15,6 +15,8 @@
 package pluginmanager
 
 import (
+	"sort"
+
 	"github.com/alibaba/ilogtail/pkg/pipeline"
 )
 
@@ -37,6 +39,69 @@ const (
 	tagKeyLogTopic = "__log_topic__"
 )
 
+// Plugin is a plugin held by a PluginRunner, together with its wrapper.
+type Plugin interface {
+	Category() pluginCategory
+
+	PluginType() string
+
+	// PluginID returns the id of the plugin in the config, such as "1" of "flusher_sls/1".
+	PluginID() string
+
+	// pluginInstance returns the plugin wrapped.
+	pluginInstance() interface{}
+
+	// detachConfig drops the reference to the config when it is deleted.
+	detachConfig()
+}
+
+// stopOrder is the order in which runners stop plugins, so that upstream stages quiesce and pass their data
+// on before downstream ones are closed. Service inputs are asked to stop before waiting for all inputs.
+var stopOrder = []pluginCategory{
+	pluginServiceInput,
+	pluginMetricInput,
+	pluginProcessor,
+	pluginAggregator,
+	pluginFlusher,
+	pluginExtension,
+}
+
+// stopPriority returns the priority the plugin instance declares with pipeline.StopPrioritizer, or 0.
+func stopPriority(instance interface{}) int {
+	if prioritizer, ok := instance.(pipeline.StopPrioritizer); ok {
+		return prioritizer.StopPriority()
+	}
+	return 0
+}
+
+// sortByStopPriority returns the plugins of a category in the order to stop them, by ascending stop priority,
+// and in the order of the config for the same priority.
+func sortByStopPriority[T Plugin](plugins []T) []T {
+	sorted := make([]T, len(plugins))
+	copy(sorted, plugins)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return stopPriority(sorted[i].pluginInstance()) < stopPriority(sorted[j].pluginInstance())
+	})
+	return sorted
+}
+
+// sortExtensionsByStopPriority returns the names of the extensions in the order to stop them, by ascending
+// stop priority, and by name for the same priority.
+func sortExtensionsByStopPriority(extensions map[string]pipeline.Extension) []string {
+	names := make([]string, 0, len(extensions))
+	for name := range extensions {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		pi, pj := stopPriority(extensions[names[i]]), stopPriority(extensions[names[j]])
+		if pi != pj {
+			return pi < pj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
 type PluginRunner interface {
 	Init(inputQueueSize int, aggrQueueSize int) error
 
@@ -61,4 +126,12 @@ type PluginRunner interface {
 	Stop(exit bool) error
 
 	IsWithInputPlugin() bool
+
+	// ForEachPlugin calls fn for the inputs, processors, aggregators and flushers in the order
+	// data passes them. Extensions are not included.
+	ForEachPlugin(fn func(p Plugin))
+
+	// ShouldPause returns true if the flush queue is above BackpressureWatermarkPercent of the config,
+	// inputs should stop collecting until it returns false.
+	ShouldPause() bool
 }
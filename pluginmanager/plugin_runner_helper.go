@@ -18,10 +18,13 @@ import (
 	"fmt"
 	"math/rand"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
 	"github.com/alibaba/ilogtail/pkg/util"
 )
 
@@ -30,11 +33,14 @@ type timerRunner struct {
 	interval        time.Duration
 	context         pipeline.Context
 	state           interface{}
+	config          *LogstoreConfig
+	// paused skips the task while set, it is nil if the task can not be paused.
+	paused *atomic.Bool
 }
 
 func (p *timerRunner) Run(task func(state interface{}) error, cc *pipeline.AsyncControl) {
 	logger.Info(p.context.GetRuntimeContext(), "task run", "start", "interval", p.interval, "max delay", p.initialMaxDelay, "state", fmt.Sprintf("%T", p.state))
-	defer panicRecover(fmt.Sprint(p.state))
+	defer configPanicRecover(p.config, fmt.Sprint(p.state))
 
 	exitFlag := false
 	if p.initialMaxDelay > 0 {
@@ -57,6 +63,9 @@ func (p *timerRunner) Run(task func(state interface{}) error, cc *pipeline.Async
 }
 
 func (p *timerRunner) execTask(task func(state interface{}) error) {
+	if p.paused != nil && p.paused.Load() {
+		return
+	}
 	if err := task(p.state); err != nil {
 		logger.Error(p.context.GetRuntimeContext(), "PLUGIN_RUN_ALARM", "task run", "error", err, "plugin", "state", fmt.Sprintf("%T", p.state))
 	}
@@ -80,6 +89,31 @@ func flushOutStore[T FlushData, F FlusherWrapperInterface](lc *LogstoreConfig, s
 	return true
 }
 
+// flushRemovedBuffer tries once more to flush data left in the buffer of a removed config,
+// which failed to be flushed out when the config stopped and will be dropped on deletion.
+func flushRemovedBuffer(lc *LogstoreConfig) {
+	count := GetFlushStoreLen(lc.PluginRunner)
+	if count == 0 {
+		return
+	}
+	flushed := false
+	switch runner := lc.PluginRunner.(type) {
+	case *pluginv1Runner:
+		flushed = flushOutStore(lc, runner.FlushOutStore, runner.FlusherPlugins, func(lc *LogstoreConfig, sf *FlusherWrapperV1, store *FlushOutStore[protocol.LogGroup]) error {
+			return sf.Flusher.Flush(lc.Context.GetProject(), lc.Context.GetLogstore(), lc.Context.GetConfigName(), store.Get())
+		})
+	case *pluginv2Runner:
+		flushed = flushOutStore(lc, runner.FlushOutStore, runner.FlusherPlugins, func(lc *LogstoreConfig, pf *FlusherWrapperV2, store *FlushOutStore[models.PipelineGroupEvents]) error {
+			return pf.Export(store.Get(), runner.FlushPipeContext)
+		})
+	}
+	if flushed {
+		logger.Info(lc.Context.GetRuntimeContext(), "flush buffer of removed config, flushed groups", count, "dropped groups", 0)
+	} else {
+		logger.Warning(lc.Context.GetRuntimeContext(), "DROP_DATA_ALARM", "flush buffer of removed config, flushed groups", 0, "dropped groups", count)
+	}
+}
+
 func GetFlushStoreLen(runner PluginRunner) int {
 	if r, ok := runner.(*pluginv1Runner); ok {
 		return r.FlushOutStore.Len()
@@ -100,6 +134,39 @@ func GetFlushCancelToken(runner PluginRunner) <-chan struct{} {
 	return make(<-chan struct{})
 }
 
+// runningGoroutines returns the number of plugin goroutines started by the runner which have not exited.
+func runningGoroutines(runner PluginRunner) int64 {
+	var count int64
+	for _, cc := range runnerControls(runner) {
+		if cc != nil {
+			count += cc.Running()
+		}
+	}
+	return count
+}
+
+// runnerControls returns the controls of the goroutines started by the runner, keyed by the category of
+// plugins they run. Service inputs run in their own goroutines, which are not controlled.
+func runnerControls(runner PluginRunner) map[pluginCategory]*pipeline.AsyncControl {
+	switch r := runner.(type) {
+	case *pluginv1Runner:
+		return map[pluginCategory]*pipeline.AsyncControl{pluginMetricInput: r.InputControl, pluginProcessor: r.ProcessControl,
+			pluginAggregator: r.AggregateControl, pluginFlusher: r.FlushControl}
+	case *pluginv2Runner:
+		return map[pluginCategory]*pipeline.AsyncControl{pluginMetricInput: r.InputControl, pluginProcessor: r.ProcessControl,
+			pluginAggregator: r.AggregateControl, pluginFlusher: r.FlushControl}
+	}
+	return nil
+}
+
+// stopPlugin calls stop of the plugin and records the plugin in the config until stop returns,
+// so that a plugin hanging in Stop can be reported, see unexitedPlugins.
+func stopPlugin(lc *LogstoreConfig, plugin Plugin, stop func() error) error {
+	lc.stoppingPlugin.Store(plugin.PluginType())
+	defer lc.stoppingPlugin.Store("")
+	return stop()
+}
+
 func GetConfigInputs(runner PluginRunner) []pipeline.ServiceInput {
 	inputs := make([]pipeline.ServiceInput, 0)
 	if r, ok := runner.(*pluginv1Runner); ok {
@@ -128,6 +195,21 @@ func GetConfigFlushers(runner PluginRunner) []pipeline.Flusher {
 	return flushers
 }
 
+// checkPermutation checks that order is a permutation of [0, n).
+func checkPermutation(order []int, n int) error {
+	if len(order) != n {
+		return fmt.Errorf("invalid order length %d, expect %d", len(order), n)
+	}
+	seen := make([]bool, n)
+	for _, idx := range order {
+		if idx < 0 || idx >= n || seen[idx] {
+			return fmt.Errorf("invalid order %v, not a permutation of [0, %d)", order, n)
+		}
+		seen[idx] = true
+	}
+	return nil
+}
+
 func pluginUnImplementError(category pluginCategory, version ConfigVersion, pluginType string) error {
 	return fmt.Errorf("plugin does not implement %s%s. pluginType: %s", category, strings.ToUpper(string(version)), pluginType)
 }
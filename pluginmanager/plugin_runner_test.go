@@ -16,11 +16,14 @@ package pluginmanager
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/alibaba/ilogtail/pkg/logger"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -99,3 +102,118 @@ func (s *pluginRunnerTestSuite) TestTimerRunner_WithInitialDelay() {
 	cc.WaitCancel()
 	s.Equal(2, len(ch))
 }
+
+func (s *pluginRunnerTestSuite) TestReorderProcessors() {
+	processors := make([]*ProcessorWrapperV1, 3)
+	for i := range processors {
+		processors[i] = &ProcessorWrapperV1{}
+		processors[i].pluginType = fmt.Sprintf("processor_%d", i)
+	}
+	runner := &pluginv1Runner{ProcessorPlugins: processors}
+	s.NoError(runner.reorderProcessors([]int{2, 0, 1}))
+	s.Equal([]*ProcessorWrapperV1{processors[2], processors[0], processors[1]}, runner.ProcessorPlugins)
+
+	for _, order := range [][]int{{0, 1}, {0, 0, 1}, {0, 1, 3}, {-1, 0, 1}} {
+		s.Error(runner.reorderProcessors(order), order)
+	}
+	s.Equal([]*ProcessorWrapperV1{processors[2], processors[0], processors[1]}, runner.ProcessorPlugins)
+	s.Error(ReorderProcessors("not_exist_config", []int{0}))
+}
+
+func (s *pluginRunnerTestSuite) TestForEachPlugin() {
+	jsonStr := `{"inputs": [{"type": "service_mock"}], "processors": [{"type": "processor_anchor"}], "flushers": [{"type": "flusher_checker"}]}`
+	config, err := buildLogstoreConfig("test_prj", "test_logstore", "for_each_plugin", -1, jsonStr)
+	s.NoError(err)
+	defer cancelRuntimeContext(config)
+	var categories []pluginCategory
+	var pluginTypes []string
+	config.PluginRunner.ForEachPlugin(func(p Plugin) {
+		categories = append(categories, p.Category())
+		pluginTypes = append(pluginTypes, p.PluginType())
+	})
+	s.Equal([]pluginCategory{pluginServiceInput, pluginProcessor, pluginAggregator, pluginFlusher}, categories)
+	s.Equal("service_mock", pluginTypes[0])
+	s.Equal("processor_anchor", pluginTypes[1])
+	s.Equal("flusher_checker", pluginTypes[3])
+
+	runner := config.PluginRunner.(*pluginv1Runner)
+	DeleteLogstoreConfig(config, true)
+	s.Nil(runner.ServicePlugins[0].Config)
+	s.Nil(runner.ProcessorPlugins[0].Config)
+	s.Nil(runner.AggregatorPlugins[0].Config)
+	s.Nil(runner.FlusherPlugins[0].Config)
+}
+
+// toggleFlusher counts the log groups flushed to it, and is ready or fails as set.
+type toggleFlusher struct {
+	hangFlusher
+	ready   bool
+	fail    bool
+	flushed int
+}
+
+func (f *toggleFlusher) IsReady(projectName string, logstoreName string, logstoreKey int64) bool {
+	return f.ready
+}
+
+func (f *toggleFlusher) Flush(projectName string, logstoreName string, configName string, logGroupList []*protocol.LogGroup) error {
+	if f.fail {
+		return errors.New("flush fail")
+	}
+	f.flushed += len(logGroupList)
+	return nil
+}
+
+// fallbackFlusher is a toggleFlusher used as a fallback.
+type fallbackFlusher struct {
+	toggleFlusher
+}
+
+func (f *fallbackFlusher) IsFallback() bool {
+	return true
+}
+
+func init() {
+	pipeline.Flushers["flusher_toggle_test"] = func() pipeline.Flusher {
+		return &toggleFlusher{ready: true}
+	}
+	pipeline.Flushers["flusher_fallback_test"] = func() pipeline.Flusher {
+		return &fallbackFlusher{toggleFlusher{ready: true}}
+	}
+}
+
+func (s *pluginRunnerTestSuite) TestFlushWithFallback() {
+	jsonStr := `{"flushers": [{"type": "flusher_toggle_test"}, {"type": "flusher_fallback_test"}]}`
+	config, err := buildLogstoreConfig("test_prj", "test_logstore", "flush_with_fallback", -1, jsonStr)
+	s.Require().NoError(err)
+	defer cancelRuntimeContext(config)
+	runner := config.PluginRunner.(*pluginv1Runner)
+	primary := runner.FlusherPlugins[0].Flusher.(*toggleFlusher)
+	fallback := runner.FlusherPlugins[1].Flusher.(*fallbackFlusher)
+	logGroups := []*protocol.LogGroup{{Logs: []*protocol.Log{{Time: 1}}}}
+
+	// the fallback flusher receives nothing while the primary one is healthy
+	runner.flushLogGroups(logGroups)
+	s.Equal(1, primary.flushed)
+	s.Equal(0, fallback.flushed)
+
+	// log groups the primary flusher is not ready for go to the fallback flusher without waiting
+	primary.ready = false
+	runner.flushLogGroups(logGroups)
+	s.Equal(1, primary.flushed)
+	s.Equal(1, fallback.flushed)
+
+	// as well as those the primary flusher fails to flush
+	primary.ready = true
+	primary.fail = true
+	runner.flushLogGroups(logGroups)
+	s.Equal(2, fallback.flushed)
+
+	// fallback flushers of a config without other flushers receive all log groups
+	config, err = buildLogstoreConfig("test_prj", "test_logstore", "flush_fallback_only", -1, `{"flushers": [{"type": "flusher_fallback_test"}]}`)
+	s.Require().NoError(err)
+	defer cancelRuntimeContext(config)
+	runner = config.PluginRunner.(*pluginv1Runner)
+	runner.flushLogGroups(logGroups)
+	s.Equal(1, runner.FlusherPlugins[0].Flusher.(*fallbackFlusher).flushed)
+}
@@ -15,6 +15,8 @@
 package pluginmanager
 
 import (
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/alibaba/ilogtail/pkg/flags"
@@ -39,6 +41,11 @@ type pluginv1Runner struct {
 
 	FlushOutStore  *FlushOutStore[protocol.LogGroup]
 	LogstoreConfig *LogstoreConfig
+	recordCounters *recordCounters
+	// processorLock guards ProcessorPlugins against reordering while the config is running.
+	processorLock sync.RWMutex
+	// mergeQueue holds stopped runners handed over after start, see handOverUnsendBuffer.
+	mergeQueue *mergeQueue
 
 	InputControl     *pipeline.AsyncControl
 	ProcessControl   *pipeline.AsyncControl
@@ -57,9 +64,11 @@ func (p *pluginv1Runner) Init(inputQueueSize int, flushQueueSize int) error {
 	p.AggregatorPlugins = make([]*AggregatorWrapperV1, 0)
 	p.FlusherPlugins = make([]*FlusherWrapperV1, 0)
 	p.ExtensionPlugins = make(map[string]pipeline.Extension, 0)
+	p.recordCounters = newRecordCounters(p.LogstoreConfig.Context)
 	p.LogsChan = make(chan *pipeline.LogWithContext, inputQueueSize)
 	p.LogGroupsChan = make(chan *protocol.LogGroup, math.Max(flushQueueSize, p.FlushOutStore.Len()))
 	p.FlushOutStore.Write(p.LogGroupsChan)
+	p.mergeQueue = newMergeQueue()
 	return nil
 }
 
@@ -142,6 +151,28 @@ func (p *pluginv1Runner) IsWithInputPlugin() bool {
 	return len(p.MetricPlugins) > 0 || len(p.ServicePlugins) > 0
 }
 
+func (p *pluginv1Runner) ShouldPause() bool {
+	return p.LogstoreConfig.overWatermark(len(p.LogGroupsChan), cap(p.LogGroupsChan))
+}
+
+func (p *pluginv1Runner) ForEachPlugin(fn func(p Plugin)) {
+	for _, plugin := range p.MetricPlugins {
+		fn(plugin)
+	}
+	for _, plugin := range p.ServicePlugins {
+		fn(plugin)
+	}
+	for _, plugin := range p.ProcessorPlugins {
+		fn(plugin)
+	}
+	for _, plugin := range p.AggregatorPlugins {
+		fn(plugin)
+	}
+	for _, plugin := range p.FlusherPlugins {
+		fn(plugin)
+	}
+}
+
 func (p *pluginv1Runner) addMetricInput(pluginMeta *pipeline.PluginMeta, input pipeline.MetricInputV1, inputInterval int) error {
 	var wrapper MetricWrapperV1
 	wrapper.Config = p.LogstoreConfig
@@ -212,10 +243,14 @@ func (p *pluginv1Runner) runMetricInput(async *pipeline.AsyncControl) {
 			state:           m.Input,
 			interval:        m.Interval,
 			context:         m.Config.Context,
+			config:          p.LogstoreConfig,
+			paused:          &m.paused,
 		}
 		async.Run(func(ac *pipeline.AsyncControl) {
 			runner.Run(func(state interface{}) error {
-				return m.Input.Collect(m)
+				return p.LogstoreConfig.collect(func() error {
+					return m.Input.Collect(m)
+				})
 			}, ac)
 		})
 	}
@@ -238,12 +273,13 @@ func (p *pluginv1Runner) runProcessor() {
 //
 // It returns when processShutdown is closed.
 func (p *pluginv1Runner) runProcessorInternal(cc *pipeline.AsyncControl) {
-	defer panicRecover(p.LogstoreConfig.ConfigName)
+	defer configPanicRecover(p.LogstoreConfig, p.LogstoreConfig.ConfigName)
 	var logCtx *pipeline.LogWithContext
 	var processorTag *ProcessorTag
 	if globalConfig := p.LogstoreConfig.GlobalConfig; globalConfig.EnableProcessorTag {
 		processorTag = NewProcessorTag(globalConfig.PipelineMetaTagKey, globalConfig.AppendingAllEnvMetaTag, globalConfig.AgentEnvMetaTagKey)
 	}
+	rateLimiter := newInputRateLimiter(p.LogstoreConfig.GlobalConfig)
 	for {
 		select {
 		case <-cc.CancelToken():
@@ -251,19 +287,32 @@ func (p *pluginv1Runner) runProcessorInternal(cc *pipeline.AsyncControl) {
 				return
 			}
 		case logCtx = <-p.LogsChan:
+			if !rateLimiter.admit(1, int64(logCtx.Log.Size()), cc.CancelToken()) {
+				p.recordCounters.rateLimited(1)
+				break
+			}
 			if processorTag != nil {
 				processorTag.ProcessV1(logCtx)
 			}
 			logs := []*protocol.Log{logCtx.Log}
-			for _, processor := range p.ProcessorPlugins {
+			p.processorLock.RLock()
+			processors := p.ProcessorPlugins
+			p.processorLock.RUnlock()
+			for _, processor := range processors {
 				logs = processor.Process(logs)
 				if len(logs) == 0 {
 					break
 				}
 			}
+			p.recordCounters.processed(1, len(logs))
 			nowTime := time.Now()
 
 			if len(logs) > 0 {
+				for _, l := range logs {
+					if len(l.Contents) == 0 {
+						p.recordCounters.aggregatorDroppedRecords.Add(1)
+					}
+				}
 				for _, aggregator := range p.AggregatorPlugins {
 					for _, l := range logs {
 						if len(l.Contents) == 0 {
@@ -307,7 +356,13 @@ func (p *pluginv1Runner) runFlusher() {
 }
 
 func (p *pluginv1Runner) runFlusherInternal(cc *pipeline.AsyncControl) {
-	defer panicRecover(p.LogstoreConfig.ConfigName)
+	defer configPanicRecover(p.LogstoreConfig, p.LogstoreConfig.ConfigName)
+	// LogGroups merged from a replaced config after Init are flushed before new ones.
+	if p.FlushOutStore.Len() > 0 {
+		logGroups := p.FlushOutStore.Get()
+		p.FlushOutStore.Reset()
+		p.flushLogGroups(logGroups)
+	}
 	var logGroup *protocol.LogGroup
 	for {
 		select {
@@ -328,48 +383,127 @@ func (p *pluginv1Runner) runFlusherInternal(cc *pipeline.AsyncControl) {
 				logGroups[i] = <-p.LogGroupsChan
 			}
 
-			for _, logGroup := range logGroups {
-				if len(logGroup.Logs) == 0 {
-					continue
+			p.flushLogGroups(logGroups)
+
+		case <-p.mergeQueue.notify:
+			p.flushMerged()
+		}
+	}
+}
+
+// flushMerged flushes unsent data of stopped runners handed over after start, see handOverUnsendBuffer.
+func (p *pluginv1Runner) flushMerged() {
+	for _, runner := range p.mergeQueue.take() {
+		p.Merge(runner)
+	}
+	if p.FlushOutStore.Len() > 0 {
+		logGroups := p.FlushOutStore.Get()
+		p.FlushOutStore.Reset()
+		p.flushLogGroups(logGroups)
+	}
+}
+
+// flushLogGroups flushes logGroups to all flushers, or moves them to FlushOutStore
+// if flushers are not ready when the config is stopping.
+// With fallback flushers, see flushWithFallback, it never waits for flushers to be ready.
+func (p *pluginv1Runner) flushLogGroups(logGroups []*protocol.LogGroup) {
+	logCount := 0
+	for _, logGroup := range logGroups {
+		if logGroup == nil || len(logGroup.Logs) == 0 {
+			continue
+		}
+		logGroup.Source = util.GetIPAddress()
+		if logCount == 0 {
+			p.recordCounters.sampleGroupSize(int64(logGroup.Size()), len(logGroup.Logs))
+		}
+		logCount += len(logGroup.Logs)
+	}
+	p.recordCounters.aggregatorOutRecords.Add(int64(logCount))
+
+	if primaries, fallbacks := p.splitFallbackFlushers(); len(primaries) > 0 && len(fallbacks) > 0 {
+		p.flushWithFallback(primaries, fallbacks, logGroups, logCount)
+		return
+	}
+
+	// Flush LogGroups to all flushers.
+	// Note: multiple flushers is unrecommended, because all flushers will
+	//   be blocked if one of them is unready.
+	for {
+		allReady := true
+		for _, flusher := range p.FlusherPlugins {
+			if !flusher.Flusher.IsReady(p.LogstoreConfig.ProjectName,
+				p.LogstoreConfig.LogstoreName, p.LogstoreConfig.LogstoreKey) {
+				allReady = false
+				break
+			}
+		}
+		if allReady {
+			success := true
+			for _, flusher := range p.FlusherPlugins {
+				err := flusher.Flush(p.LogstoreConfig.ProjectName,
+					p.LogstoreConfig.LogstoreName, p.LogstoreConfig.ConfigName, logGroups)
+				if err != nil {
+					success = false
+					logger.Error(p.LogstoreConfig.Context.GetRuntimeContext(), "FLUSH_DATA_ALARM", "flush data error",
+						p.LogstoreConfig.ProjectName, p.LogstoreConfig.LogstoreName, err)
 				}
-				logGroup.Source = util.GetIPAddress()
 			}
+			p.recordCounters.flushed(logCount, success)
+			return
+		}
+		if !p.LogstoreConfig.FlushOutFlag.Load() {
+			time.Sleep(time.Duration(10) * time.Millisecond)
+			continue
+		}
 
-			// Flush LogGroups to all flushers.
-			// Note: multiple flushers is unrecommended, because all flushers will
-			//   be blocked if one of them is unready.
-			for {
-				allReady := true
-				for _, flusher := range p.FlusherPlugins {
-					if !flusher.Flusher.IsReady(p.LogstoreConfig.ProjectName,
-						p.LogstoreConfig.LogstoreName, p.LogstoreConfig.LogstoreKey) {
-						allReady = false
-						break
-					}
-				}
-				if allReady {
-					for _, flusher := range p.FlusherPlugins {
-						err := flusher.Flush(p.LogstoreConfig.ProjectName,
-							p.LogstoreConfig.LogstoreName, p.LogstoreConfig.ConfigName, logGroups)
-						if err != nil {
-							logger.Error(p.LogstoreConfig.Context.GetRuntimeContext(), "FLUSH_DATA_ALARM", "flush data error",
-								p.LogstoreConfig.ProjectName, p.LogstoreConfig.LogstoreName, err)
-						}
-					}
-					break
-				}
-				if !p.LogstoreConfig.FlushOutFlag.Load() {
-					time.Sleep(time.Duration(10) * time.Millisecond)
-					continue
-				}
+		// Config is stopping, move unflushed LogGroups to FlushOutLogGroups.
+		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "flush loggroup to slice, loggroup count", len(logGroups))
+		p.FlushOutStore.Add(logGroups...)
+		return
+	}
+}
 
-				// Config is stopping, move unflushed LogGroups to FlushOutLogGroups.
-				logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "flush loggroup to slice, loggroup count", listLen)
-				p.FlushOutStore.Add(logGroups...)
-				break
+// splitFallbackFlushers splits the flushers into primary ones and fallback ones, see pipeline.FallbackFlusher.
+func (p *pluginv1Runner) splitFallbackFlushers() (primaries, fallbacks []*FlusherWrapperV1) {
+	for _, flusher := range p.FlusherPlugins {
+		if fallback, ok := flusher.Flusher.(pipeline.FallbackFlusher); ok && fallback.IsFallback() {
+			fallbacks = append(fallbacks, flusher)
+		} else {
+			primaries = append(primaries, flusher)
+		}
+	}
+	return primaries, fallbacks
+}
+
+// flushWithFallback flushes logGroups to the primary flushers which are ready, and to the fallback flushers
+// only if a primary one is not ready or fails, so that no primary flusher blocks the others.
+func (p *pluginv1Runner) flushWithFallback(primaries, fallbacks []*FlusherWrapperV1, logGroups []*protocol.LogGroup, logCount int) {
+	missed := false
+	for _, flusher := range primaries {
+		if !flusher.Flusher.IsReady(p.LogstoreConfig.ProjectName,
+			p.LogstoreConfig.LogstoreName, p.LogstoreConfig.LogstoreKey) {
+			missed = true
+			continue
+		}
+		if err := flusher.Flush(p.LogstoreConfig.ProjectName,
+			p.LogstoreConfig.LogstoreName, p.LogstoreConfig.ConfigName, logGroups); err != nil {
+			missed = true
+			logger.Error(p.LogstoreConfig.Context.GetRuntimeContext(), "FLUSH_DATA_ALARM", "flush data error",
+				p.LogstoreConfig.ProjectName, p.LogstoreConfig.LogstoreName, err)
+		}
+	}
+	success := true
+	if missed {
+		for _, flusher := range fallbacks {
+			if err := flusher.Flush(p.LogstoreConfig.ProjectName,
+				p.LogstoreConfig.LogstoreName, p.LogstoreConfig.ConfigName, logGroups); err != nil {
+				success = false
+				logger.Error(p.LogstoreConfig.Context.GetRuntimeContext(), "FLUSH_DATA_ALARM", "flush data to fallback flusher error",
+					p.LogstoreConfig.ProjectName, p.LogstoreConfig.LogstoreName, err)
 			}
 		}
 	}
+	p.recordCounters.flushed(logCount, success)
 }
 
 func (p *pluginv1Runner) Stop(exit bool) error {
@@ -378,49 +512,103 @@ func (p *pluginv1Runner) Stop(exit bool) error {
 	}
 	p.LogstoreConfig.FlushOutFlag.Store(true)
 
-	for _, service := range p.ServicePlugins {
-		_ = service.Stop()
+	for _, category := range stopOrder {
+		p.stopPlugins(category, exit)
 	}
-	p.InputControl.WaitCancel()
-	logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "metric plugins stop", "done", "service plugins stop", "done")
-
-	p.ProcessControl.WaitCancel()
-	logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "processor plugins stop", "done")
-
-	p.AggregateControl.WaitCancel()
-	logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "aggregator plugins stop", "done")
+	return nil
+}
 
-	p.FlushControl.WaitCancel()
+// stopPlugins stops plugins of the category, see stopOrder.
+func (p *pluginv1Runner) stopPlugins(category pluginCategory, exit bool) {
+	switch category {
+	case pluginServiceInput:
+		p.setInputsPaused(false)
+		for _, service := range sortByStopPriority(p.ServicePlugins) {
+			_ = stopPlugin(p.LogstoreConfig, service, service.Stop)
+		}
+	case pluginMetricInput:
+		p.InputControl.WaitCancel()
+		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "metric plugins stop", "done", "service plugins stop", "done")
+	case pluginProcessor:
+		p.ProcessControl.WaitCancel()
+		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "processor plugins stop", "done")
+	case pluginAggregator:
+		p.AggregateControl.WaitCancel()
+		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "aggregator plugins stop", "done")
+	case pluginFlusher:
+		p.FlushControl.WaitCancel()
+		// runners handed over after the flusher goroutine exits are kept as unsent data
+		for _, runner := range p.mergeQueue.take() {
+			p.Merge(runner)
+		}
 
-	if exit && p.FlushOutStore.Len() > 0 {
-		flushers := make([]pipeline.FlusherV1, len(p.FlusherPlugins))
-		for idx, flusher := range p.FlusherPlugins {
-			flushers[idx] = flusher.Flusher
+		if exit && p.FlushOutStore.Len() > 0 {
+			flushers := make([]pipeline.FlusherV1, len(p.FlusherPlugins))
+			for idx, flusher := range p.FlusherPlugins {
+				flushers[idx] = flusher.Flusher
+			}
+			logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "flushout loggroups, count", p.FlushOutStore.Len())
+			rst := flushOutStore(p.LogstoreConfig, p.FlushOutStore, p.FlusherPlugins, func(lc *LogstoreConfig, sf *FlusherWrapperV1, store *FlushOutStore[protocol.LogGroup]) error {
+				return sf.Flusher.Flush(lc.Context.GetProject(), lc.Context.GetLogstore(), lc.Context.GetConfigName(), store.Get())
+			})
+			logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "flushout loggroups, result", rst)
 		}
-		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "flushout loggroups, count", p.FlushOutStore.Len())
-		rst := flushOutStore(p.LogstoreConfig, p.FlushOutStore, p.FlusherPlugins, func(lc *LogstoreConfig, sf *FlusherWrapperV1, store *FlushOutStore[protocol.LogGroup]) error {
-			return sf.Flusher.Flush(lc.Context.GetProject(), lc.Context.GetLogstore(), lc.Context.GetConfigName(), store.Get())
-		})
-		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "flushout loggroups, result", rst)
-	}
-	for idx, flusher := range p.FlusherPlugins {
-		if err := flusher.Flusher.Stop(); err != nil {
-			logger.Warningf(p.LogstoreConfig.Context.GetRuntimeContext(), "STOP_FLUSHER_ALARM",
-				"Failed to stop %vth flusher (description: %v): %v",
-				idx, flusher.Flusher.Description(), err)
+		for idx, flusher := range sortByStopPriority(p.FlusherPlugins) {
+			if err := stopPlugin(p.LogstoreConfig, flusher, flusher.Flusher.Stop); err != nil {
+				logger.Warningf(p.LogstoreConfig.Context.GetRuntimeContext(), "STOP_FLUSHER_ALARM",
+					"Failed to stop %vth flusher (description: %v): %v",
+					idx, flusher.Flusher.Description(), err)
+			}
 		}
+		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "flusher plugins stop", "done")
+	case pluginExtension:
+		for _, name := range sortExtensionsByStopPriority(p.ExtensionPlugins) {
+			extension := p.ExtensionPlugins[name]
+			err := extension.Stop()
+			if err != nil {
+				logger.Warningf(p.LogstoreConfig.Context.GetRuntimeContext(), "STOP_EXTENSION_ALARM",
+					"failed to stop extension (description: %v): %v", extension.Description(), err)
+			}
+		}
+		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "extension plugins stop", "done")
 	}
-	logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "flusher plugins stop", "done")
+}
 
-	for _, extension := range p.ExtensionPlugins {
-		err := extension.Stop()
-		if err != nil {
-			logger.Warningf(p.LogstoreConfig.Context.GetRuntimeContext(), "STOP_EXTENSION_ALARM",
-				"failed to stop extension (description: %v): %v", extension.Description(), err)
-		}
+// setInputPaused pauses or resumes the input at index, metric inputs come before service inputs.
+func (p *pluginv1Runner) setInputPaused(index int, paused bool) (string, error) {
+	switch {
+	case index >= 0 && index < len(p.MetricPlugins):
+		p.MetricPlugins[index].paused.Store(paused)
+		return p.MetricPlugins[index].pluginType, nil
+	case index >= len(p.MetricPlugins) && index < len(p.MetricPlugins)+len(p.ServicePlugins):
+		service := p.ServicePlugins[index-len(p.MetricPlugins)]
+		service.paused.Store(paused)
+		return service.pluginType, nil
 	}
-	logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "extension plugins stop", "done")
+	return "", fmt.Errorf("invalid input index %d, config has %d inputs", index, len(p.MetricPlugins)+len(p.ServicePlugins))
+}
+
+// setInputsPaused pauses or resumes all inputs.
+func (p *pluginv1Runner) setInputsPaused(paused bool) {
+	for _, metric := range p.MetricPlugins {
+		metric.paused.Store(paused)
+	}
+	for _, service := range p.ServicePlugins {
+		service.paused.Store(paused)
+	}
+}
 
+func (p *pluginv1Runner) reorderProcessors(newOrder []int) error {
+	p.processorLock.Lock()
+	defer p.processorLock.Unlock()
+	if err := checkPermutation(newOrder, len(p.ProcessorPlugins)); err != nil {
+		return err
+	}
+	processors := make([]*ProcessorWrapperV1, len(newOrder))
+	for i, idx := range newOrder {
+		processors[i] = p.ProcessorPlugins[idx]
+	}
+	p.ProcessorPlugins = processors
 	return nil
 }
 
@@ -463,5 +651,8 @@ func (p *pluginv1Runner) ReceiveLogGroup(logGroup pipeline.LogGroupWithContext)
 func (p *pluginv1Runner) Merge(r PluginRunner) {
 	if other, ok := r.(*pluginv1Runner); ok {
 		p.FlushOutStore.Merge(other.FlushOutStore)
+		for _, runner := range other.mergeQueue.take() {
+			p.Merge(runner)
+		}
 	}
 }
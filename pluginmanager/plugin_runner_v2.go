@@ -15,8 +15,10 @@
 package pluginmanager
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/alibaba/ilogtail/pkg/helper"
@@ -54,6 +56,11 @@ type pluginv2Runner struct {
 
 	FlushOutStore  *FlushOutStore[models.PipelineGroupEvents]
 	LogstoreConfig *LogstoreConfig
+	recordCounters *recordCounters
+	// processorLock guards ProcessorPlugins against reordering while the config is running.
+	processorLock sync.RWMutex
+	// mergeQueue holds stopped runners handed over after start, see handOverUnsendBuffer.
+	mergeQueue *mergeQueue
 }
 
 func (p *pluginv2Runner) Init(inputQueueSize int, flushQueueSize int) error {
@@ -67,11 +74,13 @@ func (p *pluginv2Runner) Init(inputQueueSize int, flushQueueSize int) error {
 	p.AggregatorPlugins = make([]*AggregatorWrapperV2, 0)
 	p.FlusherPlugins = make([]*FlusherWrapperV2, 0)
 	p.ExtensionPlugins = make(map[string]pipeline.Extension, 0)
+	p.recordCounters = newRecordCounters(p.LogstoreConfig.Context)
 	p.InputPipeContext = helper.NewObservePipelineContext(inputQueueSize)
 	p.ProcessPipeContext = helper.NewGroupedPipelineContext()
 	p.AggregatePipeContext = helper.NewObservePipelineContext(flushQueueSize)
 	p.FlushPipeContext = helper.NewNoopPipelineContext()
 	p.FlushOutStore.Write(p.AggregatePipeContext.Collector().Observe())
+	p.mergeQueue = newMergeQueue()
 	return nil
 }
 
@@ -134,6 +143,11 @@ func (p *pluginv2Runner) Run() {
 	p.runInput()
 }
 
+func (p *pluginv2Runner) ShouldPause() bool {
+	flushChan := p.AggregatePipeContext.Collector().Observe()
+	return p.LogstoreConfig.overWatermark(len(flushChan), cap(flushChan))
+}
+
 func (p *pluginv2Runner) RunPlugins(category pluginCategory, control *pipeline.AsyncControl) {
 	switch category {
 	case pluginMetricInput:
@@ -146,6 +160,24 @@ func (p *pluginv2Runner) IsWithInputPlugin() bool {
 	return len(p.MetricPlugins) > 0 || len(p.ServicePlugins) > 0
 }
 
+func (p *pluginv2Runner) ForEachPlugin(fn func(p Plugin)) {
+	for _, plugin := range p.MetricPlugins {
+		fn(plugin)
+	}
+	for _, plugin := range p.ServicePlugins {
+		fn(plugin)
+	}
+	for _, plugin := range p.ProcessorPlugins {
+		fn(plugin)
+	}
+	for _, plugin := range p.AggregatorPlugins {
+		fn(plugin)
+	}
+	for _, plugin := range p.FlusherPlugins {
+		fn(plugin)
+	}
+}
+
 func (p *pluginv2Runner) addMetricInput(pluginMeta *pipeline.PluginMeta, input pipeline.MetricInputV2, inputInterval int) error {
 	var wrapper MetricWrapperV2
 	wrapper.Config = p.LogstoreConfig
@@ -160,6 +192,8 @@ func (p *pluginv2Runner) addMetricInput(pluginMeta *pipeline.PluginMeta, input p
 		interval:        wrapper.Interval,
 		state:           input,
 		context:         p.LogstoreConfig.Context,
+		config:          p.LogstoreConfig,
+		paused:          &wrapper.paused,
 	})
 	return err
 }
@@ -196,6 +230,7 @@ func (p *pluginv2Runner) addAggregator(pluginMeta *pipeline.PluginMeta, aggregat
 		initialMaxDelay: wrapper.Interval,
 		interval:        wrapper.Interval,
 		context:         p.LogstoreConfig.Context,
+		config:          p.LogstoreConfig,
 	})
 	return nil
 }
@@ -221,7 +256,7 @@ func (p *pluginv2Runner) runInput() {
 		service := input
 		p.InputControl.Run(func(c *pipeline.AsyncControl) {
 			logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "start run service", service)
-			defer panicRecover(service.Input.Description())
+			defer configPanicRecover(p.LogstoreConfig, service.Input.Description())
 			if err := service.StartService(p.InputPipeContext); err != nil {
 				logger.Error(p.LogstoreConfig.Context.GetRuntimeContext(), "PLUGIN_ALARM", "start service error, err", err)
 			}
@@ -237,7 +272,9 @@ func (p *pluginv2Runner) runMetricInput(control *pipeline.AsyncControl) {
 			timer := t
 			control.Run(func(cc *pipeline.AsyncControl) {
 				timer.Run(func(state interface{}) error {
-					return metric.Read(p.InputPipeContext)
+					return p.LogstoreConfig.collect(func() error {
+						return metric.Read(p.InputPipeContext)
+					})
 				}, cc)
 			})
 		} else {
@@ -252,13 +289,14 @@ func (p *pluginv2Runner) runProcessor() {
 }
 
 func (p *pluginv2Runner) runProcessorInternal(cc *pipeline.AsyncControl) {
-	defer panicRecover(p.LogstoreConfig.ConfigName)
+	defer configPanicRecover(p.LogstoreConfig, p.LogstoreConfig.ConfigName)
 	pipeContext := p.ProcessPipeContext
 	pipeChan := p.InputPipeContext.Collector().Observe()
 	var processorTag *ProcessorTag
 	if globalConfig := p.LogstoreConfig.GlobalConfig; globalConfig.EnableProcessorTag {
 		processorTag = NewProcessorTag(globalConfig.PipelineMetaTagKey, globalConfig.AppendingAllEnvMetaTag, globalConfig.AgentEnvMetaTagKey)
 	}
+	rateLimiter := newInputRateLimiter(p.LogstoreConfig.GlobalConfig)
 	for {
 		select {
 		case <-cc.CancelToken():
@@ -266,11 +304,19 @@ func (p *pluginv2Runner) runProcessorInternal(cc *pipeline.AsyncControl) {
 				return
 			}
 		case group := <-pipeChan:
+			if !rateLimiter.admit(len(group.Events), groupEventsSize(group), cc.CancelToken()) {
+				p.recordCounters.rateLimited(len(group.Events))
+				break
+			}
 			if processorTag != nil {
 				processorTag.ProcessV2(group)
 			}
+			inCount := len(group.Events)
 			pipeEvents := []*models.PipelineGroupEvents{group}
-			for _, processor := range p.ProcessorPlugins {
+			p.processorLock.RLock()
+			processors := p.ProcessorPlugins
+			p.processorLock.RUnlock()
+			for _, processor := range processors {
 				for _, in := range pipeEvents {
 					processor.Process(in, pipeContext)
 				}
@@ -279,6 +325,7 @@ func (p *pluginv2Runner) runProcessorInternal(cc *pipeline.AsyncControl) {
 					break
 				}
 			}
+			p.recordCounters.processed(inCount, countGroupEvents(pipeEvents))
 			if len(pipeEvents) == 0 {
 				break
 			}
@@ -325,7 +372,13 @@ func (p *pluginv2Runner) runFlusher() {
 }
 
 func (p *pluginv2Runner) runFlusherInternal(cc *pipeline.AsyncControl) {
-	defer panicRecover(p.LogstoreConfig.ConfigName)
+	defer configPanicRecover(p.LogstoreConfig, p.LogstoreConfig.ConfigName)
+	// Group events merged from a replaced config after Init are flushed before new ones.
+	if p.FlushOutStore.Len() > 0 {
+		data := p.FlushOutStore.Get()
+		p.FlushOutStore.Reset()
+		p.flushGroupEvents(data)
+	}
 	pipeChan := p.AggregatePipeContext.Collector().Observe()
 	for {
 		select {
@@ -346,91 +399,200 @@ func (p *pluginv2Runner) runFlusherInternal(cc *pipeline.AsyncControl) {
 				data[i] = <-pipeChan
 			}
 
-			// Flush LogGroups to all flushers.
-			// Note: multiple flushers is unrecommended, because all flushers will
-			//   be blocked if one of them is unready.
-			for {
-				allReady := true
-				for _, flusher := range p.FlusherPlugins {
-					if !flusher.Flusher.IsReady(p.LogstoreConfig.ProjectName,
-						p.LogstoreConfig.LogstoreName, p.LogstoreConfig.LogstoreKey) {
-						allReady = false
-						break
-					}
-				}
-				if allReady {
-					for _, flusher := range p.FlusherPlugins {
-						err := flusher.Export(data, p.FlushPipeContext)
-						if err != nil {
-							logger.Error(p.LogstoreConfig.Context.GetRuntimeContext(), "FLUSH_DATA_ALARM", "flush data error",
-								p.LogstoreConfig.ProjectName, p.LogstoreConfig.LogstoreName, err)
-						}
-					}
-					break
-				}
-				if !p.LogstoreConfig.FlushOutFlag.Load() {
-					time.Sleep(time.Duration(10) * time.Millisecond)
-					continue
-				}
+			p.flushGroupEvents(data)
 
-				// Config is stopping, move unflushed LogGroups to FlushOutLogGroups.
-				logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "flush loggroup to slice, loggroup count", dataSize)
-				p.FlushOutStore.Add(data...)
+		case <-p.mergeQueue.notify:
+			p.flushMerged()
+		}
+	}
+}
+
+// flushMerged flushes unsent data of stopped runners handed over after start, see handOverUnsendBuffer.
+func (p *pluginv2Runner) flushMerged() {
+	for _, runner := range p.mergeQueue.take() {
+		p.Merge(runner)
+	}
+	if p.FlushOutStore.Len() > 0 {
+		data := p.FlushOutStore.Get()
+		p.FlushOutStore.Reset()
+		p.flushGroupEvents(data)
+	}
+}
+
+// flushGroupEvents exports data to all flushers, or moves it to FlushOutStore
+// if flushers are not ready when the config is stopping.
+func (p *pluginv2Runner) flushGroupEvents(data []*models.PipelineGroupEvents) {
+	eventCount := countGroupEvents(data)
+	p.recordCounters.aggregatorOutRecords.Add(int64(eventCount))
+	sampleGroupEventsSize(p.recordCounters, data)
+	// Flush LogGroups to all flushers.
+	// Note: multiple flushers is unrecommended, because all flushers will
+	//   be blocked if one of them is unready.
+	for {
+		allReady := true
+		for _, flusher := range p.FlusherPlugins {
+			if !flusher.Flusher.IsReady(p.LogstoreConfig.ProjectName,
+				p.LogstoreConfig.LogstoreName, p.LogstoreConfig.LogstoreKey) {
+				allReady = false
 				break
 			}
 		}
+		if allReady {
+			success := true
+			for _, flusher := range p.FlusherPlugins {
+				err := flusher.Export(data, p.FlushPipeContext)
+				if err != nil {
+					success = false
+					logger.Error(p.LogstoreConfig.Context.GetRuntimeContext(), "FLUSH_DATA_ALARM", "flush data error",
+						p.LogstoreConfig.ProjectName, p.LogstoreConfig.LogstoreName, err)
+				}
+			}
+			p.recordCounters.flushed(eventCount, success)
+			return
+		}
+		if !p.LogstoreConfig.FlushOutFlag.Load() {
+			time.Sleep(time.Duration(10) * time.Millisecond)
+			continue
+		}
+
+		// Config is stopping, move unflushed LogGroups to FlushOutLogGroups.
+		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "flush loggroup to slice, loggroup count", len(data))
+		p.FlushOutStore.Add(data...)
+		return
 	}
 }
 
+func countGroupEvents(groups []*models.PipelineGroupEvents) int {
+	count := 0
+	for _, group := range groups {
+		if group != nil {
+			count += len(group.Events)
+		}
+	}
+	return count
+}
+
+// sampleGroupEventsSize samples the size of the first non-empty group.
+func sampleGroupEventsSize(counters *recordCounters, groups []*models.PipelineGroupEvents) {
+	for _, group := range groups {
+		if group != nil && len(group.Events) > 0 {
+			counters.sampleGroupSize(groupEventsSize(group), len(group.Events))
+			return
+		}
+	}
+}
+
+func groupEventsSize(group *models.PipelineGroupEvents) int64 {
+	var size int64
+	for _, event := range group.Events {
+		size += event.GetSize()
+	}
+	return size
+}
+
 func (p *pluginv2Runner) Stop(exit bool) error {
 	for _, flusher := range p.FlusherPlugins {
 		flusher.Flusher.SetUrgent(exit)
 	}
 	p.LogstoreConfig.FlushOutFlag.Store(true)
 
-	for _, serviceInput := range p.ServicePlugins {
-		_ = serviceInput.Input.Stop()
+	for _, category := range stopOrder {
+		p.stopPlugins(category, exit)
 	}
-	p.InputControl.WaitCancel()
-	logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "metric plugins stop", "done", "service plugins stop", "done")
-
-	p.ProcessControl.WaitCancel()
-	logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "processor plugins stop", "done")
-
-	p.AggregateControl.WaitCancel()
-	logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "aggregator plugins stop", "done")
+	return nil
+}
 
-	p.FlushControl.WaitCancel()
+// stopPlugins stops plugins of the category, see stopOrder.
+func (p *pluginv2Runner) stopPlugins(category pluginCategory, exit bool) {
+	switch category {
+	case pluginServiceInput:
+		p.setInputsPaused(false)
+		for _, serviceInput := range sortByStopPriority(p.ServicePlugins) {
+			_ = stopPlugin(p.LogstoreConfig, serviceInput, serviceInput.Input.Stop)
+		}
+	case pluginMetricInput:
+		p.InputControl.WaitCancel()
+		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "metric plugins stop", "done", "service plugins stop", "done")
+	case pluginProcessor:
+		p.ProcessControl.WaitCancel()
+		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "processor plugins stop", "done")
+	case pluginAggregator:
+		p.AggregateControl.WaitCancel()
+		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "aggregator plugins stop", "done")
+	case pluginFlusher:
+		p.FlushControl.WaitCancel()
+		// runners handed over after the flusher goroutine exits are kept as unsent data
+		for _, runner := range p.mergeQueue.take() {
+			p.Merge(runner)
+		}
 
-	if exit && p.FlushOutStore.Len() > 0 {
-		flushers := make([]pipeline.FlusherV2, len(p.FlusherPlugins))
-		for idx, flusher := range p.FlusherPlugins {
-			flushers[idx] = flusher.Flusher
+		if exit && p.FlushOutStore.Len() > 0 {
+			flushers := make([]pipeline.FlusherV2, len(p.FlusherPlugins))
+			for idx, flusher := range p.FlusherPlugins {
+				flushers[idx] = flusher.Flusher
+			}
+			logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "Flushout group events, count", p.FlushOutStore.Len())
+			rst := flushOutStore(p.LogstoreConfig, p.FlushOutStore, p.FlusherPlugins, func(lc *LogstoreConfig, pf *FlusherWrapperV2, store *FlushOutStore[models.PipelineGroupEvents]) error {
+				return pf.Export(store.Get(), p.FlushPipeContext)
+			})
+			logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "Flushout group events, result", rst)
 		}
-		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "Flushout group events, count", p.FlushOutStore.Len())
-		rst := flushOutStore(p.LogstoreConfig, p.FlushOutStore, p.FlusherPlugins, func(lc *LogstoreConfig, pf *FlusherWrapperV2, store *FlushOutStore[models.PipelineGroupEvents]) error {
-			return pf.Export(store.Get(), p.FlushPipeContext)
-		})
-		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "Flushout group events, result", rst)
-	}
-	for idx, flusher := range p.FlusherPlugins {
-		if err := flusher.Flusher.Stop(); err != nil {
-			logger.Warningf(p.LogstoreConfig.Context.GetRuntimeContext(), "STOP_FLUSHER_ALARM",
-				"Failed to stop %vth flusher (description: %v): %v",
-				idx, flusher.Flusher.Description(), err)
+		for idx, flusher := range sortByStopPriority(p.FlusherPlugins) {
+			if err := stopPlugin(p.LogstoreConfig, flusher, flusher.Flusher.Stop); err != nil {
+				logger.Warningf(p.LogstoreConfig.Context.GetRuntimeContext(), "STOP_FLUSHER_ALARM",
+					"Failed to stop %vth flusher (description: %v): %v",
+					idx, flusher.Flusher.Description(), err)
+			}
 		}
+		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "Flusher plugins stop", "done")
+	case pluginExtension:
+		for _, name := range sortExtensionsByStopPriority(p.ExtensionPlugins) {
+			extension := p.ExtensionPlugins[name]
+			err := extension.Stop()
+			if err != nil {
+				logger.Warningf(p.LogstoreConfig.Context.GetRuntimeContext(), "STOP_EXTENSION_ALARM",
+					"failed to stop extension (description: %v): %v", extension.Description(), err)
+			}
+		}
+		logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "extension plugins stop", "done")
 	}
-	logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "Flusher plugins stop", "done")
+}
 
-	for _, extension := range p.ExtensionPlugins {
-		err := extension.Stop()
-		if err != nil {
-			logger.Warningf(p.LogstoreConfig.Context.GetRuntimeContext(), "STOP_EXTENSION_ALARM",
-				"failed to stop extension (description: %v): %v", extension.Description(), err)
-		}
+// setInputPaused pauses or resumes the input at index, metric inputs come before service inputs.
+func (p *pluginv2Runner) setInputPaused(index int, paused bool) (string, error) {
+	switch {
+	case index >= 0 && index < len(p.MetricPlugins):
+		p.MetricPlugins[index].paused.Store(paused)
+		return p.MetricPlugins[index].pluginType, nil
+	case index >= len(p.MetricPlugins) && index < len(p.MetricPlugins)+len(p.ServicePlugins):
+		service := p.ServicePlugins[index-len(p.MetricPlugins)]
+		service.paused.Store(paused)
+		return service.pluginType, nil
 	}
-	logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "extension plugins stop", "done")
+	return "", fmt.Errorf("invalid input index %d, config has %d inputs", index, len(p.MetricPlugins)+len(p.ServicePlugins))
+}
+
+// setInputsPaused pauses or resumes all inputs.
+func (p *pluginv2Runner) setInputsPaused(paused bool) {
+	for _, metric := range p.MetricPlugins {
+		metric.paused.Store(paused)
+	}
+	for _, service := range p.ServicePlugins {
+		service.paused.Store(paused)
+	}
+}
 
+func (p *pluginv2Runner) reorderProcessors(newOrder []int) error {
+	p.processorLock.Lock()
+	defer p.processorLock.Unlock()
+	if err := checkPermutation(newOrder, len(p.ProcessorPlugins)); err != nil {
+		return err
+	}
+	processors := make([]*ProcessorWrapperV2, len(newOrder))
+	for i, idx := range newOrder {
+		processors[i] = p.ProcessorPlugins[idx]
+	}
+	p.ProcessorPlugins = processors
 	return nil
 }
 
@@ -494,6 +656,9 @@ func (p *pluginv2Runner) ReceiveRawLog(in *pipeline.LogWithContext) {
 func (p *pluginv2Runner) Merge(r PluginRunner) {
 	if other, ok := r.(*pluginv2Runner); ok {
 		p.FlushOutStore.Merge(other.FlushOutStore)
+		for _, runner := range other.mergeQueue.take() {
+			p.Merge(runner)
+		}
 	}
 }
 
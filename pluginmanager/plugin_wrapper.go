@@ -15,6 +15,7 @@
 package pluginmanager
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/alibaba/ilogtail/pkg/pipeline"
@@ -32,30 +33,68 @@ type InputWrapper struct {
 	Tags     map[string]string
 	Interval time.Duration
 
+	pluginType string
+	pluginID   string
+	// paused is set by PauseInput, see waitResumed.
+	paused atomic.Bool
+
 	outEventsTotal      selfmonitor.CounterMetric
 	outEventGroupsTotal selfmonitor.CounterMetric
 	outSizeBytes        selfmonitor.CounterMetric
 }
 
+const inputPausedCheckInterval = 100 * time.Millisecond
+
+// waitResumed blocks a service input adding data while it is paused or the config is under backpressure,
+// so that the input stops producing the same way as when the pipeline is full.
+// Inputs are resumed when the runner stops.
+func (wrapper *InputWrapper) waitResumed() {
+	for wrapper.paused.Load() || (wrapper.Config != nil && wrapper.Config.underBackpressure()) {
+		time.Sleep(inputPausedCheckInterval)
+	}
+}
+
 func (wrapper *InputWrapper) InitMetricRecord(pluginMeta *pipeline.PluginMeta) {
 	labels := pipeline.GetPluginCommonLabels(wrapper.Config.Context, pluginMeta)
 	wrapper.MetricRecord = wrapper.Config.Context.RegisterMetricRecord(labels)
+	wrapper.pluginType = pluginMeta.PluginType
+	wrapper.pluginID = pluginMeta.PluginID
 
 	wrapper.outEventsTotal = selfmonitor.NewCounterMetricAndRegister(wrapper.MetricRecord, selfmonitor.MetricPluginOutEventsTotal)
 	wrapper.outEventGroupsTotal = selfmonitor.NewCounterMetricAndRegister(wrapper.MetricRecord, selfmonitor.MetricPluginOutEventGroupsTotal)
 	wrapper.outSizeBytes = selfmonitor.NewCounterMetricAndRegister(wrapper.MetricRecord, selfmonitor.MetricPluginOutSizeBytes)
 }
 
+func (wrapper *InputWrapper) PluginType() string {
+	return wrapper.pluginType
+}
+
+func (wrapper *InputWrapper) PluginID() string {
+	return wrapper.pluginID
+}
+
+func (wrapper *InputWrapper) detachConfig() {
+	wrapper.Config = nil
+}
+
 // The service plugin is an input plugin used for passively receiving data.
 type ServiceWrapper struct {
 	InputWrapper
 }
 
+func (wrapper *ServiceWrapper) Category() pluginCategory {
+	return pluginServiceInput
+}
+
 // metric plugin is an input plugin used for actively pulling data.
 type MetricWrapper struct {
 	InputWrapper
 }
 
+func (wrapper *MetricWrapper) Category() pluginCategory {
+	return pluginMetricInput
+}
+
 /*---------------------
 Plugin Processor
 The processor plugin is used for reading data.
@@ -65,6 +104,9 @@ type ProcessorWrapper struct {
 	pipeline.PluginContext
 	Config *LogstoreConfig
 
+	pluginType string
+	pluginID   string
+
 	inEventsTotal      selfmonitor.CounterMetric
 	inSizeBytes        selfmonitor.CounterMetric
 	outEventsTotal     selfmonitor.CounterMetric
@@ -72,9 +114,27 @@ type ProcessorWrapper struct {
 	totalProcessTimeMs selfmonitor.CounterMetric
 }
 
+func (wrapper *ProcessorWrapper) Category() pluginCategory {
+	return pluginProcessor
+}
+
+func (wrapper *ProcessorWrapper) PluginType() string {
+	return wrapper.pluginType
+}
+
+func (wrapper *ProcessorWrapper) PluginID() string {
+	return wrapper.pluginID
+}
+
+func (wrapper *ProcessorWrapper) detachConfig() {
+	wrapper.Config = nil
+}
+
 func (wrapper *ProcessorWrapper) InitMetricRecord(pluginMeta *pipeline.PluginMeta) {
 	labels := pipeline.GetPluginCommonLabels(wrapper.Config.Context, pluginMeta)
 	wrapper.MetricRecord = wrapper.Config.Context.RegisterMetricRecord(labels)
+	wrapper.pluginType = pluginMeta.PluginType
+	wrapper.pluginID = pluginMeta.PluginID
 
 	wrapper.inEventsTotal = selfmonitor.NewCounterMetricAndRegister(wrapper.MetricRecord, selfmonitor.MetricPluginInEventsTotal)
 	wrapper.inSizeBytes = selfmonitor.NewCounterMetricAndRegister(wrapper.MetricRecord, selfmonitor.MetricPluginInSizeBytes)
@@ -93,14 +153,35 @@ type AggregatorWrapper struct {
 	Config   *LogstoreConfig
 	Interval time.Duration
 
+	pluginType string
+	pluginID   string
+
 	outEventsTotal      selfmonitor.CounterMetric
 	outEventGroupsTotal selfmonitor.CounterMetric
 	outSizeBytes        selfmonitor.CounterMetric
 }
 
+func (wrapper *AggregatorWrapper) Category() pluginCategory {
+	return pluginAggregator
+}
+
+func (wrapper *AggregatorWrapper) PluginType() string {
+	return wrapper.pluginType
+}
+
+func (wrapper *AggregatorWrapper) PluginID() string {
+	return wrapper.pluginID
+}
+
+func (wrapper *AggregatorWrapper) detachConfig() {
+	wrapper.Config = nil
+}
+
 func (wrapper *AggregatorWrapper) InitMetricRecord(pluginMeta *pipeline.PluginMeta) {
 	labels := pipeline.GetPluginCommonLabels(wrapper.Config.Context, pluginMeta)
 	wrapper.MetricRecord = wrapper.Config.Context.RegisterMetricRecord(labels)
+	wrapper.pluginType = pluginMeta.PluginType
+	wrapper.pluginID = pluginMeta.PluginID
 
 	wrapper.outEventsTotal = selfmonitor.NewCounterMetricAndRegister(wrapper.MetricRecord, selfmonitor.MetricPluginOutEventsTotal)
 	wrapper.outEventGroupsTotal = selfmonitor.NewCounterMetricAndRegister(wrapper.MetricRecord, selfmonitor.MetricPluginOutEventGroupsTotal)
@@ -122,15 +203,36 @@ type FlusherWrapper struct {
 	Config   *LogstoreConfig
 	Interval time.Duration
 
+	pluginType string
+	pluginID   string
+
 	inEventsTotal      selfmonitor.CounterMetric
 	inEventGroupsTotal selfmonitor.CounterMetric
 	inSizeBytes        selfmonitor.CounterMetric
 	totalDelayTimeMs   selfmonitor.CounterMetric
 }
 
+func (wrapper *FlusherWrapper) Category() pluginCategory {
+	return pluginFlusher
+}
+
+func (wrapper *FlusherWrapper) PluginType() string {
+	return wrapper.pluginType
+}
+
+func (wrapper *FlusherWrapper) PluginID() string {
+	return wrapper.pluginID
+}
+
+func (wrapper *FlusherWrapper) detachConfig() {
+	wrapper.Config = nil
+}
+
 func (wrapper *FlusherWrapper) InitMetricRecord(pluginMeta *pipeline.PluginMeta) {
 	labels := pipeline.GetPluginCommonLabels(wrapper.Config.Context, pluginMeta)
 	wrapper.MetricRecord = wrapper.Config.Context.RegisterMetricRecord(labels)
+	wrapper.pluginType = pluginMeta.PluginType
+	wrapper.pluginID = pluginMeta.PluginID
 
 	wrapper.inEventsTotal = selfmonitor.NewCounterMetricAndRegister(wrapper.MetricRecord, selfmonitor.MetricPluginInEventsTotal)
 	wrapper.inEventGroupsTotal = selfmonitor.NewCounterMetricAndRegister(wrapper.MetricRecord, selfmonitor.MetricPluginInEventGroupsTotal)
@@ -37,6 +37,10 @@ type AggregatorWrapperV1 struct {
 	Aggregator    pipeline.AggregatorV1
 }
 
+func (wrapper *AggregatorWrapperV1) pluginInstance() interface{} {
+	return wrapper.Aggregator
+}
+
 func (wrapper *AggregatorWrapperV1) Init(pluginMeta *pipeline.PluginMeta) error {
 	wrapper.InitMetricRecord(pluginMeta)
 
@@ -87,7 +91,7 @@ func (wrapper *AggregatorWrapperV1) AddWithWait(loggroup *protocol.LogGroup, dur
 // Run calls periodically Aggregator.Flush to get log groups from associated aggregator and
 // pass them to LogstoreConfig through LogGroupsChan.
 func (wrapper *AggregatorWrapperV1) Run(control *pipeline.AsyncControl) {
-	defer panicRecover(wrapper.Aggregator.Description())
+	defer configPanicRecover(wrapper.Config, wrapper.Aggregator.Description())
 	for {
 		exitFlag := util.RandomSleep(wrapper.Interval, 0.1, control.CancelToken())
 		logGroups := wrapper.Aggregator.Flush()
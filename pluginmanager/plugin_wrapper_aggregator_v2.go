@@ -36,6 +36,10 @@ type AggregatorWrapperV2 struct {
 	totalDelayTimeMs selfmonitor.CounterMetric
 }
 
+func (wrapper *AggregatorWrapperV2) pluginInstance() interface{} {
+	return wrapper.Aggregator
+}
+
 func (wrapper *AggregatorWrapperV2) Init(pluginMeta *pipeline.PluginMeta) error {
 	wrapper.InitMetricRecord(pluginMeta)
 	wrapper.totalDelayTimeMs = selfmonitor.NewCounterMetricAndRegister(wrapper.MetricRecord, selfmonitor.MetricPluginTotalDelayMs)
@@ -27,6 +27,10 @@ type FlusherWrapperV1 struct {
 	Flusher       pipeline.FlusherV1
 }
 
+func (wrapper *FlusherWrapperV1) pluginInstance() interface{} {
+	return wrapper.Flusher
+}
+
 func (wrapper *FlusherWrapperV1) Init(pluginMeta *pipeline.PluginMeta) error {
 	wrapper.InitMetricRecord(pluginMeta)
 
@@ -26,6 +26,10 @@ type FlusherWrapperV2 struct {
 	Flusher pipeline.FlusherV2
 }
 
+func (wrapper *FlusherWrapperV2) pluginInstance() interface{} {
+	return wrapper.Flusher
+}
+
 func (wrapper *FlusherWrapperV2) Init(pluginMeta *pipeline.PluginMeta) error {
 	wrapper.InitMetricRecord(pluginMeta)
 
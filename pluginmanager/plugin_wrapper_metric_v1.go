@@ -28,6 +28,10 @@ type MetricWrapperV1 struct {
 	Input    pipeline.MetricInputV1
 }
 
+func (wrapper *MetricWrapperV1) pluginInstance() interface{} {
+	return wrapper.Input
+}
+
 func (wrapper *MetricWrapperV1) Init(pluginMeta *pipeline.PluginMeta, inputInterval int) error {
 	wrapper.InitMetricRecord(pluginMeta)
 
@@ -25,6 +25,10 @@ type MetricWrapperV2 struct {
 	Input pipeline.MetricInputV2
 }
 
+func (wrapper *MetricWrapperV2) pluginInstance() interface{} {
+	return wrapper.Input
+}
+
 func (wrapper *MetricWrapperV2) Init(pluginMeta *pipeline.PluginMeta, inputInterval int) error {
 	wrapper.InitMetricRecord(pluginMeta)
 
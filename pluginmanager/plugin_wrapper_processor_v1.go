@@ -27,6 +27,10 @@ type ProcessorWrapperV1 struct {
 	Processor pipeline.ProcessorV1
 }
 
+func (wrapper *ProcessorWrapperV1) pluginInstance() interface{} {
+	return wrapper.Processor
+}
+
 func (wrapper *ProcessorWrapperV1) Init(pluginMeta *pipeline.PluginMeta) error {
 	wrapper.InitMetricRecord(pluginMeta)
 
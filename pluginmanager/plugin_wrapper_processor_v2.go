@@ -30,6 +30,10 @@ type ProcessorWrapperV2 struct {
 	outEventGroupsTotal selfmonitor.CounterMetric
 }
 
+func (wrapper *ProcessorWrapperV2) pluginInstance() interface{} {
+	return wrapper.Processor
+}
+
 func (wrapper *ProcessorWrapperV2) Init(pluginMeta *pipeline.PluginMeta) error {
 	wrapper.InitMetricRecord(pluginMeta)
 	wrapper.inEventGroupsTotal = selfmonitor.NewCounterMetricAndRegister(wrapper.MetricRecord, selfmonitor.MetricPluginInEventGroupsTotal)
@@ -29,6 +29,10 @@ type ServiceWrapperV1 struct {
 	Input    pipeline.ServiceInputV1
 }
 
+func (wrapper *ServiceWrapperV1) pluginInstance() interface{} {
+	return wrapper.Input
+}
+
 func (wrapper *ServiceWrapperV1) Init(pluginMeta *pipeline.PluginMeta) error {
 	wrapper.InitMetricRecord(pluginMeta)
 
@@ -39,13 +43,15 @@ func (wrapper *ServiceWrapperV1) Init(pluginMeta *pipeline.PluginMeta) error {
 func (wrapper *ServiceWrapperV1) Run(cc *pipeline.AsyncControl) {
 	logger.Info(wrapper.Config.Context.GetRuntimeContext(), "start run service", wrapper.Input)
 
+	// The config may be detached before Start returns, e.g. once released by ForceStop.
+	ctx := wrapper.Config.Context.GetRuntimeContext()
 	go func() {
-		defer panicRecover(wrapper.Input.Description())
+		defer configPanicRecover(wrapper.Config, wrapper.Input.Description())
 		err := wrapper.Input.Start(wrapper)
 		if err != nil {
-			logger.Error(wrapper.Config.Context.GetRuntimeContext(), "PLUGIN_ALARM", "start service error, err", err)
+			logger.Error(ctx, "PLUGIN_ALARM", "start service error, err", err)
 		}
-		logger.Info(wrapper.Config.Context.GetRuntimeContext(), "service done", wrapper.Input.Description())
+		logger.Info(ctx, "service done", wrapper.Input.Description())
 	}()
 
 }
@@ -80,6 +86,7 @@ func (wrapper *ServiceWrapperV1) AddDataWithContext(tags map[string]string, fiel
 	} else {
 		logTime = t[0]
 	}
+	wrapper.waitResumed()
 	slsLog, _ := helper.CreateLog(logTime, len(t) != 0, wrapper.Tags, tags, fields)
 	wrapper.outEventsTotal.Add(1)
 	wrapper.outEventGroupsTotal.Add(1)
@@ -98,6 +105,7 @@ func (wrapper *ServiceWrapperV1) AddDataArrayWithContext(tags map[string]string,
 	} else {
 		logTime = t[0]
 	}
+	wrapper.waitResumed()
 	slsLog, _ := helper.CreateLogByArray(logTime, len(t) != 0, wrapper.Tags, tags, columns, values)
 	wrapper.outEventsTotal.Add(1)
 	wrapper.outEventGroupsTotal.Add(1)
@@ -106,6 +114,7 @@ func (wrapper *ServiceWrapperV1) AddDataArrayWithContext(tags map[string]string,
 }
 
 func (wrapper *ServiceWrapperV1) AddRawLogWithContext(log *protocol.Log, ctx map[string]interface{}) {
+	wrapper.waitResumed()
 	wrapper.outEventsTotal.Add(1)
 	wrapper.outEventGroupsTotal.Add(1)
 	wrapper.outSizeBytes.Add(int64(log.Size()))
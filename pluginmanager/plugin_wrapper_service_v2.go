@@ -14,6 +14,7 @@
 package pluginmanager
 
 import (
+	"github.com/alibaba/ilogtail/pkg/models"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
 )
 
@@ -22,6 +23,10 @@ type ServiceWrapperV2 struct {
 	Input pipeline.ServiceInputV2
 }
 
+func (wrapper *ServiceWrapperV2) pluginInstance() interface{} {
+	return wrapper.Input
+}
+
 func (wrapper *ServiceWrapperV2) Init(pluginMeta *pipeline.PluginMeta) error {
 	wrapper.InitMetricRecord(pluginMeta)
 
@@ -30,5 +35,31 @@ func (wrapper *ServiceWrapperV2) Init(pluginMeta *pipeline.PluginMeta) error {
 }
 
 func (wrapper *ServiceWrapperV2) StartService(pipelineContext pipeline.PipelineContext) error {
-	return wrapper.Input.StartService(pipelineContext)
+	return wrapper.Input.StartService(&pausableContext{
+		collector: &pausableCollector{PipelineCollector: pipelineContext.Collector(), input: &wrapper.InputWrapper},
+	})
+}
+
+// pausableContext passes data collected by a service input to the pipeline unless the input is paused.
+type pausableContext struct {
+	collector pipeline.PipelineCollector
+}
+
+func (c *pausableContext) Collector() pipeline.PipelineCollector {
+	return c.collector
+}
+
+type pausableCollector struct {
+	pipeline.PipelineCollector
+	input *InputWrapper
+}
+
+func (c *pausableCollector) Collect(groupInfo *models.GroupInfo, eventList ...models.PipelineEvent) {
+	c.input.waitResumed()
+	c.PipelineCollector.Collect(groupInfo, eventList...)
+}
+
+func (c *pausableCollector) CollectList(groupEventsList ...*models.PipelineGroupEvents) {
+	c.input.waitResumed()
+	c.PipelineCollector.CollectList(groupEventsList...)
 }
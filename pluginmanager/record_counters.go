@@ -0,0 +1,170 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/selfmonitor"
+)
+
+// RecordCounters is a snapshot of records passing each stage of a config since it was created.
+// Records inherited from a previous instance of the config are counted from the flusher stage.
+type RecordCounters struct {
+	InputRecords         int64 // records received from inputs
+	ProcessorOutRecords  int64 // records passed from processors to aggregators
+	AggregatorOutRecords int64 // records received by flushers from aggregators
+	FlushedRecords       int64 // records flushed by all flushers without error
+
+	ProcessorDroppedRecords  int64 // records dropped by processors
+	AggregatorDroppedRecords int64 // records without content, which are skipped by aggregators
+	FlusherDroppedRecords    int64 // records failed to be flushed by any flusher
+	RateLimitDroppedRecords  int64 // records from inputs dropped over MaxEventsPerSecond or MaxBytesPerSecond
+
+	Panics int64 // panics recovered from goroutines of the config
+}
+
+// recordCounters are maintained by the runner and exported in self monitor metrics of the config.
+// The start latency of the config is exported along with them.
+type recordCounters struct {
+	inputRecords             selfmonitor.CounterMetric
+	processorOutRecords      selfmonitor.CounterMetric
+	aggregatorOutRecords     selfmonitor.CounterMetric
+	flushedRecords           selfmonitor.CounterMetric
+	processorDroppedRecords  selfmonitor.CounterMetric
+	aggregatorDroppedRecords selfmonitor.CounterMetric
+	flusherDroppedRecords    selfmonitor.CounterMetric
+	rateLimitDroppedRecords  selfmonitor.CounterMetric
+	panics                   selfmonitor.CounterMetric
+	startLatencyMs           selfmonitor.GaugeMetric
+	// lastInput is the unix nano time of the latest record from inputs, or the time the counters are created.
+	lastInput atomic.Int64
+	// lastFlush is the unix nano time of the latest successful flush, or 0 if nothing is flushed.
+	lastFlush atomic.Int64
+	// groupSize and recordSize are sampled from groups passed to flushers, byte.
+	groupSize  atomic.Int64
+	recordSize atomic.Int64
+}
+
+func newRecordCounters(context pipeline.Context) *recordCounters {
+	labels := []selfmonitor.LabelPair{
+		{Key: selfmonitor.MetricLabelKeyMetricCategory, Value: selfmonitor.MetricLabelValueMetricCategoryPipeline},
+		{Key: selfmonitor.MetricLabelKeyProject, Value: context.GetProject()},
+		{Key: selfmonitor.MetricLabelKeyLogstore, Value: context.GetLogstore()},
+		{Key: selfmonitor.MetricLabelKeyPipelineName, Value: context.GetConfigName()},
+	}
+	metricRecord := context.RegisterMetricRecord(labels)
+	counters := &recordCounters{
+		inputRecords:             selfmonitor.NewCumulativeCounterMetricAndRegister(metricRecord, selfmonitor.MetricPipelineInRecordsTotal),
+		processorOutRecords:      selfmonitor.NewCumulativeCounterMetricAndRegister(metricRecord, selfmonitor.MetricPipelineProcessorOutRecordsTotal),
+		aggregatorOutRecords:     selfmonitor.NewCumulativeCounterMetricAndRegister(metricRecord, selfmonitor.MetricPipelineAggregatorOutRecordsTotal),
+		flushedRecords:           selfmonitor.NewCumulativeCounterMetricAndRegister(metricRecord, selfmonitor.MetricPipelineFlushedRecordsTotal),
+		processorDroppedRecords:  selfmonitor.NewCumulativeCounterMetricAndRegister(metricRecord, selfmonitor.MetricPipelineProcessorDroppedRecordsTotal),
+		aggregatorDroppedRecords: selfmonitor.NewCumulativeCounterMetricAndRegister(metricRecord, selfmonitor.MetricPipelineAggregatorDroppedRecordsTotal),
+		flusherDroppedRecords:    selfmonitor.NewCumulativeCounterMetricAndRegister(metricRecord, selfmonitor.MetricPipelineFlusherDroppedRecordsTotal),
+		rateLimitDroppedRecords:  selfmonitor.NewCumulativeCounterMetricAndRegister(metricRecord, selfmonitor.MetricPipelineRateLimitDroppedRecordsTotal),
+		panics:                   selfmonitor.NewCumulativeCounterMetricAndRegister(metricRecord, selfmonitor.MetricPipelinePanicsTotal),
+		startLatencyMs:           selfmonitor.NewGaugeMetricAndRegister(metricRecord, selfmonitor.MetricPipelineStartLatencyMs),
+	}
+	counters.lastInput.Store(time.Now().UnixNano())
+	return counters
+}
+
+// processed records the result of passing inCount records through processors.
+// Processors may split records, so only the decreased part is counted as dropped.
+func (c *recordCounters) processed(inCount int, outCount int) {
+	c.inputRecords.Add(int64(inCount))
+	if inCount > 0 {
+		c.lastInput.Store(time.Now().UnixNano())
+	}
+	c.processorOutRecords.Add(int64(outCount))
+	if inCount > outCount {
+		c.processorDroppedRecords.Add(int64(inCount - outCount))
+	}
+}
+
+// rateLimited records count records from inputs dropped by the rate limiter before processors.
+func (c *recordCounters) rateLimited(count int) {
+	c.inputRecords.Add(int64(count))
+	c.rateLimitDroppedRecords.Add(int64(count))
+	c.lastInput.Store(time.Now().UnixNano())
+}
+
+func (c *recordCounters) lastInputTime() time.Time {
+	return time.Unix(0, c.lastInput.Load())
+}
+
+// lastFlushTime returns the zero time if nothing is flushed.
+func (c *recordCounters) lastFlushTime() time.Time {
+	if lastFlush := c.lastFlush.Load(); lastFlush != 0 {
+		return time.Unix(0, lastFlush)
+	}
+	return time.Time{}
+}
+
+// flushed records the result of passing count records to flushers.
+func (c *recordCounters) flushed(count int, success bool) {
+	if success {
+		c.flushedRecords.Add(int64(count))
+		c.lastFlush.Store(time.Now().UnixNano())
+	} else {
+		c.flusherDroppedRecords.Add(int64(count))
+	}
+}
+
+// sampleGroupSize records the size of a group with recordCount records passed to flushers,
+// which is used to estimate the memory of queued data.
+func (c *recordCounters) sampleGroupSize(groupSize int64, recordCount int) {
+	c.groupSize.Store(groupSize)
+	if recordCount > 0 {
+		c.recordSize.Store(groupSize / int64(recordCount))
+	}
+}
+
+func (c *recordCounters) snapshot() RecordCounters {
+	return RecordCounters{
+		InputRecords:             int64(c.inputRecords.Collect().Value),
+		ProcessorOutRecords:      int64(c.processorOutRecords.Collect().Value),
+		AggregatorOutRecords:     int64(c.aggregatorOutRecords.Collect().Value),
+		FlushedRecords:           int64(c.flushedRecords.Collect().Value),
+		ProcessorDroppedRecords:  int64(c.processorDroppedRecords.Collect().Value),
+		AggregatorDroppedRecords: int64(c.aggregatorDroppedRecords.Collect().Value),
+		FlusherDroppedRecords:    int64(c.flusherDroppedRecords.Collect().Value),
+		RateLimitDroppedRecords:  int64(c.rateLimitDroppedRecords.Collect().Value),
+		Panics:                   int64(c.panics.Collect().Value),
+	}
+}
+
+// RecordCounters returns the records passing each stage of the config since it was created.
+func (lc *LogstoreConfig) RecordCounters() RecordCounters {
+	counters := lc.recordCounters()
+	if counters == nil {
+		return RecordCounters{}
+	}
+	return counters.snapshot()
+}
+
+// recordCounters returns nil if the runner of the config has no counters.
+func (lc *LogstoreConfig) recordCounters() *recordCounters {
+	switch r := lc.PluginRunner.(type) {
+	case *pluginv1Runner:
+		return r.recordCounters
+	case *pluginv2Runner:
+		return r.recordCounters
+	}
+	return nil
+}
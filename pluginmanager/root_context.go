@@ -0,0 +1,55 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"sync"
+)
+
+// The runtime contexts of all configs derive from the root context, so that context-aware plugins
+// can abort in-flight work at once when the agent shuts down, instead of waiting for their own Stop.
+var rootContextLock sync.Mutex
+var rootContext, rootCancel = context.WithCancel(context.Background())
+
+// newRuntimeContext derives a cancelable context for a config from the root context.
+func newRuntimeContext() (context.Context, context.CancelFunc) {
+	rootContextLock.Lock()
+	defer rootContextLock.Unlock()
+	return context.WithCancel(rootContext)
+}
+
+// newBuiltinRuntimeContext derives a cancelable context for a built-in config. Built-in configs do not derive
+// from the root context, as they keep running to report alarms of user configs while those are stopped, until
+// StopBuiltInModulesConfig.
+func newBuiltinRuntimeContext() (context.Context, context.CancelFunc) {
+	return context.WithCancel(context.Background())
+}
+
+// cancelRootContext cancels the runtime contexts of all user configs, including those still hanging in
+// DisabledLogtailConfig. Configs created afterwards derive from a new root context.
+func cancelRootContext() {
+	rootContextLock.Lock()
+	defer rootContextLock.Unlock()
+	rootCancel()
+	rootContext, rootCancel = context.WithCancel(context.Background())
+}
+
+// cancelRuntimeContext cancels the runtime context of the config before it is stopped.
+func cancelRuntimeContext(config *LogstoreConfig) {
+	if contextImp, ok := config.Context.(*ContextImp); ok && contextImp.cancel != nil {
+		contextImp.cancel()
+	}
+}
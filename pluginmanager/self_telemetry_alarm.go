@@ -15,6 +15,10 @@
 package pluginmanager
 
 import (
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/alibaba/ilogtail/pkg"
 	"github.com/alibaba/ilogtail/pkg/logger"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
@@ -22,6 +26,16 @@ import (
 	"github.com/alibaba/ilogtail/pkg/util"
 )
 
+// Alarms of configs stopped by Shutdown, reported by the next Collect, as the configs are gone by then.
+var stoppedConfigAlarmsLock sync.Mutex
+var stoppedConfigAlarms []*util.Alarm
+
+func addStoppedConfigAlarms(alarms []*util.Alarm) {
+	stoppedConfigAlarmsLock.Lock()
+	stoppedConfigAlarms = append(stoppedConfigAlarms, alarms...)
+	stoppedConfigAlarmsLock.Unlock()
+}
+
 type InputAlarm struct {
 	context pipeline.Context
 }
@@ -36,15 +50,26 @@ func (r *InputAlarm) Description() string {
 }
 
 func (r *InputAlarm) Collect(collector pipeline.Collector) error {
+	expireUnsendBuffer()
 	loggroup := &protocol.LogGroup{}
-	LogtailConfigLock.RLock()
-	for _, config := range LogtailConfig {
-		alarm := config.Context.GetRuntimeContext().Value(pkg.LogTailMeta).(*pkg.LogtailContextMeta).GetAlarm()
-		if alarm != nil {
+	RangeConfigs(func(_ string, config *LogstoreConfig) bool {
+		if alarm := configAlarm(config); alarm != nil {
 			alarm.SerializeToPb(loggroup)
 		}
+		return true
+	})
+	stoppedConfigAlarmsLock.Lock()
+	for _, alarm := range stoppedConfigAlarms {
+		alarm.SerializeToPb(loggroup)
+	}
+	stoppedConfigAlarms = nil
+	stoppedConfigAlarmsLock.Unlock()
+	for _, config := range GetDisabledConfigs() {
+		if disabledDuration := time.Since(config.DisabledTime); disabledDuration > time.Duration(*DisabledConfigAlarmMinutes)*time.Minute {
+			util.GlobalAlarm.Record("CONFIG_DISABLED_ALARM", fmt.Sprintf("config %s has been stopping for %v, goroutine might leak",
+				config.ConfigNameWithSuffix, disabledDuration.Truncate(time.Second)))
+		}
 	}
-	LogtailConfigLock.RUnlock()
 	util.GlobalAlarm.SerializeToPb(loggroup)
 	if len(loggroup.Logs) > 0 && AlarmConfig != nil {
 		for _, log := range loggroup.Logs {
@@ -56,6 +81,10 @@ func (r *InputAlarm) Collect(collector pipeline.Collector) error {
 	return nil
 }
 
+func configAlarm(config *LogstoreConfig) *util.Alarm {
+	return config.Context.GetRuntimeContext().Value(pkg.LogTailMeta).(*pkg.LogtailContextMeta).GetAlarm()
+}
+
 func init() {
 	pipeline.MetricInputs["metric_alarm"] = func() pipeline.MetricInput {
 		return &InputAlarm{}
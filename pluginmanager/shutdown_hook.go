@@ -0,0 +1,68 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+var ShutdownHookTimeoutMs = flag.Int("ShutdownHookTimeoutMs", 5000, "time to wait for OnAgentShutdown of all plugins when stop all pipelines, ms")
+
+// closePlugins calls OnAgentShutdown of the plugins implementing pipeline.Closer in the stopped configs,
+// and then release for each config. It returns when all of them return or ShutdownHookTimeoutMs is reached,
+// whichever comes first. The ctx passed to hooks is canceled on timeout, and hooks not called by then are
+// skipped. A config whose hook is still running is released only once the hook returns, so that it is not
+// torn down under the hook.
+func closePlugins(ctx context.Context, configs []*LogstoreConfig, release func(config *LogstoreConfig)) {
+	if len(configs) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(*ShutdownHookTimeoutMs)*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, config := range configs {
+			if ctx.Err() == nil {
+				closeConfigPlugins(ctx, config)
+			}
+			release(config)
+		}
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Warning(context.Background(), "PLUGIN_SHUTDOWN_ALARM", "timeout when call shutdown hooks of plugins", ctx.Err())
+	}
+}
+
+func closeConfigPlugins(ctx context.Context, config *LogstoreConfig) {
+	defer panicRecover("OnAgentShutdown")
+	config.PluginRunner.ForEachPlugin(func(p Plugin) {
+		closer, ok := p.pluginInstance().(pipeline.Closer)
+		if !ok {
+			return
+		}
+		if err := closer.OnAgentShutdown(ctx); err != nil {
+			logger.Warning(config.Context.GetRuntimeContext(), "PLUGIN_SHUTDOWN_ALARM", "shutdown hook of plugin fail", err,
+				"plugin", p.PluginType())
+		}
+	})
+}
@@ -0,0 +1,215 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"flag"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+var UnsendBufferTTLSec = flag.Int("UnsendBufferTTLSec", 1800, "drop unsent data of a stopped config if no new version of the config adopts it in time, second, 0 to keep it forever")
+var MaxUnsendBuffers = flag.Int("MaxUnsendBuffers", 256, "max number of stopped configs whose unsent data is kept, the oldest is dropped when exceeded, 0 for unlimited")
+
+// lastUnsendBufferTime is the time each runner is parked in LastUnsendBuffer, protected by LastUnsendBufferLock.
+var lastUnsendBufferTime = make(map[string]time.Time)
+
+// Unsent data adopted from LastUnsendBuffer by new versions of configs, and stopped configs whose unsent
+// data is dropped as no new version adopts it in time, exported by GetAgentStat.
+var unsendBufferRecoveredRecords atomic.Int64
+var unsendBufferRecoveredBytes atomic.Int64
+var unsendBufferDropped atomic.Int64
+
+// GetUnsendBufferSize returns the number of stopped configs whose unsent data is kept in LastUnsendBuffer.
+func GetUnsendBufferSize() int {
+	LastUnsendBufferLock.Lock()
+	defer LastUnsendBufferLock.Unlock()
+	return len(LastUnsendBuffer)
+}
+
+// PendingUnsentConfigs returns names with suffix of the stopped configs whose unsent data is kept in
+// LastUnsendBuffer, to be adopted by the next version of the config, ordered by name.
+func PendingUnsentConfigs() []string {
+	LastUnsendBufferLock.Lock()
+	defer LastUnsendBufferLock.Unlock()
+	names := make(map[string]struct{}, len(LastUnsendBuffer))
+	for key := range LastUnsendBuffer {
+		names[unsendBufferConfigName(key)] = struct{}{}
+	}
+	configs := make([]string, 0, len(names))
+	for name := range names {
+		configs = append(configs, name)
+	}
+	sort.Strings(configs)
+	return configs
+}
+
+// DiscardUnsentBuffer drops unsent data of all stopped versions of the config, which will not be
+// adopted by the next version. ConfigName is with suffix.
+func DiscardUnsentBuffer(configName string) {
+	LastUnsendBufferLock.Lock()
+	defer LastUnsendBufferLock.Unlock()
+	for key := range LastUnsendBuffer {
+		if unsendBufferConfigName(key) == configName {
+			logger.Info(context.Background(), "discard unsent data of stopped config", key)
+			delete(LastUnsendBuffer, key)
+			delete(lastUnsendBufferTime, key)
+		}
+	}
+}
+
+// parkUnsendBuffer keeps the runner of a stopped config in LastUnsendBuffer until a new version of the config
+// adopts it, and drops expired ones.
+func parkUnsendBuffer(key string, runner PluginRunner) {
+	LastUnsendBufferLock.Lock()
+	defer LastUnsendBufferLock.Unlock()
+	LastUnsendBuffer[key] = runner
+	lastUnsendBufferTime[key] = time.Now()
+	expireUnsendBufferLocked()
+}
+
+// expireUnsendBuffer drops runners in LastUnsendBuffer not adopted within UnsendBufferTTLSec.
+func expireUnsendBuffer() {
+	LastUnsendBufferLock.Lock()
+	defer LastUnsendBufferLock.Unlock()
+	expireUnsendBufferLocked()
+}
+
+func expireUnsendBufferLocked() {
+	now := time.Now()
+	keys := make([]string, 0, len(LastUnsendBuffer))
+	for key := range LastUnsendBuffer {
+		if _, ok := lastUnsendBufferTime[key]; !ok {
+			lastUnsendBufferTime[key] = now
+		}
+		keys = append(keys, key)
+	}
+	for key := range lastUnsendBufferTime {
+		if _, ok := LastUnsendBuffer[key]; !ok {
+			delete(lastUnsendBufferTime, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return lastUnsendBufferTime[keys[i]].Before(lastUnsendBufferTime[keys[j]])
+	})
+	ttl := time.Duration(*UnsendBufferTTLSec) * time.Second
+	for i, key := range keys {
+		age := now.Sub(lastUnsendBufferTime[key])
+		expired := *UnsendBufferTTLSec > 0 && age > ttl
+		overflow := *MaxUnsendBuffers > 0 && len(keys)-i > *MaxUnsendBuffers
+		if !expired && !overflow {
+			break
+		}
+		records, bytes := unsendBufferSize(LastUnsendBuffer[key])
+		logger.Warning(context.Background(), "UNSEND_BUFFER_LEAK_ALARM", "drop unsent data of stopped config", key,
+			"age", age.Truncate(time.Second), "buffers", len(keys)-i, "records", records, "bytes", bytes)
+		unsendBufferDropped.Add(1)
+		delete(LastUnsendBuffer, key)
+		delete(lastUnsendBufferTime, key)
+	}
+}
+
+// unsendBufferSize returns the number of events and bytes kept in the runner of a stopped config.
+func unsendBufferSize(runner PluginRunner) (records int64, bytes int64) {
+	switch r := runner.(type) {
+	case *pluginv1Runner:
+		for _, group := range r.FlushOutStore.Get() {
+			records += int64(len(group.Logs))
+			bytes += int64(group.Size())
+		}
+	case *pluginv2Runner:
+		for _, group := range r.FlushOutStore.Get() {
+			records += int64(len(group.Events))
+			bytes += groupEventsSize(group)
+		}
+	}
+	return records, bytes
+}
+
+// mergeQueue holds stopped runners handed over to a running runner by handOverUnsendBuffer. The flusher
+// goroutine of the running runner merges and flushes them, so that its FlushOutStore is only touched by it.
+type mergeQueue struct {
+	lock    sync.Mutex
+	runners []PluginRunner
+	notify  chan struct{}
+}
+
+func newMergeQueue() *mergeQueue {
+	return &mergeQueue{notify: make(chan struct{}, 1)}
+}
+
+// add queues the stopped runner, and wakes up the flusher goroutine.
+func (q *mergeQueue) add(runner PluginRunner) {
+	q.lock.Lock()
+	q.runners = append(q.runners, runner)
+	q.lock.Unlock()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the queued runners and empties the queue. It is safe on a nil queue.
+func (q *mergeQueue) take() []PluginRunner {
+	if q == nil {
+		return nil
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	runners := q.runners
+	q.runners = nil
+	return runners
+}
+
+// takeUnsendBuffer removes unsent data of all previous versions of the config from LastUnsendBuffer.
+func takeUnsendBuffer(config *LogstoreConfig) []PluginRunner {
+	LastUnsendBufferLock.Lock()
+	defer LastUnsendBufferLock.Unlock()
+	var runners []PluginRunner
+	for key, runner := range LastUnsendBuffer {
+		if unsendBufferConfigName(key) == config.ConfigNameWithSuffix {
+			records, bytes := unsendBufferSize(runner)
+			runners = append(runners, runner)
+			delete(LastUnsendBuffer, key)
+			delete(lastUnsendBufferTime, key)
+			unsendBufferRecoveredRecords.Add(records)
+			unsendBufferRecoveredBytes.Add(bytes)
+			logger.Info(config.Context.GetRuntimeContext(), "adopt unsent data of stopped config", key, "records", records, "bytes", bytes)
+		}
+	}
+	return runners
+}
+
+// handOverUnsendBuffer moves unsent data of all previous versions of the running config into its runner,
+// to be sent by its flusher goroutine.
+func handOverUnsendBuffer(config *LogstoreConfig) {
+	var queue *mergeQueue
+	switch runner := config.PluginRunner.(type) {
+	case *pluginv1Runner:
+		queue = runner.mergeQueue
+	case *pluginv2Runner:
+		queue = runner.mergeQueue
+	default:
+		return
+	}
+	for _, runner := range takeUnsendBuffer(config) {
+		queue.add(runner)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+var StopGracePeriodMs = flag.Int("StopGracePeriodMs", 0, "grace period before a config stopped without removal is really stopped, it is reused if loaded again unchanged within the period, 0 to disable")
+
+// warmConfig is a config stopped without removal whose plugins are still running.
+type warmConfig struct {
+	config *LogstoreConfig
+	timer  *time.Timer
+}
+
+var warmConfigLock sync.Mutex
+var warmConfigs = make(map[string]*warmConfig)
+
+// parkWarmConfig keeps the config running for StopGracePeriodMs before stopping it really,
+// so that a config loaded again unchanged can reuse it without reconnecting flushers.
+func parkWarmConfig(config *LogstoreConfig) {
+	configName := config.ConfigNameWithSuffix
+	w := &warmConfig{config: config}
+	warmConfigLock.Lock()
+	previous := warmConfigs[configName]
+	warmConfigs[configName] = w
+	w.timer = time.AfterFunc(time.Duration(*StopGracePeriodMs)*time.Millisecond, func() {
+		if takeWarmConfigIf(configName, func(c *warmConfig) bool { return c == w }) != nil {
+			stopWarmConfig(config, false)
+		}
+	})
+	warmConfigLock.Unlock()
+	logger.Info(config.Context.GetRuntimeContext(), "park config", "begin", "grace period ms", *StopGracePeriodMs)
+	if previous != nil && previous.timer.Stop() {
+		stopWarmConfig(previous.config, false)
+	}
+}
+
+// takeWarmConfigIf removes the warm config from the pool and returns it if match returns true.
+func takeWarmConfigIf(configName string, match func(*warmConfig) bool) *LogstoreConfig {
+	warmConfigLock.Lock()
+	defer warmConfigLock.Unlock()
+	if w, ok := warmConfigs[configName]; ok && match(w) {
+		delete(warmConfigs, configName)
+		return w.config
+	}
+	return nil
+}
+
+// reuseWarmConfig returns the warm config if it has the same detail as the config being loaded.
+// A warm config with a different detail is stopped at once, so that its unsent data can be adopted.
+func reuseWarmConfig(project string, logstore string, configName string, logstoreKey int64, jsonStr string) *LogstoreConfig {
+	detailHash := fmt.Sprintf("%x", md5.Sum([]byte(jsonStr))) //nolint:gosec
+	config := takePendingWarmConfig(configName)
+	if config == nil {
+		return nil
+	}
+	if config.ProjectName != project || config.LogstoreName != logstore ||
+		config.LogstoreKey != logstoreKey || config.configDetailHash != detailHash {
+		stopWarmConfig(config, false)
+		return nil
+	}
+	logger.Info(config.Context.GetRuntimeContext(), "reuse parked config", configName)
+	config.warmReused = true
+	return config
+}
+
+// discardWarmConfig stops the warm config at once if there is one.
+func discardWarmConfig(configName string, removedFlag bool) {
+	if config := takePendingWarmConfig(configName); config != nil {
+		stopWarmConfig(config, removedFlag)
+	}
+}
+
+// takePendingWarmConfig removes the warm config from the pool if its timer has not fired yet.
+func takePendingWarmConfig(configName string) *LogstoreConfig {
+	return takeWarmConfigIf(configName, func(w *warmConfig) bool {
+		// The timer has fired if Stop returns false, leave the config to it.
+		return w.timer.Stop()
+	})
+}
+
+// unloadWarmReusedConfig puts a reused config that will not be started back to the warm pool.
+func unloadWarmReusedConfig(config *LogstoreConfig) {
+	if config.warmReused {
+		config.warmReused = false
+		parkWarmConfig(config)
+	}
+}
+
+// stopWarmConfigs stops all warm configs matching withInput at once.
+func stopWarmConfigs(withInput bool, removedFlag bool) {
+	warmConfigLock.Lock()
+	toStop := make([]*LogstoreConfig, 0)
+	for configName, w := range warmConfigs {
+		if w.config.PluginRunner.IsWithInputPlugin() == withInput && w.timer.Stop() {
+			toStop = append(toStop, w.config)
+			delete(warmConfigs, configName)
+		}
+	}
+	warmConfigLock.Unlock()
+	for _, config := range toStop {
+		cancelRuntimeContext(config)
+	}
+	for _, config := range toStop {
+		stopWarmConfig(config, removedFlag)
+	}
+}
+
+func stopWarmConfig(config *LogstoreConfig, removedFlag bool) {
+	logger.Info(config.Context.GetRuntimeContext(), "Stop parked config", config.ConfigNameWithSuffix)
+	if hasStopped := timeoutStop(config, removedFlag); !hasStopped {
+		notifyConfigState(config.ConfigNameWithSuffix, ConfigStateDisabled)
+		return
+	}
+	DeleteLogstoreConfig(config, removedFlag)
+	notifyConfigState(config.ConfigNameWithSuffix, ConfigStateStopped)
+	logger.Debug(context.Background(), "parked config stopped", config.ConfigNameWithSuffix)
+}
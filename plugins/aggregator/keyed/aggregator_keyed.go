@@ -0,0 +1,208 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyed
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/pkg/util"
+	"github.com/alibaba/ilogtail/plugins/aggregator/baseagg"
+)
+
+const (
+	pluginType = "aggregator_keyed"
+
+	defaultMaxKeys = 1000
+)
+
+// batch is the log group being built for a key.
+type batch struct {
+	key    string
+	group  *protocol.LogGroup
+	size   int
+	pack   string
+	packID int64
+	// sealed is set once the log group is completed, it may be sealed before a failed send.
+	sealed bool
+}
+
+// AggregatorKeyed batches logs by the value of KeyField, such as a tenant id, so that each log group holds
+// logs of one key, tagged with TagKey. A batch is sent once it reaches MaxLogCount or MaxLogGroupBytes,
+// and all batches are flushed every FlushIntervalMs. At most MaxKeys batches are kept, the batch of the
+// least recently used key is sent to make room for a new key.
+type AggregatorKeyed struct {
+	KeyField         string
+	TagKey           string // KeyField by default
+	MaxLogCount      int
+	MaxLogGroupBytes int
+	FlushIntervalMs  int // AggregatIntervalMs of the global config by default
+	MaxKeys          int
+	Topic            string
+	EnablePackID     bool
+
+	lock    sync.Mutex
+	batches map[string]*list.Element
+	lru     *list.List // batches from the most to the least recently used
+	context pipeline.Context
+	queue   pipeline.LogGroupQueue
+}
+
+func (*AggregatorKeyed) Description() string {
+	return "aggregator that batches logs by the value of a field"
+}
+
+func (a *AggregatorKeyed) Init(context pipeline.Context, que pipeline.LogGroupQueue) (int, error) {
+	a.context = context
+	a.queue = que
+	if a.KeyField == "" {
+		return 0, fmt.Errorf("must specify KeyField for plugin %v", pluginType)
+	}
+	if a.TagKey == "" {
+		a.TagKey = a.KeyField
+	}
+	if a.MaxLogCount <= 0 {
+		a.MaxLogCount = baseagg.MaxLogCount
+	}
+	if a.MaxLogGroupBytes <= 0 {
+		a.MaxLogGroupBytes = baseagg.MaxLogGroupSize
+	}
+	if a.MaxKeys <= 0 {
+		a.MaxKeys = defaultMaxKeys
+	}
+	a.batches = make(map[string]*list.Element)
+	a.lru = list.New()
+	return a.FlushIntervalMs, nil
+}
+
+// Add appends the log to the batch of its key. A full batch, or the batch evicted for a new key, is sent
+// to the queue first. If the queue is full, the log is not added and the error is returned, so that the
+// caller retries.
+func (a *AggregatorKeyed) Add(log *protocol.Log, ctx map[string]interface{}) error {
+	key := a.keyOf(log)
+	logSize := log.Size()
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	element, ok := a.batches[key]
+	if !ok {
+		if a.lru.Len() >= a.MaxKeys {
+			if err := a.sendLocked(a.lru.Back()); err != nil {
+				return err
+			}
+		}
+		element = a.lru.PushFront(a.newBatch(key))
+		a.batches[key] = element
+	}
+	a.lru.MoveToFront(element)
+	b := element.Value.(*batch)
+	if len(b.group.Logs) > 0 && (len(b.group.Logs) >= a.MaxLogCount || b.size+logSize > a.MaxLogGroupBytes) {
+		if err := a.queue.Add(a.seal(b)); err != nil {
+			return err
+		}
+		a.resetBatch(b)
+	}
+	b.group.Logs = append(b.group.Logs, log)
+	b.size += logSize
+	return nil
+}
+
+// Flush returns batches of all keys, and forgets the keys.
+func (a *AggregatorKeyed) Flush() []*protocol.LogGroup {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	logGroups := make([]*protocol.LogGroup, 0, a.lru.Len())
+	for element := a.lru.Front(); element != nil; element = element.Next() {
+		if b := element.Value.(*batch); len(b.group.Logs) > 0 {
+			logGroups = append(logGroups, a.seal(b))
+		}
+	}
+	a.batches = make(map[string]*list.Element)
+	a.lru.Init()
+	return logGroups
+}
+
+func (a *AggregatorKeyed) Reset() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.batches = make(map[string]*list.Element)
+	a.lru.Init()
+}
+
+// sendLocked sends the batch of the element to the queue and forgets its key.
+func (a *AggregatorKeyed) sendLocked(element *list.Element) error {
+	b := element.Value.(*batch)
+	if len(b.group.Logs) > 0 {
+		if err := a.queue.Add(a.seal(b)); err != nil {
+			logger.Warning(a.context.GetRuntimeContext(), "AGGREGATOR_KEYED_ALARM", "evict batch error, key", b.key, "error", err)
+			return err
+		}
+	}
+	a.lru.Remove(element)
+	delete(a.batches, b.key)
+	return nil
+}
+
+func (a *AggregatorKeyed) keyOf(log *protocol.Log) string {
+	for _, content := range log.Contents {
+		if content.Key == a.KeyField {
+			return content.Value
+		}
+	}
+	return ""
+}
+
+func (a *AggregatorKeyed) newBatch(key string) *batch {
+	b := &batch{key: key}
+	if a.EnablePackID {
+		b.pack = util.NewPackIDPrefix(a.context.GetConfigName() + key)
+	}
+	a.resetBatch(b)
+	return b
+}
+
+func (a *AggregatorKeyed) resetBatch(b *batch) {
+	b.group = &protocol.LogGroup{Logs: make([]*protocol.Log, 0, a.MaxLogCount)}
+	b.size = 0
+	b.sealed = false
+}
+
+// seal completes the log group of the batch with the topic and tags.
+func (a *AggregatorKeyed) seal(b *batch) *protocol.LogGroup {
+	group := b.group
+	if b.sealed {
+		return group
+	}
+	b.sealed = true
+	group.Topic = a.Topic
+	group.LogTags = append(group.LogTags, &protocol.LogTag{Key: a.TagKey, Value: b.key})
+	if a.EnablePackID {
+		group.LogTags = append(group.LogTags, util.NewLogTagForPackID(b.pack, &b.packID))
+	}
+	return group
+}
+
+func init() {
+	pipeline.Aggregators[pluginType] = func() pipeline.Aggregator {
+		return &AggregatorKeyed{
+			MaxKeys:      defaultMaxKeys,
+			EnablePackID: true,
+		}
+	}
+}
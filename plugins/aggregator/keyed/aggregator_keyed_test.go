@@ -0,0 +1,146 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyed
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/pkg/util"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+type mockQueue struct {
+	logGroups []*protocol.LogGroup
+	full      bool
+}
+
+func (q *mockQueue) Add(logGroup *protocol.LogGroup) error {
+	if q.full {
+		return errors.New("queue is full")
+	}
+	q.logGroups = append(q.logGroups, logGroup)
+	return nil
+}
+
+func (q *mockQueue) AddWithWait(logGroup *protocol.LogGroup, duration time.Duration) error {
+	return q.Add(logGroup)
+}
+
+func newAggregator(t *testing.T, maxLogCount, maxKeys int) (*AggregatorKeyed, *mockQueue) {
+	agg := &AggregatorKeyed{KeyField: "tenant", MaxLogCount: maxLogCount, MaxKeys: maxKeys, EnablePackID: true}
+	que := &mockQueue{}
+	_, err := agg.Init(mock.NewEmptyContext("p", "l", "c"), que)
+	require.NoError(t, err)
+	return agg, que
+}
+
+func newLog(tenant string) *protocol.Log {
+	return &protocol.Log{Contents: []*protocol.Log_Content{{Key: "tenant", Value: tenant}, {Key: "content", Value: "test"}}}
+}
+
+func tagValue(logGroup *protocol.LogGroup, key string) string {
+	for _, tag := range logGroup.LogTags {
+		if tag.Key == key {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+func TestInit(t *testing.T) {
+	agg := &AggregatorKeyed{}
+	_, err := agg.Init(mock.NewEmptyContext("p", "l", "c"), &mockQueue{})
+	assert.Error(t, err)
+
+	agg = &AggregatorKeyed{KeyField: "tenant", FlushIntervalMs: 500}
+	interval, err := agg.Init(mock.NewEmptyContext("p", "l", "c"), &mockQueue{})
+	assert.NoError(t, err)
+	assert.Equal(t, 500, interval)
+	assert.Equal(t, "tenant", agg.TagKey)
+	assert.Equal(t, defaultMaxKeys, agg.MaxKeys)
+}
+
+func TestBatchByKey(t *testing.T) {
+	agg, que := newAggregator(t, 10, 10)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, agg.Add(newLog("a"), nil))
+		require.NoError(t, agg.Add(newLog("b"), nil))
+	}
+	require.NoError(t, agg.Add(&protocol.Log{Contents: []*protocol.Log_Content{{Key: "content", Value: "no tenant"}}}, nil))
+	assert.Empty(t, que.logGroups)
+
+	logGroups := agg.Flush()
+	require.Len(t, logGroups, 3)
+	counts := make(map[string]int)
+	for _, logGroup := range logGroups {
+		counts[tagValue(logGroup, "tenant")] = len(logGroup.Logs)
+		assert.NotEmpty(t, tagValue(logGroup, util.PackIDTagKey))
+	}
+	assert.Equal(t, map[string]int{"a": 3, "b": 3, "": 1}, counts)
+	assert.Empty(t, agg.Flush())
+}
+
+func TestSendFullBatch(t *testing.T) {
+	agg, que := newAggregator(t, 2, 10)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, agg.Add(newLog("a"), nil))
+	}
+	require.Len(t, que.logGroups, 2)
+	for _, logGroup := range que.logGroups {
+		assert.Len(t, logGroup.Logs, 2)
+		assert.Equal(t, "a", tagValue(logGroup, "tenant"))
+	}
+	logGroups := agg.Flush()
+	require.Len(t, logGroups, 1)
+	assert.Len(t, logGroups[0].Logs, 1)
+
+	// bytes are limited as well
+	agg.MaxLogGroupBytes = newLog("a").Size() * 3 / 2
+	require.NoError(t, agg.Add(newLog("a"), nil))
+	require.NoError(t, agg.Add(newLog("a"), nil))
+	assert.Len(t, que.logGroups, 3)
+}
+
+func TestEvictLeastRecentlyUsedKey(t *testing.T) {
+	agg, que := newAggregator(t, 10, 2)
+	require.NoError(t, agg.Add(newLog("a"), nil))
+	require.NoError(t, agg.Add(newLog("b"), nil))
+	require.NoError(t, agg.Add(newLog("a"), nil))
+	// b is the least recently used
+	require.NoError(t, agg.Add(newLog("c"), nil))
+	require.Len(t, que.logGroups, 1)
+	assert.Equal(t, "b", tagValue(que.logGroups[0], "tenant"))
+
+	// the log is not added if the evicted batch can not be sent
+	que.full = true
+	assert.Error(t, agg.Add(newLog("d"), nil))
+	que.full = false
+	require.NoError(t, agg.Add(newLog("d"), nil))
+	require.Len(t, que.logGroups, 2)
+	assert.Equal(t, "a", tagValue(que.logGroups[1], "tenant"))
+	assert.Len(t, que.logGroups[1].Logs, 2)
+	assert.Len(t, que.logGroups[1].LogTags, 2, "tags are added once")
+
+	logGroups := agg.Flush()
+	require.Len(t, logGroups, 2)
+	assert.Equal(t, "d", tagValue(logGroups[0], "tenant"))
+	assert.Equal(t, "c", tagValue(logGroups[1], "tenant"))
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compression provides the codecs flushers use to compress the data they send,
+// selected by the Compression and CompressionLevel parameters of a flusher.
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+const (
+	Gzip   = "gzip"
+	Snappy = "snappy"
+	Zstd   = "zstd"
+	LZ4    = "lz4"
+)
+
+// ErrUnsupported is returned by NewCodec if the compression type is unknown.
+var ErrUnsupported = errors.New("unsupported compression")
+
+// Codec compresses data. The name of a codec is the value of the Content-Encoding header of the data.
+// A codec is safe for concurrent use.
+type Codec interface {
+	Name() string
+	Encode(data []byte) ([]byte, error)
+}
+
+// NewCodec returns the codec of the compression type with level, where level 0 means the default level
+// of the type. It returns nil if compressionType is empty, which means no compression.
+// Levels are 1-9 for gzip and lz4, and 1-22 for zstd, which are mapped to the nearest level of the
+// encoder. Snappy has no level.
+func NewCodec(compressionType string, level int) (Codec, error) {
+	switch compressionType {
+	case "":
+		return nil, nil
+	case Gzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		} else if level < gzip.BestSpeed || level > gzip.BestCompression {
+			return nil, fmt.Errorf("invalid gzip level %d", level)
+		}
+		return &gzipCodec{level: level}, nil
+	case Snappy:
+		return snappyCodec{}, nil
+	case Zstd:
+		encoderLevel := zstd.SpeedDefault
+		if level < 0 || level > 22 {
+			return nil, fmt.Errorf("invalid zstd level %d", level)
+		} else if level > 0 {
+			encoderLevel = zstd.EncoderLevelFromZstd(level)
+		}
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(encoderLevel))
+		if err != nil {
+			return nil, err
+		}
+		return &zstdCodec{encoder: encoder}, nil
+	case LZ4:
+		if level < 0 || level > len(lz4Levels) {
+			return nil, fmt.Errorf("invalid lz4 level %d", level)
+		}
+		codec := &lz4Codec{level: lz4.Fast}
+		if level > 0 {
+			codec.level = lz4Levels[level-1]
+		}
+		return codec, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, compressionType)
+	}
+}
+
+type gzipCodec struct {
+	level int
+}
+
+func (*gzipCodec) Name() string {
+	return Gzip
+}
+
+func (c *gzipCodec) Encode(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type snappyCodec struct{}
+
+func (snappyCodec) Name() string {
+	return Snappy
+}
+
+func (snappyCodec) Encode(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+type zstdCodec struct {
+	encoder *zstd.Encoder
+}
+
+func (*zstdCodec) Name() string {
+	return Zstd
+}
+
+func (c *zstdCodec) Encode(data []byte) ([]byte, error) {
+	return c.encoder.EncodeAll(data, nil), nil
+}
+
+var lz4Levels = []lz4.CompressionLevel{
+	lz4.Level1, lz4.Level2, lz4.Level3, lz4.Level4, lz4.Level5, lz4.Level6, lz4.Level7, lz4.Level8, lz4.Level9,
+}
+
+type lz4Codec struct {
+	level lz4.CompressionLevel
+}
+
+func (*lz4Codec) Name() string {
+	return LZ4
+}
+
+func (c *lz4Codec) Encode(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := lz4.NewWriter(&buf)
+	if err := writer.Apply(lz4.CompressionLevelOption(c.level)); err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
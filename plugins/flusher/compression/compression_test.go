@@ -0,0 +1,96 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, name string, data []byte) []byte {
+	var reader io.Reader
+	switch name {
+	case Gzip:
+		gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		reader = gzipReader
+	case Snappy:
+		decoded, err := snappy.Decode(nil, data)
+		require.NoError(t, err)
+		return decoded
+	case Zstd:
+		decoder, err := zstd.NewReader(nil)
+		require.NoError(t, err)
+		defer decoder.Close()
+		decoded, err := decoder.DecodeAll(data, nil)
+		require.NoError(t, err)
+		return decoded
+	case LZ4:
+		reader = lz4.NewReader(bytes.NewReader(data))
+	}
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return decoded
+}
+
+func TestCodec(t *testing.T) {
+	data := []byte(strings.Repeat("weather,location=hangzhou value=30 1668653452000000000\n", 100))
+	for _, tt := range []struct {
+		name  string
+		level int
+	}{
+		{Gzip, 0}, {Gzip, 9},
+		{Snappy, 0},
+		{Zstd, 0}, {Zstd, 3}, {Zstd, 19},
+		{LZ4, 0}, {LZ4, 9},
+	} {
+		codec, err := NewCodec(tt.name, tt.level)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.name, codec.Name())
+		encoded, err := codec.Encode(data)
+		require.NoError(t, err, tt.name)
+		assert.Less(t, len(encoded), len(data), tt.name)
+		assert.Equal(t, data, decode(t, tt.name, encoded), tt.name)
+	}
+}
+
+func TestNewCodec(t *testing.T) {
+	codec, err := NewCodec("", 3)
+	assert.NoError(t, err)
+	assert.Nil(t, codec)
+
+	_, err = NewCodec("brotli", 0)
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	for _, tt := range []struct {
+		name  string
+		level int
+	}{
+		{Gzip, 10}, {Gzip, -2}, {Zstd, 23}, {LZ4, 10},
+	} {
+		_, err = NewCodec(tt.name, tt.level)
+		assert.Error(t, err, tt.name)
+		assert.NotErrorIs(t, err, ErrUnsupported, tt.name)
+	}
+}
@@ -16,7 +16,6 @@ package http
 
 import (
 	"bytes"
-	"compress/gzip"
 	"crypto/rand"
 	"errors"
 	"fmt"
@@ -27,8 +26,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/golang/snappy"
-
 	"github.com/alibaba/ilogtail/pkg/fmtstr"
 	"github.com/alibaba/ilogtail/pkg/helper"
 	"github.com/alibaba/ilogtail/pkg/logger"
@@ -37,6 +34,7 @@ import (
 	"github.com/alibaba/ilogtail/pkg/pipeline/extensions"
 	"github.com/alibaba/ilogtail/pkg/protocol"
 	converter "github.com/alibaba/ilogtail/pkg/protocol/converter"
+	"github.com/alibaba/ilogtail/plugins/flusher/compression"
 )
 
 const (
@@ -54,11 +52,6 @@ var contentTypeMaps = map[string]string{
 	converter.EncodingCustom:   defaultContentType,
 }
 
-var supportedCompressionType = map[string]any{
-	"gzip":   nil,
-	"snappy": nil,
-}
-
 type retryConfig struct {
 	Enable        bool          // If enable retry, default is true
 	MaxRetryTimes int           // Max retry times, default is 3
@@ -89,7 +82,8 @@ type FlusherHTTP struct {
 	RequestInterceptors    []extensions.ExtensionConfig // custom request interceptor settings
 	QueueCapacity          int                          // capacity of channel
 	DropEventWhenQueueFull bool                         // If true, pipeline events will be dropped when the queue is full
-	Compression            string                       // Compression type, support gzip and snappy at this moment.
+	Compression            string                       // Compression type, support gzip, snappy, zstd and lz4.
+	CompressionLevel       int                          // Compression level, 0 means the default level of the compression type.
 
 	varKeys []string
 
@@ -98,6 +92,7 @@ type FlusherHTTP struct {
 	converter   *converter.Converter
 	client      Client
 	interceptor extensions.FlushInterceptor
+	codec       compression.Codec
 
 	queue   chan interface{}
 	counter sync.WaitGroup
@@ -182,11 +177,16 @@ func (f *FlusherHTTP) Init(context pipeline.Context) error {
 	}
 
 	f.buildVarKeys()
+	if err = f.initCodec(); err != nil {
+		logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "http flusher init compression fail, error", err)
+		return err
+	}
 	f.fillRequestContentType()
 
 	logger.Info(f.context.GetRuntimeContext(), "http flusher init", "initialized",
 		"timeout", f.Timeout,
-		"compression", f.Compression)
+		"compression", f.Compression,
+		"compression level", f.CompressionLevel)
 	return nil
 }
 
@@ -225,6 +225,22 @@ func (f *FlusherHTTP) Stop() error {
 	return nil
 }
 
+// CloseIdle closes idle connections if the endpoint is RemoteURL or its host.
+func (f *FlusherHTTP) CloseIdle(endpoint string) (bool, error) {
+	if endpoint != f.RemoteURL {
+		u, err := url.Parse(f.RemoteURL)
+		if err != nil || (endpoint != u.Host && endpoint != u.Hostname()) {
+			return false, nil
+		}
+	}
+	closer, ok := f.client.(interface{ CloseIdleConnections() })
+	if !ok {
+		return false, nil
+	}
+	closer.CloseIdleConnections()
+	return true, nil
+}
+
 func (f *FlusherHTTP) SetHTTPClient(client Client) {
 	f.client = client
 }
@@ -491,27 +507,34 @@ func (f *FlusherHTTP) getNextRetryDelay(retryTime int) time.Duration {
 	return time.Duration(harf + jitter.Int64())
 }
 
+// initCodec creates the codec by Compression, or by the Content-Encoding header if Compression is empty.
+// Unsupported compression types are ignored, and data are sent without compression.
+func (f *FlusherHTTP) initCodec() error {
+	compressionType := f.Compression
+	if compressionType == "" {
+		compressionType = f.Headers[contentEncodingHeader]
+	}
+	codec, err := compression.NewCodec(compressionType, f.CompressionLevel)
+	if errors.Is(err, compression.ErrUnsupported) {
+		logger.Warning(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "http flusher ignore compression", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	f.codec = codec
+	return nil
+}
+
 func (f *FlusherHTTP) compressData(data []byte) (io.Reader, error) {
-	var reader io.Reader = bytes.NewReader(data)
-	if compressionType, ok := f.Headers[contentEncodingHeader]; ok {
-		switch compressionType {
-		case "gzip":
-			var buf bytes.Buffer
-			gw := gzip.NewWriter(&buf)
-			if _, err := gw.Write(data); err != nil {
-				return nil, err
-			}
-			if err := gw.Close(); err != nil {
-				return nil, err
-			}
-			reader = &buf
-		case "snappy":
-			compressedData := snappy.Encode(nil, data)
-			reader = bytes.NewReader(compressedData)
-		default:
-		}
+	if f.codec == nil {
+		return bytes.NewReader(data), nil
 	}
-	return reader, nil
+	compressedData, err := f.codec.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(compressedData), nil
 }
 
 func (f *FlusherHTTP) flush(data []byte, varValues map[string]string) (ok, retryable bool, err error) {
@@ -625,10 +648,8 @@ func (f *FlusherHTTP) fillRequestContentType() {
 		f.Headers = make(map[string]string, 4)
 	}
 
-	if f.Compression != "" {
-		if _, ok := supportedCompressionType[f.Compression]; ok {
-			f.Headers[contentEncodingHeader] = f.Compression
-		}
+	if f.codec != nil {
+		f.Headers[contentEncodingHeader] = f.codec.Name()
 	}
 
 	_, ok := f.Headers[contentTypeHeader]
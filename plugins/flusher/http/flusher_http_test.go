@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/jarcoal/httpmock"
+	"github.com/klauspost/compress/zstd"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/stretchr/testify/assert"
 
@@ -701,6 +702,21 @@ func TestGetNextRetryDelay(t *testing.T) {
 	}
 }
 
+func TestHttpFlusherCloseIdle(t *testing.T) {
+	f := &FlusherHTTP{
+		RemoteURL: "http://test.com:8080/write",
+		client:    &http.Client{},
+	}
+	for _, endpoint := range []string{"http://test.com:8080/write", "test.com:8080", "test.com"} {
+		closed, err := f.CloseIdle(endpoint)
+		assert.NoError(t, err)
+		assert.True(t, closed, endpoint)
+	}
+	closed, err := f.CloseIdle("other.com")
+	assert.NoError(t, err)
+	assert.False(t, closed)
+}
+
 func TestHttpFlusherFlushWithInterceptor(t *testing.T) {
 	Convey("Given a http flusher with sync intercepter", t, func() {
 		mockIntercepter := &mockInterceptor{}
@@ -1018,6 +1034,57 @@ func TestFlusherHTTP_GzipCompression(t *testing.T) {
 	})
 }
 
+func TestFlusherHTTP_Compression(t *testing.T) {
+	Convey("Given a http flusher with compression zstd and level 3", t, func() {
+		flusher := &FlusherHTTP{
+			RemoteURL:        "http://test.com/write",
+			Convert:          helper.ConvertConfig{Protocol: converter.ProtocolInfluxdb, Encoding: converter.EncodingCustom},
+			Timeout:          defaultTimeout,
+			Concurrency:      1,
+			Compression:      "zstd",
+			CompressionLevel: 3,
+		}
+		So(flusher.Init(&mockContext{}), ShouldBeNil)
+		So(flusher.Headers["Content-Encoding"], ShouldEqual, "zstd")
+
+		Convey("Then data are compressed by zstd", func() {
+			reader, err := flusher.compressData([]byte("weather,location=hangzhou value=30"))
+			So(err, ShouldBeNil)
+			decoder, err := zstd.NewReader(reader)
+			So(err, ShouldBeNil)
+			defer decoder.Close()
+			data, err := io.ReadAll(decoder)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "weather,location=hangzhou value=30")
+		})
+	})
+
+	Convey("Given a http flusher with an invalid compression level", t, func() {
+		flusher := &FlusherHTTP{
+			RemoteURL:        "http://test.com/write",
+			Convert:          helper.ConvertConfig{Protocol: converter.ProtocolInfluxdb, Encoding: converter.EncodingCustom},
+			Timeout:          defaultTimeout,
+			Concurrency:      1,
+			Compression:      "gzip",
+			CompressionLevel: 10,
+		}
+		So(flusher.Init(&mockContext{}), ShouldNotBeNil)
+	})
+
+	Convey("Given a http flusher with an unsupported compression", t, func() {
+		flusher := &FlusherHTTP{
+			RemoteURL:   "http://test.com/write",
+			Convert:     helper.ConvertConfig{Protocol: converter.ProtocolInfluxdb, Encoding: converter.EncodingCustom},
+			Timeout:     defaultTimeout,
+			Concurrency: 1,
+			Compression: "brotli",
+		}
+		So(flusher.Init(&mockContext{}), ShouldBeNil)
+		_, ok := flusher.Headers["Content-Encoding"]
+		So(ok, ShouldBeFalse)
+	})
+}
+
 type mockContext struct {
 	pipeline.Context
 	basicAuth   *basicAuth
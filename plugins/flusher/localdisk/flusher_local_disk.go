@@ -0,0 +1,377 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localdisk
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	spillFilePrefix = "spill-"
+	spillFileSuffix = ".json"
+	// replayBatchSize is the number of log groups passed to the target flusher at once when replaying.
+	replayBatchSize = 64
+	// lowDiskAlarmInterval is the min interval of alarms for log groups dropped while disk space is low.
+	lowDiskAlarmInterval = time.Minute
+	// cleanupInterval is the interval to apply RetentionMinutes and MaxTotalSizeMB to files in the directory.
+	cleanupInterval = time.Minute
+)
+
+// FlusherLocalDisk spills log groups to local disk as newline-delimited JSON, one log group per line.
+// It is a fallback flusher, see pipeline.FallbackFlusher: listed with other flushers in a config, it only
+// receives the log groups they are not ready for or fail to flush, and the spilled data can be sent later
+// with Replay.
+// Files are rotated by MaxFileSizeMB, and every cleanupInterval the oldest files are removed when the
+// directory exceeds MaxTotalSizeMB or files are older than RetentionMinutes.
+// While disk space of the data dir is low, see util.IsDiskSpaceLow, log groups are dropped with alarm
+// instead of being written.
+type FlusherLocalDisk struct {
+	Directory        string // directory to write files into, required
+	MaxFileSizeMB    int    // size of a file above which it is rotated, 64MB by default
+	MaxTotalSizeMB   int    // total size of files above which the oldest ones are removed, 1024MB by default
+	RetentionMinutes int    // files older than it are removed, 0 to keep files until MaxTotalSizeMB is reached
+
+	context pipeline.Context
+	lock    sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	size    int64
+
+	lowDiskDropped   int
+	lastLowDiskAlarm time.Time
+
+	stopCleanup chan struct{}
+	cleanupDone chan struct{}
+}
+
+// Init creates the directory, and starts to clean up files in it.
+func (p *FlusherLocalDisk) Init(context pipeline.Context) error {
+	p.context = context
+	if p.Directory == "" {
+		return fmt.Errorf("directory of flusher_local_disk is empty")
+	}
+	if p.MaxFileSizeMB <= 0 {
+		p.MaxFileSizeMB = 64
+	}
+	if p.MaxTotalSizeMB <= 0 {
+		p.MaxTotalSizeMB = 1024
+	}
+	if err := os.MkdirAll(p.Directory, 0750); err != nil {
+		return err
+	}
+	p.stopCleanup = make(chan struct{})
+	p.cleanupDone = make(chan struct{})
+	go p.runCleanup()
+	return nil
+}
+
+// runCleanup cleans up files at once and every cleanupInterval until Stop, so that files are removed by
+// RetentionMinutes even if nothing is spilled for a long time.
+func (p *FlusherLocalDisk) runCleanup() {
+	defer close(p.cleanupDone)
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		p.lock.Lock()
+		p.cleanup()
+		p.lock.Unlock()
+		select {
+		case <-ticker.C:
+		case <-p.stopCleanup:
+			return
+		}
+	}
+}
+
+func (*FlusherLocalDisk) Description() string {
+	return "local disk flusher to spill log groups when the backend is unreachable"
+}
+
+// Flush appends the log groups to the current file, and rotates it when it is full.
+func (p *FlusherLocalDisk) Flush(projectName string, logstoreName string, configName string, logGroupList []*protocol.LogGroup) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if util.IsDiskSpaceLow() {
+		p.dropForLowDisk(len(logGroupList))
+		return nil
+	}
+	for _, logGroup := range logGroupList {
+		line, err := json.Marshal(logGroup)
+		if err != nil {
+			return err
+		}
+		if err = p.write(append(line, '\n')); err != nil {
+			logger.Error(p.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "write spill file error", err, "directory", p.Directory)
+			return err
+		}
+	}
+	if p.writer != nil {
+		return p.writer.Flush()
+	}
+	return nil
+}
+
+// dropForLowDisk drops log groups instead of writing them while disk space is low, and reports them
+// at most once per lowDiskAlarmInterval.
+func (p *FlusherLocalDisk) dropForLowDisk(count int) {
+	p.lowDiskDropped += count
+	if time.Since(p.lastLowDiskAlarm) < lowDiskAlarmInterval {
+		return
+	}
+	logger.Warning(p.context.GetRuntimeContext(), "DROP_DATA_ALARM", "disk space is low, drop log groups instead of spilling them",
+		p.lowDiskDropped, "directory", p.Directory)
+	p.lowDiskDropped = 0
+	p.lastLowDiskAlarm = time.Now()
+}
+
+func (p *FlusherLocalDisk) write(data []byte) error {
+	if p.file == nil {
+		if err := p.open(); err != nil {
+			return err
+		}
+	}
+	n, err := p.writer.Write(data)
+	p.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if p.size >= int64(p.MaxFileSizeMB)*1024*1024 {
+		return p.rotate()
+	}
+	return nil
+}
+
+func (p *FlusherLocalDisk) open() error {
+	name := filepath.Join(p.Directory, fmt.Sprintf("%s%020d%s", spillFilePrefix, time.Now().UnixNano(), spillFileSuffix))
+	file, err := os.OpenFile(filepath.Clean(name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	p.file = file
+	p.writer = bufio.NewWriter(file)
+	p.size = 0
+	return nil
+}
+
+// rotate closes the current file, and removes the oldest files beyond the limits.
+func (p *FlusherLocalDisk) rotate() error {
+	if p.file == nil {
+		return nil
+	}
+	err := p.writer.Flush()
+	if closeErr := p.file.Close(); err == nil {
+		err = closeErr
+	}
+	p.file = nil
+	p.writer = nil
+	p.size = 0
+	p.cleanup()
+	return err
+}
+
+// cleanup removes closed files older than RetentionMinutes, and the oldest ones while the total size
+// exceeds MaxTotalSizeMB. The file being written is never removed.
+func (p *FlusherLocalDisk) cleanup() {
+	files, err := spillFiles(p.Directory)
+	if err != nil {
+		logger.Warning(p.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "list spill files error", err, "directory", p.Directory)
+		return
+	}
+	var total int64
+	infos := make([]os.FileInfo, len(files))
+	for i, file := range files {
+		if infos[i], err = os.Stat(file); err == nil {
+			total += infos[i].Size()
+		}
+	}
+	maxTotal := int64(p.MaxTotalSizeMB) * 1024 * 1024
+	expire := time.Now().Add(-time.Duration(p.RetentionMinutes) * time.Minute)
+	for i, file := range files {
+		if infos[i] == nil || (p.file != nil && file == p.file.Name()) {
+			continue
+		}
+		expired := p.RetentionMinutes > 0 && infos[i].ModTime().Before(expire)
+		if !expired && total <= maxTotal {
+			break
+		}
+		if err := os.Remove(file); err != nil {
+			continue
+		}
+		total -= infos[i].Size()
+		logger.Warning(p.context.GetRuntimeContext(), "DROP_DATA_ALARM", "remove spill file", file, "expired", expired)
+	}
+}
+
+// Replay sends the data spilled by the flusher to target, see Replay.
+// The current file is closed first so that all data written so far are sent.
+func (p *FlusherLocalDisk) Replay(target pipeline.FlusherV1, projectName, logstoreName, configName string) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err := p.rotate(); err != nil {
+		return 0, err
+	}
+	return Replay(p.Directory, target, projectName, logstoreName, configName)
+}
+
+// Replay reads the files spilled into dir, oldest first, and flushes the log groups in them through target.
+// A file is removed once all its log groups are flushed. It stops at the first error of target, and
+// rewrites the file with the log groups not flushed yet, so that they are replayed later without sending
+// the flushed ones again. It returns the number of log groups flushed.
+// Files being written by a running flusher_local_disk should be replayed by its Replay method instead.
+func Replay(dir string, target pipeline.FlusherV1, projectName, logstoreName, configName string) (int, error) {
+	files, err := spillFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+	replayed := 0
+	for _, file := range files {
+		logGroups, err := readSpillFile(file)
+		if err != nil {
+			return replayed, err
+		}
+		for start := 0; start < len(logGroups); start += replayBatchSize {
+			end := start + replayBatchSize
+			if end > len(logGroups) {
+				end = len(logGroups)
+			}
+			if err = target.Flush(projectName, logstoreName, configName, logGroups[start:end]); err != nil {
+				if start > 0 {
+					if rewriteErr := rewriteSpillFile(file, logGroups[start:]); rewriteErr != nil {
+						return replayed, fmt.Errorf("%w, and rewrite spill file %s error: %v", err, file, rewriteErr)
+					}
+				}
+				return replayed, err
+			}
+			replayed += end - start
+		}
+		if err = os.Remove(file); err != nil {
+			return replayed, err
+		}
+	}
+	return replayed, nil
+}
+
+// rewriteSpillFile replaces the file with the log groups, through a temp file renamed over it.
+func rewriteSpillFile(file string, logGroups []*protocol.LogGroup) error {
+	tmp := file + ".tmp"
+	f, err := os.OpenFile(filepath.Clean(tmp), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(f)
+	for _, logGroup := range logGroups {
+		var line []byte
+		if line, err = json.Marshal(logGroup); err != nil {
+			break
+		}
+		if _, err = writer.Write(append(line, '\n')); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+func readSpillFile(file string) ([]*protocol.LogGroup, error) {
+	f, err := os.Open(filepath.Clean(file))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck
+	logGroups := make([]*protocol.LogGroup, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		logGroup := &protocol.LogGroup{}
+		if err := json.Unmarshal(scanner.Bytes(), logGroup); err != nil {
+			return nil, fmt.Errorf("invalid spill file %s: %w", file, err)
+		}
+		logGroups = append(logGroups, logGroup)
+	}
+	return logGroups, scanner.Err()
+}
+
+// spillFiles returns the files in dir written by flusher_local_disk, oldest first.
+func spillFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), spillFilePrefix) && strings.HasSuffix(entry.Name(), spillFileSuffix) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (p *FlusherLocalDisk) SetUrgent(flag bool) {
+}
+
+// IsReady is ready to flush
+func (*FlusherLocalDisk) IsReady(projectName string, logstoreName string, logstoreKey int64) bool {
+	return true
+}
+
+// IsFallback returns true, so that only log groups missed by the other flushers of the config are spilled.
+func (*FlusherLocalDisk) IsFallback() bool {
+	return true
+}
+
+// Stop stops cleaning up files and closes the current file.
+func (p *FlusherLocalDisk) Stop() error {
+	if p.stopCleanup != nil {
+		close(p.stopCleanup)
+		<-p.cleanupDone
+		p.stopCleanup = nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.rotate()
+}
+
+// Register the plugin to the Flushers array.
+func init() {
+	pipeline.Flushers["flusher_local_disk"] = func() pipeline.Flusher {
+		return &FlusherLocalDisk{}
+	}
+}
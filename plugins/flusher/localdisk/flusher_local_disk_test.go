@@ -0,0 +1,177 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localdisk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/pkg/util"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+// recordingFlusher records the log groups flushed to it, and fails after failAfter log groups if it is positive.
+type recordingFlusher struct {
+	FlusherLocalDisk
+	logGroups []*protocol.LogGroup
+	failAfter int
+}
+
+func (f *recordingFlusher) Flush(projectName string, logstoreName string, configName string, logGroupList []*protocol.LogGroup) error {
+	if f.failAfter > 0 && len(f.logGroups)+len(logGroupList) > f.failAfter {
+		return errors.New("backend unreachable")
+	}
+	f.logGroups = append(f.logGroups, logGroupList...)
+	return nil
+}
+
+func newLogGroup(content string) *protocol.LogGroup {
+	return &protocol.LogGroup{
+		Topic: "topic",
+		Logs:  []*protocol.Log{{Time: 1, Contents: []*protocol.Log_Content{{Key: "content", Value: content}}}},
+	}
+}
+
+func TestFlusherLocalDiskInit(t *testing.T) {
+	flusher := &FlusherLocalDisk{}
+	assert.Error(t, flusher.Init(mock.NewEmptyContext("p", "l", "c")))
+}
+
+func TestFlusherLocalDiskReplay(t *testing.T) {
+	flusher := &FlusherLocalDisk{Directory: t.TempDir()}
+	require.NoError(t, flusher.Init(mock.NewEmptyContext("p", "l", "c")))
+	require.NoError(t, flusher.Flush("p", "l", "c", []*protocol.LogGroup{newLogGroup("a"), newLogGroup("b")}))
+	require.NoError(t, flusher.Flush("p", "l", "c", []*protocol.LogGroup{newLogGroup("c")}))
+
+	target := &recordingFlusher{}
+	replayed, err := flusher.Replay(target, "p", "l", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 3, replayed)
+	require.Len(t, target.logGroups, 3)
+	for i, content := range []string{"a", "b", "c"} {
+		assert.Equal(t, content, target.logGroups[i].Logs[0].Contents[0].Value)
+		assert.Equal(t, "topic", target.logGroups[i].Topic)
+	}
+	files, err := spillFiles(flusher.Directory)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+	assert.NoError(t, flusher.Stop())
+}
+
+func TestFlusherLocalDiskReplayFailure(t *testing.T) {
+	dir := t.TempDir()
+	flusher := &FlusherLocalDisk{Directory: dir}
+	require.NoError(t, flusher.Init(mock.NewEmptyContext("p", "l", "c")))
+	logGroups := make([]*protocol.LogGroup, 0, replayBatchSize+1)
+	for i := 0; i < replayBatchSize; i++ {
+		logGroups = append(logGroups, newLogGroup("a"))
+	}
+	logGroups = append(logGroups, newLogGroup("b"))
+	require.NoError(t, flusher.Flush("p", "l", "c", logGroups))
+	require.NoError(t, flusher.Stop())
+
+	// the file is kept if it is not fully replayed
+	replayed, err := Replay(dir, &recordingFlusher{failAfter: replayBatchSize}, "p", "l", "c")
+	assert.Error(t, err)
+	assert.Equal(t, replayBatchSize, replayed)
+	files, err := spillFiles(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// log groups flushed are not sent again
+	target := &recordingFlusher{}
+	replayed, err = Replay(dir, target, "p", "l", "c")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	require.Len(t, target.logGroups, 1)
+	assert.Equal(t, "b", target.logGroups[0].Logs[0].Contents[0].Value)
+	files, err = spillFiles(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestFlusherLocalDiskRotation(t *testing.T) {
+	dir := t.TempDir()
+	flusher := &FlusherLocalDisk{Directory: dir, MaxFileSizeMB: 1, MaxTotalSizeMB: 3}
+	require.NoError(t, flusher.Init(mock.NewEmptyContext("p", "l", "c")))
+	big := make([]byte, 300*1024)
+	for i := range big {
+		big[i] = 'x'
+	}
+	for i := 0; i < 20; i++ {
+		require.NoError(t, flusher.Flush("p", "l", "c", []*protocol.LogGroup{newLogGroup(string(big))}))
+	}
+	require.NoError(t, flusher.Stop())
+	files, err := spillFiles(dir)
+	require.NoError(t, err)
+	assert.Greater(t, len(files), 1)
+	var total int64
+	for _, file := range files {
+		info, err := os.Stat(file)
+		require.NoError(t, err)
+		total += info.Size()
+	}
+	assert.LessOrEqual(t, total, int64(3*1024*1024))
+}
+
+func TestFlusherLocalDiskRetention(t *testing.T) {
+	dir := t.TempDir()
+	expired := filepath.Join(dir, spillFilePrefix+"00000000000000000001"+spillFileSuffix)
+	require.NoError(t, os.WriteFile(expired, []byte("{}\n"), 0600))
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(expired, old, old))
+
+	// files are removed by retention even if nothing is spilled
+	flusher := &FlusherLocalDisk{Directory: dir, RetentionMinutes: 1}
+	require.NoError(t, flusher.Init(mock.NewEmptyContext("p", "l", "c")))
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(expired)
+		return os.IsNotExist(err)
+	}, time.Second*5, time.Millisecond*10)
+
+	// the file being written is not removed
+	require.NoError(t, flusher.Flush("p", "l", "c", []*protocol.LogGroup{newLogGroup("a")}))
+	current := flusher.file.Name()
+	require.NoError(t, os.Chtimes(current, old, old))
+	flusher.lock.Lock()
+	flusher.cleanup()
+	flusher.lock.Unlock()
+	_, err := os.Stat(current)
+	assert.NoError(t, err)
+	require.NoError(t, flusher.Stop())
+}
+
+func TestFlusherLocalDiskLowDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	flusher := &FlusherLocalDisk{Directory: dir}
+	require.NoError(t, flusher.Init(mock.NewEmptyContext("p", "l", "c")))
+	util.SetDiskSpaceLow(true)
+	defer util.SetDiskSpaceLow(false)
+	// log groups are dropped instead of being written
+	require.NoError(t, flusher.Flush("p", "l", "c", []*protocol.LogGroup{newLogGroup("a")}))
+	require.NoError(t, flusher.Flush("p", "l", "c", []*protocol.LogGroup{newLogGroup("b")}))
+	assert.Equal(t, 1, flusher.lowDiskDropped)
+	require.NoError(t, flusher.Stop())
+	files, err := spillFiles(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
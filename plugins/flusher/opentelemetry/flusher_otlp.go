@@ -43,10 +43,11 @@ type Version string
 var v1 Version = "v1"
 
 type FlusherOTLP struct {
-	Version Version                  `json:"Version"`
-	Logs    *helper.GrpcClientConfig `json:"Logs"`
-	Metrics *helper.GrpcClientConfig `json:"Metrics"`
-	Traces  *helper.GrpcClientConfig `json:"Traces"`
+	Version  Version                  `json:"Version"`
+	Protocol string                   `json:"Protocol"` // grpc or http/protobuf, default grpc
+	Logs     *helper.GrpcClientConfig `json:"Logs"`
+	Metrics  *helper.GrpcClientConfig `json:"Metrics"`
+	Traces   *helper.GrpcClientConfig `json:"Traces"`
 
 	converter    *converter.Converter
 	context      pipeline.Context
@@ -68,8 +69,24 @@ func (f *FlusherOTLP) Init(ctx pipeline.Context) error {
 		return err
 	}
 	f.converter = convert
+	switch f.Protocol {
+	case "":
+		f.Protocol = protocolGRPC
+	case protocolGRPC, protocolHTTPProtobuf:
+	default:
+		logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "unsupported otlp protocol", f.Protocol)
+		return fmt.Errorf("unsupported otlp protocol: %s", f.Protocol)
+	}
 
-	if f.Logs != nil {
+	if f.Logs != nil && f.Protocol == protocolHTTPProtobuf {
+		client, err := newHTTPClient[plogotlp.ExportRequest](f.Logs, "/v1/logs", plogotlp.NewExportResponse)
+		if err != nil {
+			logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "init otlp logs http client fail, error", err)
+		} else {
+			logger.Info(f.context.GetRuntimeContext(), "otlp logs flusher url", client.url)
+			f.logClient = newGrpcClient[plogotlp.GRPCClient](client, nil, f.Logs, metadata.MD{})
+		}
+	} else if f.Logs != nil {
 		grpcConn, err := buildGrpcClientConn(f.Logs)
 		if err != nil {
 			logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "init otlp logs gRPC conn fail, error", err)
@@ -79,7 +96,15 @@ func (f *FlusherOTLP) Init(ctx pipeline.Context) error {
 			f.logClient = newGrpcClient(plogotlp.NewGRPCClient(grpcConn), grpcConn, f.Logs, logMeta)
 		}
 	}
-	if f.Metrics != nil {
+	if f.Metrics != nil && f.Protocol == protocolHTTPProtobuf {
+		client, err := newHTTPClient[pmetricotlp.ExportRequest](f.Metrics, "/v1/metrics", pmetricotlp.NewExportResponse)
+		if err != nil {
+			logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "init otlp metrics http client fail, error", err)
+		} else {
+			logger.Info(f.context.GetRuntimeContext(), "otlp metrics flusher url", client.url)
+			f.metricClient = newGrpcClient[pmetricotlp.GRPCClient](client, nil, f.Metrics, metadata.MD{})
+		}
+	} else if f.Metrics != nil {
 		grpcConn, err := buildGrpcClientConn(f.Metrics)
 		if err != nil {
 			logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "init otlp metrics gRPC conn fail, error", err)
@@ -90,7 +115,15 @@ func (f *FlusherOTLP) Init(ctx pipeline.Context) error {
 		}
 	}
 
-	if f.Traces != nil {
+	if f.Traces != nil && f.Protocol == protocolHTTPProtobuf {
+		client, err := newHTTPClient[ptraceotlp.ExportRequest](f.Traces, "/v1/traces", ptraceotlp.NewExportResponse)
+		if err != nil {
+			logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "init otlp traces http client fail, error", err)
+		} else {
+			logger.Info(f.context.GetRuntimeContext(), "otlp traces flusher url", client.url)
+			f.traceClient = newGrpcClient[ptraceotlp.GRPCClient](client, nil, f.Traces, metadata.MD{})
+		}
+	} else if f.Traces != nil {
 		grpcConn, err := buildGrpcClientConn(f.Traces)
 		if err != nil {
 			logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "init otlp traces gRPC conn fail, error", err)
@@ -125,10 +158,7 @@ func (f *FlusherOTLP) Flush(projectName string, logstoreName string, configName
 		return nil
 	}
 	request := f.convertLogGroupToRequest(logGroupList)
-	if !f.logClient.grpcConfig.Retry.Enable {
-		return timeoutFlush[plogotlp.ExportRequest, plogotlp.ExportResponse](f.logClient.client, request, f.logClient.metadata, f.logClient.grpcConfig)
-	}
-	return flushWithRetry[plogotlp.ExportRequest, plogotlp.ExportResponse](f.logClient.client, request, f.logClient.metadata, f.logClient.grpcConfig)
+	return send[plogotlp.ExportRequest, plogotlp.ExportResponse](f.context, f.logClient, request)
 }
 
 // Export data to destination, such as gRPC, console, file, etc.
@@ -237,12 +267,7 @@ func (f *FlusherOTLP) flushRequests(log plogotlp.ExportRequest, metric pmetricot
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			var err error
-			if f.logClient.grpcConfig.Retry.Enable {
-				err = flushWithRetry[plogotlp.ExportRequest, plogotlp.ExportResponse](f.logClient.client, log, f.logClient.metadata, f.logClient.grpcConfig)
-			} else {
-				err = timeoutFlush[plogotlp.ExportRequest, plogotlp.ExportResponse](f.logClient.client, log, f.logClient.metadata, f.logClient.grpcConfig)
-			}
+			err := send[plogotlp.ExportRequest, plogotlp.ExportResponse](f.context, f.logClient, log)
 
 			if err != nil {
 				logger.Error(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "send data to otlp server fail, error", err)
@@ -255,12 +280,7 @@ func (f *FlusherOTLP) flushRequests(log plogotlp.ExportRequest, metric pmetricot
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			var err error
-			if f.metricClient.grpcConfig.Retry.Enable {
-				err = flushWithRetry[pmetricotlp.ExportRequest, pmetricotlp.ExportResponse](f.metricClient.client, metric, f.metricClient.metadata, f.metricClient.grpcConfig)
-			} else {
-				err = timeoutFlush[pmetricotlp.ExportRequest, pmetricotlp.ExportResponse](f.metricClient.client, metric, f.metricClient.metadata, f.metricClient.grpcConfig)
-			}
+			err := send[pmetricotlp.ExportRequest, pmetricotlp.ExportResponse](f.context, f.metricClient, metric)
 
 			if err != nil {
 				logger.Error(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "send metric data to otlp server fail, error", err)
@@ -273,12 +293,7 @@ func (f *FlusherOTLP) flushRequests(log plogotlp.ExportRequest, metric pmetricot
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			var err error
-			if f.traceClient.grpcConfig.Retry.Enable {
-				err = flushWithRetry[ptraceotlp.ExportRequest, ptraceotlp.ExportResponse](f.traceClient.client, trace, f.traceClient.metadata, f.traceClient.grpcConfig)
-			} else {
-				err = timeoutFlush[ptraceotlp.ExportRequest, ptraceotlp.ExportResponse](f.traceClient.client, trace, f.traceClient.metadata, f.traceClient.grpcConfig)
-			}
+			err := send[ptraceotlp.ExportRequest, ptraceotlp.ExportResponse](f.context, f.traceClient, trace)
 
 			if err != nil {
 				logger.Error(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "send trace data to otlp server fail, error", err)
@@ -298,17 +313,17 @@ func flushWithRetry[
 	Q interface {
 		Export(ctx context.Context, request T, opts ...grpc.CallOption) (P, error)
 	},
-](q Q, request T, metadata metadata.MD, grpcConfig *helper.GrpcClientConfig) error {
+](q Q, request T, metadata metadata.MD, grpcConfig *helper.GrpcClientConfig) (P, error) {
 	var retryNum = 0
 
 	for {
-		err := timeoutFlush[T, P](q, request, metadata, grpcConfig)
+		response, err := timeoutFlush[T, P](q, request, metadata, grpcConfig)
 		if err == nil {
-			return nil
+			return response, nil
 		}
 		retry := helper.GetRetryInfo(err)
 		if retry == nil || retryNum >= grpcConfig.Retry.MaxCount {
-			return err
+			return response, err
 		}
 
 		retryNum++
@@ -323,12 +338,49 @@ func timeoutFlush[
 		Export(ctx context.Context, request T, opts ...grpc.CallOption) (P, error)
 	},
 ](
-	q Q, req T, metadata metadata.MD, grpcConfig *helper.GrpcClientConfig) error {
+	q Q, req T, metadata metadata.MD, grpcConfig *helper.GrpcClientConfig) (P, error) {
 	timeout, canal := context.WithTimeout(withMetadata(metadata), grpcConfig.GetTimeout())
 	defer canal()
-	_, err := q.Export(timeout, req)
-	return err
+	return q.Export(timeout, req)
+}
 
+// send exports the request with the client, retrying if enabled, and reports the records rejected by the server.
+// A partial success is not retried, as the server does not tell which records are rejected, and the OTLP
+// specification forbids retrying them.
+func send[
+	T plogotlp.ExportRequest | pmetricotlp.ExportRequest | ptraceotlp.ExportRequest,
+	P plogotlp.ExportResponse | pmetricotlp.ExportResponse | ptraceotlp.ExportResponse,
+	Q interface {
+		Export(ctx context.Context, request T, opts ...grpc.CallOption) (P, error)
+	},
+](ctx pipeline.Context, c *grpcClient[Q], request T) error {
+	var response P
+	var err error
+	if c.grpcConfig.Retry.Enable {
+		response, err = flushWithRetry[T, P](c.client, request, c.metadata, c.grpcConfig)
+	} else {
+		response, err = timeoutFlush[T, P](c.client, request, c.metadata, c.grpcConfig)
+	}
+	if err != nil {
+		return err
+	}
+	if rejected, message := rejectedRecords(response); rejected > 0 || message != "" {
+		logger.Warning(ctx.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "otlp server rejected records", rejected, "message", message)
+	}
+	return nil
+}
+
+// rejectedRecords returns the records rejected in a partial success response and the message of the server.
+func rejectedRecords(response any) (int64, string) {
+	switch r := response.(type) {
+	case plogotlp.ExportResponse:
+		return r.PartialSuccess().RejectedLogRecords(), r.PartialSuccess().ErrorMessage()
+	case pmetricotlp.ExportResponse:
+		return r.PartialSuccess().RejectedDataPoints(), r.PartialSuccess().ErrorMessage()
+	case ptraceotlp.ExportResponse:
+		return r.PartialSuccess().RejectedSpans(), r.PartialSuccess().ErrorMessage()
+	}
+	return 0, ""
 }
 
 // append withMetadata to context. refer to https://github.com/open-telemetry/opentelemetry-collector/blob/main/exporter/otlpexporter/otlp.go#L121
@@ -368,6 +420,10 @@ func (c *grpcClient[T]) isReady() bool {
 	if c == nil {
 		return false
 	}
+	if c.grpcConn == nil {
+		// the client over http has no connection to keep
+		return true
+	}
 	state := c.grpcConn.GetState()
 	var ready bool
 	switch c.grpcConn.GetState() {
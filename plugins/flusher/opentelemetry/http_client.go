@@ -0,0 +1,139 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+)
+
+const (
+	protocolGRPC         = "grpc"
+	protocolHTTPProtobuf = "http/protobuf"
+
+	protobufContentType = "application/x-protobuf"
+	maxErrorBodySize    = 1024
+)
+
+// httpClient exports OTLP requests over HTTP with the protobuf encoding. It has the Export method of the gRPC
+// clients of pdata, so that it can replace them. Failed responses are converted to gRPC status errors by
+// helper.HTTPStatusError, so that retries are decided as those of gRPC.
+type httpClient[T interface{ MarshalProto() ([]byte, error) }, P interface{ UnmarshalProto([]byte) error }] struct {
+	client      *http.Client
+	url         string
+	headers     map[string]string
+	gzip        bool
+	newResponse func() P
+}
+
+// newHTTPClient returns a client posting to the endpoint, or to the default path of the signal under the endpoint
+// if the endpoint has no path, e.g. http://collector:4318 is resolved to http://collector:4318/v1/logs.
+func newHTTPClient[T interface{ MarshalProto() ([]byte, error) }, P interface{ UnmarshalProto([]byte) error }](
+	config *helper.GrpcClientConfig, defaultPath string, newResponse func() P) (*httpClient[T, P], error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	scheme := "http://"
+	if config.TLS != nil && config.TLS.Enabled {
+		tlsConfig, err := config.TLS.LoadTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+		scheme = "https://"
+	}
+	switch config.Compression {
+	case "", "none", "gzip":
+	default:
+		return nil, fmt.Errorf("unsupported compression type %q for %s", config.Compression, protocolHTTPProtobuf)
+	}
+	endpoint := config.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = scheme + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %w", config.Endpoint, err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = defaultPath
+	}
+	return &httpClient[T, P]{
+		client:      &http.Client{Transport: transport, Timeout: config.GetTimeout()},
+		url:         u.String(),
+		headers:     config.Headers,
+		gzip:        config.Compression == "gzip",
+		newResponse: newResponse,
+	}, nil
+}
+
+func (c *httpClient[T, P]) Export(ctx context.Context, request T, _ ...grpc.CallOption) (P, error) {
+	response := c.newResponse()
+	body, err := request.MarshalProto()
+	if err != nil {
+		return response, err
+	}
+	if c.gzip {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err = writer.Write(body); err != nil {
+			return response, err
+		}
+		if err = writer.Close(); err != nil {
+			return response, err
+		}
+		body = buf.Bytes()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return response, err
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", protobufContentType)
+	if c.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return response, status.Error(codes.Unavailable, err.Error())
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return response, helper.HTTPStatusError(resp.StatusCode, resp.Header, string(message))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return response, err
+	}
+	if len(data) > 0 && strings.HasPrefix(resp.Header.Get("Content-Type"), protobufContentType) {
+		if err = response.UnmarshalProto(data); err != nil {
+			return response, fmt.Errorf("invalid response: %w", err)
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentelemetry
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+type testOtlpHTTPServer struct {
+	*httptest.Server
+	requests   chan plogotlp.ExportRequest
+	headers    chan http.Header
+	failures   atomic.Int32 // requests to fail with 503 before succeeding
+	rejected   int64
+	statusCode int
+}
+
+func newTestOtlpHTTPServer(t *testing.T) *testOtlpHTTPServer {
+	s := &testOtlpHTTPServer{
+		requests: make(chan plogotlp.ExportRequest, 10),
+		headers:  make(chan http.Header, 10),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if s.failures.Add(-1) >= 0 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if s.statusCode != 0 {
+			w.WriteHeader(s.statusCode)
+			return
+		}
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			reader, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = reader
+		}
+		data, err := io.ReadAll(body)
+		require.NoError(t, err)
+		request := plogotlp.NewExportRequest()
+		require.NoError(t, request.UnmarshalProto(data))
+		s.requests <- request
+		s.headers <- r.Header
+
+		response := plogotlp.NewExportResponse()
+		if s.rejected > 0 {
+			response.PartialSuccess().SetRejectedLogRecords(s.rejected)
+			response.PartialSuccess().SetErrorMessage("too old")
+		}
+		data, err = response.MarshalProto()
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", protobufContentType)
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestHTTPFlusher(t *testing.T, config *helper.GrpcClientConfig) *FlusherOTLP {
+	f := &FlusherOTLP{Version: v1, Protocol: protocolHTTPProtobuf, Logs: config}
+	require.NoError(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+	t.Cleanup(func() {
+		require.NoError(t, f.Stop())
+	})
+	return f
+}
+
+func Test_Flusher_Flush_HTTP(t *testing.T) {
+	server := newTestOtlpHTTPServer(t)
+	f := newTestHTTPFlusher(t, &helper.GrpcClientConfig{Endpoint: server.URL, Compression: "gzip", Headers: map[string]string{"X-AppKey": "key"}})
+	require.True(t, f.IsReady("p", "l", 0))
+
+	groupList := makeTestLogGroupList().GetLogGroupList()
+	require.NoError(t, f.Flush("p", "l", "c", groupList))
+	request := <-server.requests
+	require.Equal(t, f.convertLogGroupToRequest(groupList).Logs().LogRecordCount(), request.Logs().LogRecordCount())
+	header := <-server.headers
+	require.Equal(t, "key", header.Get("X-AppKey"))
+	require.Equal(t, protobufContentType, header.Get("Content-Type"))
+
+	// partial success is not retried
+	server.rejected = 3
+	require.NoError(t, f.Flush("p", "l", "c", groupList))
+	<-server.requests
+	require.Len(t, server.requests, 0)
+}
+
+func Test_Flusher_Flush_HTTP_Retry(t *testing.T) {
+	server := newTestOtlpHTTPServer(t)
+	server.failures.Store(2)
+	f := newTestHTTPFlusher(t, &helper.GrpcClientConfig{Endpoint: server.URL, Retry: helper.RetryConfig{Enable: true, MaxCount: 2}})
+	require.NoError(t, f.Flush("p", "l", "c", makeTestLogGroupList().GetLogGroupList()))
+	require.Len(t, server.requests, 1)
+
+	// errors other than 429, 502, 503 and 504 are not retried
+	server.statusCode = http.StatusBadRequest
+	require.Error(t, f.Flush("p", "l", "c", makeTestLogGroupList().GetLogGroupList()))
+}
+
+func Test_Flusher_Init_HTTP(t *testing.T) {
+	f := &FlusherOTLP{Version: v1, Protocol: protocolHTTPProtobuf, Logs: &helper.GrpcClientConfig{Endpoint: "collector:4318"}}
+	require.NoError(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+	require.Equal(t, "http://collector:4318/v1/logs", f.logClient.client.(*httpClient[plogotlp.ExportRequest, plogotlp.ExportResponse]).url)
+
+	f = &FlusherOTLP{Version: v1, Protocol: protocolHTTPProtobuf, Logs: &helper.GrpcClientConfig{Endpoint: "https://collector/otlp/logs"}}
+	require.NoError(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+	require.Equal(t, "https://collector/otlp/logs", f.logClient.client.(*httpClient[plogotlp.ExportRequest, plogotlp.ExportResponse]).url)
+
+	f = &FlusherOTLP{Version: v1, Protocol: protocolHTTPProtobuf, Logs: &helper.GrpcClientConfig{Endpoint: "collector:4318", Compression: "snappy"}}
+	require.Error(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+
+	f = &FlusherOTLP{Version: v1, Protocol: "http/json", Logs: &helper.GrpcClientConfig{Endpoint: "collector:4318"}}
+	require.Error(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+}
@@ -15,6 +15,7 @@
 package stdout
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"sync"
@@ -416,7 +417,27 @@ func (sds *ServiceDockerStdout) Stop() error {
 	return nil
 }
 
+// resolveCheckpointFiles returns the container log files of the checkpoint saved by SaveCheckPoint,
+// so that checkpoint compaction can remove it once all of them are removed or expired.
+func resolveCheckpointFiles(_, key string, value []byte) ([]string, bool) {
+	if key != serviceDockerStdoutKey {
+		return nil, false
+	}
+	checkpointMap := make(map[string]helper.LogFileReaderCheckPoint)
+	if err := json.Unmarshal(value, &checkpointMap); err != nil {
+		return nil, false
+	}
+	paths := make([]string, 0, len(checkpointMap))
+	for _, checkpoint := range checkpointMap {
+		if len(checkpoint.Path) > 0 {
+			paths = append(paths, checkpoint.Path)
+		}
+	}
+	return paths, true
+}
+
 func init() {
+	helper.RegisterCheckpointFileResolver(resolveCheckpointFiles)
 	pipeline.ServiceInputs[input.ServiceDockerStdoutPluginName] = func() pipeline.ServiceInput {
 		return &ServiceDockerStdout{
 			FlushIntervalMs:      3000,
@@ -76,6 +76,18 @@ var SyslogFacilityString = map[string]string{
 	"23": "local7",
 }
 
+// priorityLevels maps the names of priorities accepted by Priority to their numbers, as journalctl -p does.
+var priorityLevels = map[string]int{
+	"emerg":   0,
+	"alert":   1,
+	"crit":    2,
+	"err":     3,
+	"warning": 4,
+	"notice":  5,
+	"info":    6,
+	"debug":   7,
+}
+
 // PriorityConversionMap is a map containing the textual equivalence of a given priority string number
 var PriorityConversionMap = map[string]string{
 	"0": "emergency",
@@ -94,6 +106,7 @@ type ServiceJournal struct {
 	CursorSeekFallback  string
 	Units               []string
 	Kernel              bool
+	Priority            string // the lowest priority to collect, a number 0-7 or a name like "err", all by default
 	Identifiers         []string
 	JournalPaths        []string
 	MatchPatterns       []string
@@ -102,6 +115,7 @@ type ServiceJournal struct {
 	UseJournalEventTime bool
 	ResetIntervalSecond int
 
+	maxPriority    int
 	journal        *sdjournal.Journal
 	lastSaveCPTime time.Time
 	lastCPCursor   string
@@ -140,9 +154,45 @@ func (sj *ServiceJournal) LoadCheckpoint() {
 
 func (sj *ServiceJournal) Init(context pipeline.Context) (int, error) {
 	sj.context = context
+	maxPriority, err := parsePriority(sj.Priority)
+	if err != nil {
+		return 0, err
+	}
+	sj.maxPriority = maxPriority
 	return 0, nil
 }
 
+// parsePriority returns the number of the priority, or -1 if it is empty.
+func parsePriority(priority string) (int, error) {
+	if priority == "" {
+		return -1, nil
+	}
+	if level, ok := priorityLevels[priority]; ok {
+		return level, nil
+	}
+	if level, err := strconv.Atoi(priority); err == nil && level >= 0 && level <= 7 {
+		return level, nil
+	}
+	return 0, fmt.Errorf("invalid priority %s", priority)
+}
+
+// addPriorities keeps entries with priorities not lower than Priority, like journalctl -p.
+// The priorities are in conjunction with the other matches.
+func (sj *ServiceJournal) addPriorities() error {
+	if sj.maxPriority < 0 {
+		return nil
+	}
+	if err := sj.journal.AddConjunction(); err != nil {
+		return fmt.Errorf("Filtering priority failed: %v", err)
+	}
+	for level := 0; level <= sj.maxPriority; level++ {
+		if err := sj.journal.AddMatch(sdjournal.SD_JOURNAL_FIELD_PRIORITY + "=" + strconv.Itoa(level)); err != nil {
+			return fmt.Errorf("Filtering priority %d failed: %v", level, err)
+		}
+	}
+	return sj.journal.AddConjunction()
+}
+
 func (sj *ServiceJournal) addKernel() error {
 	if len(sj.Units) > 0 && sj.Kernel {
 		err := sj.addMatchesForKernel()
@@ -253,21 +303,23 @@ func (sj *ServiceJournal) initJournal() error {
 		return err
 	}
 
+	if err = sj.addPriorities(); err != nil {
+		return err
+	}
+
+	seekPosition := func(position string) error {
+		if position == SeekPositionHead {
+			return seekToHelper(SeekPositionHead, sj.journal.SeekHead())
+		}
+		return seekToHelper(SeekPositionTail, sj.journal.SeekTail())
+	}
 	sj.LoadCheckpoint()
 	if len(sj.lastCPCursor) == 0 {
-		switch sj.SeekPosition {
-		case SeekPositionHead:
-			err = seekToHelper(SeekPositionHead, sj.journal.SeekHead())
-		default:
-			err = seekToHelper(SeekPositionTail, sj.journal.SeekTail())
-		}
-	} else {
-		_ = seekToHelper(sj.lastCPCursor, sj.journal.SeekCursor(sj.lastCPCursor))
-		_, err = sj.journal.Next()
-		if err != nil {
-			logger.Warning(sj.context.GetRuntimeContext(), "JOURNAL_READ_ALARM", "call next when init error", err)
-			err = nil
-		}
+		err = seekPosition(sj.SeekPosition)
+	} else if err = seekToHelper(sj.lastCPCursor, sj.seekCursor()); err != nil {
+		// the entry of the cursor may have been rotated away, or the cursor is from another journal
+		logger.Warning(sj.context.GetRuntimeContext(), "JOURNAL_SEEK_ALARM", "checkpoint cursor is invalid, fall back to", sj.CursorSeekFallback)
+		err = seekPosition(sj.CursorSeekFallback)
 	}
 	if err != nil {
 		return fmt.Errorf("Seeking to a good position in journal failed: %v", err)
@@ -275,6 +327,18 @@ func (sj *ServiceJournal) initJournal() error {
 	return nil
 }
 
+// seekCursor moves to the entry of the checkpoint cursor, so that reading resumes from the entry after it.
+// It fails if the entry no longer exists in the journal.
+func (sj *ServiceJournal) seekCursor() error {
+	if err := sj.journal.SeekCursor(sj.lastCPCursor); err != nil {
+		return err
+	}
+	if _, err := sj.journal.Next(); err != nil {
+		return err
+	}
+	return sj.journal.TestCursor(sj.lastCPCursor)
+}
+
 // Start starts the ServiceInput's service, whatever that may be
 func (sj *ServiceJournal) Start(c pipeline.Collector) error {
 	sj.shutdown = make(chan struct{})
@@ -31,6 +31,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"gopkg.in/yaml.v2"
 
 	"github.com/alibaba/ilogtail/pkg/config"
 	"github.com/alibaba/ilogtail/pkg/logger"
@@ -107,6 +108,11 @@ func (p *ServiceStaticPrometheus) Init(context pipeline.Context) (int, error) {
 		return 0, errors.New("the scrape configuration is required")
 	}
 	var err error
+	if global := context.GetPipelineScopeConfig(); global != nil && global.InputIntervalMs != config.LoongcollectorGlobalConfig.InputIntervalMs {
+		if detail, err = withScrapeInterval(detail, global.InputIntervalMs); err != nil {
+			return 0, fmt.Errorf("illegal prometheus configuration: %v", err)
+		}
+	}
 	if p.AuthorizationPath, err = filepath.Abs(p.AuthorizationPath); err != nil {
 		return 0, fmt.Errorf("cannot find the abs authorization path: %v", err)
 	}
@@ -175,6 +181,34 @@ func (p *ServiceStaticPrometheus) StartKubeReloadScraper() {
 	}()
 }
 
+// withScrapeInterval sets global.scrape_interval of the scrape configuration to intervalMs unless it is set,
+// so that the InputIntervalMs of the pipeline works as in metric inputs. Scrape intervals of jobs still win.
+func withScrapeInterval(detail []byte, intervalMs int) ([]byte, error) {
+	var cfg yaml.MapSlice
+	if err := yaml.Unmarshal(detail, &cfg); err != nil {
+		return nil, err
+	}
+	interval := yaml.MapItem{Key: "scrape_interval", Value: strconv.Itoa(intervalMs) + "ms"}
+	for i, item := range cfg {
+		if item.Key != "global" {
+			continue
+		}
+		global, ok := item.Value.(yaml.MapSlice)
+		if !ok && item.Value != nil {
+			return detail, nil
+		}
+		for _, option := range global {
+			if option.Key == "scrape_interval" {
+				return detail, nil
+			}
+		}
+		cfg[i].Value = append(global, interval)
+		return yaml.Marshal(cfg)
+	}
+	cfg = append(cfg, yaml.MapItem{Key: "global", Value: yaml.MapSlice{interval}})
+	return yaml.Marshal(cfg)
+}
+
 func init() {
 	pipeline.ServiceInputs["service_prometheus"] = func() pipeline.ServiceInput {
 		return &ServiceStaticPrometheus{
@@ -0,0 +1,54 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestWithScrapeInterval(t *testing.T) {
+	scrapeInterval := func(detail []byte) interface{} {
+		var cfg struct {
+			Global map[string]interface{} `yaml:"global"`
+		}
+		require.NoError(t, yaml.Unmarshal(detail, &cfg))
+		return cfg.Global["scrape_interval"]
+	}
+
+	// no global section
+	detail, err := withScrapeInterval([]byte("scrape_configs:\n- job_name: app\n"), 30000)
+	require.NoError(t, err)
+	assert.Equal(t, "30000ms", scrapeInterval(detail))
+	assert.Contains(t, string(detail), "job_name: app")
+
+	// global section without scrape_interval
+	detail, err = withScrapeInterval([]byte("global:\n  scrape_timeout: 5s\nscrape_configs: []\n"), 15000)
+	require.NoError(t, err)
+	assert.Equal(t, "15000ms", scrapeInterval(detail))
+	assert.Contains(t, string(detail), "scrape_timeout: 5s")
+
+	// scrape_interval of the configuration wins
+	original := []byte("global:\n  scrape_interval: 1m\n")
+	detail, err = withScrapeInterval(original, 15000)
+	require.NoError(t, err)
+	assert.Equal(t, original, detail)
+
+	_, err = withScrapeInterval([]byte("global: ["), 15000)
+	assert.Error(t, err)
+}
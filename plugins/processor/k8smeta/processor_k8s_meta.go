@@ -0,0 +1,167 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const pluginType = "processor_k8s_meta"
+
+// Metadata of the pod supported by Metadata, they are appended with the same keys.
+const (
+	MetaNamespace     = "_namespace_"
+	MetaPodName       = "_pod_name_"
+	MetaContainerName = "_container_name_"
+	MetaImageName     = "_image_name_"
+)
+
+// maxCachedContainers bounds the cache, as container ids are read from logs.
+const maxCachedContainers = 4096
+
+// The container discovery, replaced in tests.
+var (
+	getContainerMeta            = helper.GetContainerMeta
+	getContainersLastUpdateTime = helper.GetContainersLastUpdateTime
+)
+
+// ProcessorK8sMeta appends the metadata of the pod running the container, whose id is read from SourceKey of the log.
+// The metadata are maintained by the container discovery, and cached by the processor until containers change,
+// such as a pod is deleted. Pod labels and annotations are appended only if their keys are listed, so that they do
+// not blow up the cardinality.
+type ProcessorK8sMeta struct {
+	SourceKey        string            // the key of the container id, such as "containerd://xxx" or "xxx"
+	Metadata         []string          // metadata to append, in _namespace_, _pod_name_, _container_name_ and _image_name_
+	K8sLabelTag      map[string]string // pod labels to append, as label key: appended key
+	K8sAnnotationTag map[string]string // pod annotations to append, as annotation key: appended key
+	IgnoreIfExist    bool              // whether to skip the key already in the log
+
+	context        pipeline.Context
+	cache          map[string]*helper.ContainerMeta
+	lastUpdateTime int64
+}
+
+func (p *ProcessorK8sMeta) Init(context pipeline.Context) error {
+	p.context = context
+	if p.SourceKey == "" {
+		return fmt.Errorf("must specify SourceKey for plugin %v", pluginType)
+	}
+	for _, meta := range p.Metadata {
+		switch meta {
+		case MetaNamespace, MetaPodName, MetaContainerName, MetaImageName:
+		default:
+			return fmt.Errorf("unsupported Metadata %v for plugin %v", meta, pluginType)
+		}
+	}
+	p.cache = make(map[string]*helper.ContainerMeta)
+	return nil
+}
+
+func (*ProcessorK8sMeta) Description() string {
+	return "k8s meta processor to append the metadata of the pod running the container of logs"
+}
+
+func (p *ProcessorK8sMeta) ProcessLogs(logArray []*protocol.Log) []*protocol.Log {
+	if updateTime := getContainersLastUpdateTime(); updateTime != p.lastUpdateTime {
+		// containers are added, updated or removed, metadata cached may be stale
+		p.cache = make(map[string]*helper.ContainerMeta)
+		p.lastUpdateTime = updateTime
+	}
+	for _, log := range logArray {
+		p.processLog(log)
+	}
+	return logArray
+}
+
+func (p *ProcessorK8sMeta) processLog(log *protocol.Log) {
+	var containerID string
+	for _, content := range log.Contents {
+		if content.Key == p.SourceKey {
+			containerID = content.Value
+			break
+		}
+	}
+	if containerID == "" {
+		return
+	}
+	meta := p.lookup(containerID)
+	if meta == nil {
+		return
+	}
+	for _, key := range p.Metadata {
+		switch key {
+		case MetaNamespace:
+			p.appendContent(log, key, meta.K8sNamespace)
+		case MetaPodName:
+			p.appendContent(log, key, meta.PodName)
+		case MetaContainerName:
+			p.appendContent(log, key, meta.ContainerName)
+		case MetaImageName:
+			p.appendContent(log, key, meta.Image)
+		}
+	}
+	for label, key := range p.K8sLabelTag {
+		if value, ok := meta.K8sLabels[label]; ok {
+			p.appendContent(log, key, value)
+		}
+	}
+	for annotation, key := range p.K8sAnnotationTag {
+		if value, ok := meta.K8sAnnotations[annotation]; ok {
+			p.appendContent(log, key, value)
+		}
+	}
+}
+
+// lookup returns the cached metadata of the container, or nil if the container is not found.
+func (p *ProcessorK8sMeta) lookup(containerID string) *helper.ContainerMeta {
+	if index := strings.Index(containerID, "://"); index >= 0 {
+		containerID = containerID[index+3:]
+	}
+	if meta, ok := p.cache[containerID]; ok {
+		return meta
+	}
+	if len(p.cache) >= maxCachedContainers {
+		p.cache = make(map[string]*helper.ContainerMeta)
+	}
+	// containers not found are cached as well, so that they are not fetched for each log
+	meta := getContainerMeta(containerID)
+	p.cache[containerID] = meta
+	return meta
+}
+
+func (p *ProcessorK8sMeta) appendContent(log *protocol.Log, key, value string) {
+	if p.IgnoreIfExist {
+		for _, content := range log.Contents {
+			if content.Key == key {
+				return
+			}
+		}
+	}
+	log.Contents = append(log.Contents, &protocol.Log_Content{Key: key, Value: value})
+}
+
+func init() {
+	pipeline.Processors[pluginType] = func() pipeline.Processor {
+		return &ProcessorK8sMeta{
+			SourceKey: "_container_id_",
+			Metadata:  []string{MetaNamespace, MetaPodName, MetaContainerName},
+		}
+	}
+}
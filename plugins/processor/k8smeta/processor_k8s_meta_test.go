@@ -0,0 +1,109 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	_ "github.com/alibaba/ilogtail/pkg/logger/test"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+type mockContainers struct {
+	metas      map[string]*helper.ContainerMeta
+	updateTime int64
+	lookups    int
+}
+
+func newMockContainers(t *testing.T) *mockContainers {
+	m := &mockContainers{metas: map[string]*helper.ContainerMeta{
+		"abc": {
+			K8sNamespace:   "default",
+			PodName:        "nginx-0",
+			ContainerName:  "nginx",
+			Image:          "nginx:latest",
+			K8sLabels:      map[string]string{"app": "nginx", "pod-template-hash": "123"},
+			K8sAnnotations: map[string]string{"owner": "team-a"},
+		},
+	}}
+	getContainerMeta = func(containerID string) *helper.ContainerMeta {
+		m.lookups++
+		return m.metas[containerID]
+	}
+	getContainersLastUpdateTime = func() int64 {
+		return m.updateTime
+	}
+	t.Cleanup(func() {
+		getContainerMeta = helper.GetContainerMeta
+		getContainersLastUpdateTime = helper.GetContainersLastUpdateTime
+	})
+	return m
+}
+
+func newProcessor(t *testing.T) *ProcessorK8sMeta {
+	p := pipeline.Processors[pluginType]().(*ProcessorK8sMeta)
+	p.K8sLabelTag = map[string]string{"app": "_k8s_label_app_"}
+	p.K8sAnnotationTag = map[string]string{"owner": "_k8s_annotation_owner_"}
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	return p
+}
+
+func newLog(containerID string) *protocol.Log {
+	return &protocol.Log{Contents: []*protocol.Log_Content{{Key: "_container_id_", Value: containerID}}}
+}
+
+func TestProcessorK8sMeta(t *testing.T) {
+	newMockContainers(t)
+	p := newProcessor(t)
+
+	logs := p.ProcessLogs([]*protocol.Log{newLog("containerd://abc"), newLog("unknown")})
+	require.Equal(t, "default", test.ReadLogVal(logs[0], MetaNamespace))
+	require.Equal(t, "nginx-0", test.ReadLogVal(logs[0], MetaPodName))
+	require.Equal(t, "nginx", test.ReadLogVal(logs[0], MetaContainerName))
+	require.Equal(t, "", test.ReadLogVal(logs[0], MetaImageName))
+	require.Equal(t, "nginx", test.ReadLogVal(logs[0], "_k8s_label_app_"))
+	require.Equal(t, "team-a", test.ReadLogVal(logs[0], "_k8s_annotation_owner_"))
+	// labels not listed are not appended
+	require.Len(t, logs[0].Contents, 6)
+	require.Len(t, logs[1].Contents, 1)
+}
+
+func TestProcessorK8sMetaCache(t *testing.T) {
+	m := newMockContainers(t)
+	p := newProcessor(t)
+
+	p.ProcessLogs([]*protocol.Log{newLog("abc"), newLog("abc"), newLog("unknown"), newLog("unknown")})
+	require.Equal(t, 2, m.lookups)
+
+	// the cache is invalidated once the pod is deleted
+	delete(m.metas, "abc")
+	m.updateTime++
+	logs := p.ProcessLogs([]*protocol.Log{newLog("abc")})
+	require.Equal(t, 3, m.lookups)
+	require.Len(t, logs[0].Contents, 1)
+}
+
+func TestProcessorK8sMetaInit(t *testing.T) {
+	p := &ProcessorK8sMeta{SourceKey: "_container_id_", Metadata: []string{"_pod_ip_"}}
+	require.Error(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	p = &ProcessorK8sMeta{}
+	require.Error(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logfmt
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const pluginType = "processor_logfmt"
+
+// ProcessorLogfmt parses the logfmt line in SourceKey, like `level=info msg="a \"b\"" dur=12ms debug`,
+// and merges the pairs into the log, replacing fields with the same key.
+// Values may be quoted with Go escapes, and unquoted values run until whitespace, so they may contain '='.
+// A bare key has an empty value, and the last value wins if a key appears more than once.
+type ProcessorLogfmt struct {
+	SourceKey              string
+	Prefix                 string
+	KeepSource             bool
+	KeepSourceIfParseError bool
+	NoKeyError             bool
+
+	context pipeline.Context
+}
+
+type pair struct {
+	key   string
+	value string
+}
+
+// Init called for init some system resources, like socket, mutex...
+func (p *ProcessorLogfmt) Init(context pipeline.Context) error {
+	if p.SourceKey == "" {
+		return fmt.Errorf("must specify SourceKey for plugin %v", pluginType)
+	}
+	p.context = context
+	return nil
+}
+
+func (*ProcessorLogfmt) Description() string {
+	return "logfmt processor for logtail"
+}
+
+func (p *ProcessorLogfmt) ProcessLogs(logArray []*protocol.Log) []*protocol.Log {
+	for _, log := range logArray {
+		p.processLog(log)
+	}
+	return logArray
+}
+
+func (p *ProcessorLogfmt) processLog(log *protocol.Log) {
+	for idx := range log.Contents {
+		if log.Contents[idx].Key != p.SourceKey {
+			continue
+		}
+		pairs, err := parse(log.Contents[idx].Value)
+		if err != nil {
+			logger.Warning(p.context.GetRuntimeContext(), "PROCESSOR_LOGFMT_PARSER_ALARM", "parse logfmt error", err)
+			if !p.KeepSourceIfParseError && !p.KeepSource {
+				log.Contents = append(log.Contents[:idx], log.Contents[idx+1:]...)
+			}
+			return
+		}
+		if !p.KeepSource {
+			log.Contents = append(log.Contents[:idx], log.Contents[idx+1:]...)
+		}
+		for _, kv := range pairs {
+			setContent(log, p.Prefix+kv.key, kv.value)
+		}
+		return
+	}
+	if p.NoKeyError {
+		logger.Warningf(p.context.GetRuntimeContext(), "PROCESSOR_LOGFMT_FIND_ALARM", "cannot find key %v", p.SourceKey)
+	}
+}
+
+// setContent replaces the value of the field with the key, or appends the field if not found.
+func setContent(log *protocol.Log, key, value string) {
+	for _, content := range log.Contents {
+		if content.Key == key {
+			content.Value = value
+			return
+		}
+	}
+	log.Contents = append(log.Contents, &protocol.Log_Content{Key: key, Value: value})
+}
+
+// parse splits the logfmt line into pairs in the order keys first appear.
+func parse(line string) ([]pair, error) {
+	var pairs []pair
+	indexes := make(map[string]int)
+	for i := 0; ; {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return pairs, nil
+		}
+		start := i
+		for i < len(line) && !isSpace(line[i]) && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		if i == start || (i < len(line) && line[i] == '"') {
+			return nil, fmt.Errorf("unexpected %q at %d", line[i], i)
+		}
+		key := line[start:i]
+		var value string
+		if i < len(line) && line[i] == '=' {
+			i++
+			start = i
+			if i < len(line) && line[i] == '"' {
+				i = quotedEnd(line, i)
+				if i < 0 {
+					return nil, fmt.Errorf("unterminated quoted value of key %s", key)
+				}
+				unquoted, err := strconv.Unquote(line[start:i])
+				if err != nil {
+					return nil, fmt.Errorf("invalid quoted value of key %s: %w", key, err)
+				}
+				if i < len(line) && !isSpace(line[i]) {
+					return nil, fmt.Errorf("unexpected %q after quoted value at %d", line[i], i)
+				}
+				value = unquoted
+			} else {
+				for i < len(line) && !isSpace(line[i]) {
+					i++
+				}
+				value = line[start:i]
+			}
+		}
+		if idx, ok := indexes[key]; ok {
+			pairs[idx].value = value
+			continue
+		}
+		indexes[key] = len(pairs)
+		pairs = append(pairs, pair{key: key, value: value})
+	}
+}
+
+// quotedEnd returns the index after the closing quote of the value quoted at start, or -1 if not closed.
+func quotedEnd(line string, start int) int {
+	for i := start + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func init() {
+	pipeline.Processors[pluginType] = func() pipeline.Processor {
+		return &ProcessorLogfmt{
+			SourceKey:              "content",
+			KeepSourceIfParseError: true,
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logfmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func init() {
+	logger.InitTestLogger(logger.OptionOpenMemoryReceiver)
+}
+
+func newProcessor(t *testing.T) *ProcessorLogfmt {
+	processor := &ProcessorLogfmt{
+		SourceKey:              "content",
+		KeepSourceIfParseError: true,
+	}
+	require.NoError(t, processor.Init(mock.NewEmptyContext("p", "l", "c")))
+	return processor
+}
+
+func newLog(content string) *protocol.Log {
+	return &protocol.Log{Contents: []*protocol.Log_Content{{Key: "content", Value: content}}}
+}
+
+func contents(log *protocol.Log) map[string]string {
+	result := make(map[string]string, len(log.Contents))
+	for _, content := range log.Contents {
+		result[content.Key] = content.Value
+	}
+	return result
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name  string
+		line  string
+		pairs []pair
+	}{
+		{"simple", `level=info msg=hello dur=12ms`, []pair{{"level", "info"}, {"msg", "hello"}, {"dur", "12ms"}}},
+		{"quoted value", `msg="hello world" level=info`, []pair{{"msg", "hello world"}, {"level", "info"}}},
+		{"escaped quotes", `msg="say \"hi\" \\ bye"`, []pair{{"msg", `say "hi" \ bye`}}},
+		{"bare key", `debug level=info trace`, []pair{{"debug", ""}, {"level", "info"}, {"trace", ""}}},
+		{"empty values", `a= b="" c=1`, []pair{{"a", ""}, {"b", ""}, {"c", "1"}}},
+		{"value containing equal", `url=http://host/?a=b&c=d q="x=y"`, []pair{{"url", "http://host/?a=b&c=d"}, {"q", "x=y"}}},
+		{"duplicate keys", `a=1 b=2 a=3`, []pair{{"a", "3"}, {"b", "2"}}},
+		{"extra whitespace", "  a=1 \t b=2  ", []pair{{"a", "1"}, {"b", "2"}}},
+		{"empty line", "", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pairs, err := parse(c.line)
+			require.NoError(t, err)
+			assert.Equal(t, c.pairs, pairs)
+		})
+	}
+
+	for _, line := range []string{`msg="unterminated`, `=value`, `a="x"y`, `a"b=c`, `a="\q"`} {
+		_, err := parse(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestProcessLogs(t *testing.T) {
+	processor := newProcessor(t)
+	log := newLog(`level=info msg="user \"bob\" logged in" dur=12ms`)
+	log.Contents = append(log.Contents, &protocol.Log_Content{Key: "level", Value: "unknown"})
+	processor.ProcessLogs([]*protocol.Log{log})
+	// parsed keys replace existing fields
+	assert.Equal(t, map[string]string{"level": "info", "msg": `user "bob" logged in`, "dur": "12ms"}, contents(log))
+	assert.Len(t, log.Contents, 3)
+}
+
+func TestKeepSourceAndPrefix(t *testing.T) {
+	processor := newProcessor(t)
+	processor.KeepSource = true
+	processor.Prefix = "lf_"
+	log := newLog(`a=1 b`)
+	processor.ProcessLogs([]*protocol.Log{log})
+	assert.Equal(t, map[string]string{"content": "a=1 b", "lf_a": "1", "lf_b": ""}, contents(log))
+}
+
+func TestParseError(t *testing.T) {
+	processor := newProcessor(t)
+	log := newLog(`msg="unterminated`)
+	processor.ProcessLogs([]*protocol.Log{log})
+	assert.Equal(t, map[string]string{"content": `msg="unterminated`}, contents(log))
+
+	processor.KeepSourceIfParseError = false
+	processor.ProcessLogs([]*protocol.Log{log})
+	assert.Empty(t, log.Contents)
+}
+
+func TestSourceKeyNotFound(t *testing.T) {
+	processor := newProcessor(t)
+	processor.NoKeyError = true
+	log := &protocol.Log{Contents: []*protocol.Log_Content{{Key: "other", Value: "a=1"}}}
+	processor.ProcessLogs([]*protocol.Log{log})
+	assert.Equal(t, map[string]string{"other": "a=1"}, contents(log))
+}
+
+func TestInit(t *testing.T) {
+	processor := &ProcessorLogfmt{}
+	assert.Error(t, processor.Init(mock.NewEmptyContext("p", "l", "c")))
+}
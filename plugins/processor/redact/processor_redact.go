@@ -0,0 +1,159 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/pkg/selfmonitor"
+)
+
+const (
+	pluginType = "processor_redact"
+
+	ModeReplace = "replace"
+	ModeHash    = "hash"
+
+	defaultHashLength = 16
+)
+
+// Rule redacts the substrings matching Regex.
+// In replace mode, a match is replaced with Replacement, which may refer to submatches like $1 or ${name}.
+// In hash mode, a match is replaced with the first HashLength hex digits of its SHA-256, so that redacted
+// values can still be correlated.
+type Rule struct {
+	Regex       string
+	Replacement string
+	Mode        string
+	HashLength  int
+
+	re *regexp.Regexp
+}
+
+// ProcessorRedact masks sensitive data, such as credit card numbers, in the values of Keys by Rules.
+// Rules are applied in order, and all fields are redacted if Keys is empty.
+type ProcessorRedact struct {
+	Keys  []string
+	Rules []Rule
+
+	keys           map[string]struct{}
+	context        pipeline.Context
+	redactedMetric selfmonitor.CounterMetric
+}
+
+// Init called for init some system resources, like socket, mutex...
+func (p *ProcessorRedact) Init(context pipeline.Context) error {
+	p.context = context
+	if len(p.Rules) == 0 {
+		err := errors.New("parameter Rules should not be empty")
+		logger.Error(p.context.GetRuntimeContext(), "PROCESSOR_INIT_ALARM", "init processor_redact error", err)
+		return err
+	}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		re, err := regexp.Compile(rule.Regex)
+		if err == nil && rule.Regex == "" {
+			err = errors.New("regex should not be empty")
+		}
+		if err != nil {
+			err = fmt.Errorf("invalid rule %d: %w", i, err)
+			logger.Error(p.context.GetRuntimeContext(), "PROCESSOR_INIT_ALARM", "init processor_redact error", err)
+			return err
+		}
+		rule.re = re
+		switch rule.Mode {
+		case "":
+			rule.Mode = ModeReplace
+		case ModeReplace:
+		case ModeHash:
+			if rule.HashLength <= 0 || rule.HashLength > sha256.Size*2 {
+				rule.HashLength = defaultHashLength
+			}
+		default:
+			err = fmt.Errorf("invalid rule %d: mode should be %q or %q", i, ModeReplace, ModeHash)
+			logger.Error(p.context.GetRuntimeContext(), "PROCESSOR_INIT_ALARM", "init processor_redact error", err)
+			return err
+		}
+	}
+	if len(p.Keys) > 0 {
+		p.keys = make(map[string]struct{}, len(p.Keys))
+		for _, key := range p.Keys {
+			p.keys[key] = struct{}{}
+		}
+	}
+	p.redactedMetric = selfmonitor.NewCounterMetricAndRegister(p.context.GetMetricRecord(), selfmonitor.MetricPluginRedactedFieldsTotal)
+	return nil
+}
+
+func (*ProcessorRedact) Description() string {
+	return "redact processor to mask sensitive data in logs"
+}
+
+func (p *ProcessorRedact) ProcessLogs(logArray []*protocol.Log) []*protocol.Log {
+	redacted := 0
+	for _, log := range logArray {
+		for _, cont := range log.Contents {
+			if p.keys != nil {
+				if _, ok := p.keys[cont.Key]; !ok {
+					continue
+				}
+			}
+			if value, ok := p.redact(cont.Value); ok {
+				cont.Value = value
+				redacted++
+			}
+		}
+	}
+	if redacted > 0 {
+		p.redactedMetric.Add(int64(redacted))
+	}
+	return logArray
+}
+
+// redact applies the rules to the value, and returns false if nothing matches.
+func (p *ProcessorRedact) redact(value string) (string, bool) {
+	matched := false
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.re.MatchString(value) {
+			continue
+		}
+		matched = true
+		if rule.Mode == ModeHash {
+			value = rule.re.ReplaceAllStringFunc(value, rule.hash)
+		} else {
+			value = rule.re.ReplaceAllString(value, rule.Replacement)
+		}
+	}
+	return value, matched
+}
+
+func (r *Rule) hash(match string) string {
+	sum := sha256.Sum256([]byte(match))
+	return hex.EncodeToString(sum[:])[:r.HashLength]
+}
+
+func init() {
+	pipeline.Processors[pluginType] = func() pipeline.Processor {
+		return &ProcessorRedact{}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+const (
+	cardRegex = `\b(\d{4})[- ]?\d{4}[- ]?\d{4}[- ]?(\d{4})\b`
+	ssnRegex  = `\b\d{3}-\d{2}-\d{4}\b`
+)
+
+func newLog(contents ...string) *protocol.Log {
+	log := &protocol.Log{}
+	for i := 0; i+1 < len(contents); i += 2 {
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: contents[i], Value: contents[i+1]})
+	}
+	return log
+}
+
+func TestProcessorRedactInit(t *testing.T) {
+	for _, processor := range []*ProcessorRedact{
+		{},
+		{Rules: []Rule{{Regex: ""}}},
+		{Rules: []Rule{{Regex: "("}}},
+		{Rules: []Rule{{Regex: ssnRegex, Mode: "md5"}}},
+	} {
+		assert.Error(t, processor.Init(mock.NewEmptyContext("p", "l", "c")))
+	}
+	processor := &ProcessorRedact{Rules: []Rule{{Regex: ssnRegex}, {Regex: ssnRegex, Mode: ModeHash, HashLength: 100}}}
+	require.NoError(t, processor.Init(mock.NewEmptyContext("p", "l", "c")))
+	assert.Equal(t, ModeReplace, processor.Rules[0].Mode)
+	assert.Equal(t, defaultHashLength, processor.Rules[1].HashLength)
+}
+
+func TestProcessorRedactReplace(t *testing.T) {
+	processor := &ProcessorRedact{
+		Keys: []string{"content", "card"},
+		Rules: []Rule{
+			{Regex: cardRegex, Replacement: "$1-****-****-$2"},
+			{Regex: ssnRegex, Replacement: "***-**-****"},
+		},
+	}
+	require.NoError(t, processor.Init(mock.NewEmptyContext("p", "l", "c")))
+	logs := processor.ProcessLogs([]*protocol.Log{
+		newLog("content", "pay with 4111 1111 1111 1234, ssn 123-45-6789", "card", "4111-1111-1111-1234", "other", "123-45-6789"),
+		newLog("content", "nothing sensitive"),
+	})
+	assert.Equal(t, "pay with 4111-****-****-1234, ssn ***-**-****", logs[0].Contents[0].Value)
+	assert.Equal(t, "4111-****-****-1234", logs[0].Contents[1].Value)
+	assert.Equal(t, "123-45-6789", logs[0].Contents[2].Value)
+	assert.Equal(t, "nothing sensitive", logs[1].Contents[0].Value)
+}
+
+func TestProcessorRedactHash(t *testing.T) {
+	processor := &ProcessorRedact{Rules: []Rule{{Regex: ssnRegex, Mode: ModeHash, HashLength: 8}}}
+	require.NoError(t, processor.Init(mock.NewEmptyContext("p", "l", "c")))
+	logs := processor.ProcessLogs([]*protocol.Log{
+		newLog("a", "ssn 123-45-6789", "b", "123-45-6789"),
+	})
+	sum := sha256.Sum256([]byte("123-45-6789"))
+	hash := hex.EncodeToString(sum[:])[:8]
+	assert.Equal(t, "ssn "+hash, logs[0].Contents[0].Value)
+	// the same value is hashed to the same string, so that it can be correlated
+	assert.Equal(t, hash, logs[0].Contents[1].Value)
+}
+
+func benchmarkRedact(b *testing.B, processor *ProcessorRedact, value string) {
+	if err := processor.Init(mock.NewEmptyContext("p", "l", "c")); err != nil {
+		b.Fatal(err)
+	}
+	logs := make([]*protocol.Log, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range logs {
+			logs[j] = newLog("content", value, "level", "info", "method", "GET")
+		}
+		processor.ProcessLogs(logs)
+	}
+}
+
+func BenchmarkProcessorRedact_NoMatch(b *testing.B) {
+	processor := &ProcessorRedact{Rules: []Rule{{Regex: cardRegex, Replacement: "$1-****-****-$2"}, {Regex: ssnRegex, Replacement: "***-**-****"}}}
+	benchmarkRedact(b, processor, "GET /api/v1/orders?page=1 200 12ms user agent curl/7.79.1")
+}
+
+func BenchmarkProcessorRedact_Replace(b *testing.B) {
+	processor := &ProcessorRedact{Rules: []Rule{{Regex: cardRegex, Replacement: "$1-****-****-$2"}, {Regex: ssnRegex, Replacement: "***-**-****"}}}
+	benchmarkRedact(b, processor, "POST /api/v1/pay card=4111 1111 1111 1234 ssn=123-45-6789 200 12ms")
+}
+
+func BenchmarkProcessorRedact_Hash(b *testing.B) {
+	processor := &ProcessorRedact{Keys: []string{"content"}, Rules: []Rule{{Regex: ssnRegex, Mode: ModeHash}}}
+	benchmarkRedact(b, processor, "POST /api/v1/pay card=4111 1111 1111 1234 ssn=123-45-6789 200 12ms")
+}